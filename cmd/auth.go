@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/policy"
+	"example.com/task3gcp/shared/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// authenticate rejects requests without a valid access token. For the
+// requests it lets through it replaces the identity headers with the
+// verified claims, which forwardRequest then passes on to the Cloud Function.
+func authenticate(verifier *auth.Verifier) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := auth.BearerToken(r.Header)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				utils.RespondWithError(w, r, http.StatusUnauthorized, "Missing bearer token")
+				return
+			}
+			claims, err := verifier.Verify(token, auth.AccessToken)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				utils.RespondWithError(w, r, http.StatusUnauthorized, "Invalid or expired token")
+				return
+			}
+			auth.SetIdentityHeaders(r.Header, claims)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// stripIdentity removes client supplied identity headers from requests to
+// routes that do not require a token.
+func stripIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth.ClearIdentityHeaders(r.Header)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorize rejects requests the access policy denies before they reach the
+// Cloud Function. Record level rules are checked against the {id} route
+// variable; rules on individual fields are left to the handlers, which see
+// the request body.
+func authorize(access *policy.Policy, op policy.Operation, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		targetID := 0
+		if id, ok := mux.Vars(r)["id"]; ok {
+			// An invalid ID is left for the handler to reject with 400
+			targetID, _ = strconv.Atoi(id)
+		}
+		if _, err := access.AuthorizeRequest(r, op, targetID); err != nil {
+			if errors.Is(err, policy.ErrUnauthenticated) {
+				utils.RespondWithError(w, r, http.StatusUnauthorized, "Authentication required")
+				return
+			}
+			utils.RespondWithError(w, r, http.StatusForbidden, "Forbidden")
+			return
+		}
+		next(w, r)
+	}
+}
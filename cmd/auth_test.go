@@ -0,0 +1,124 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/policy"
+	"example.com/task3gcp/shared/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func TestAuthenticate(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair, err := auth.NewIssuer(key).Issue(models.Employee{ID: 7, Email: "ada@example.com", Role: "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var upstream http.Header
+	function := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstream = r.Header.Clone()
+	}))
+	defer function.Close()
+
+	r := mux.NewRouter()
+	r.Use(authenticate(auth.NewVerifier(key.Public().(ed25519.PublicKey))))
+	r.HandleFunc("/function-1", forwardRequest(function.URL, time.Second))
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"valid", "Bearer " + pair.AccessToken, http.StatusOK},
+		{"missing", "", http.StatusUnauthorized},
+		{"refresh token", "Bearer " + pair.RefreshToken, http.StatusUnauthorized},
+		{"malformed", "Bearer garbage", http.StatusUnauthorized},
+		{"basic", "Basic YWRhOnNlY3JldA==", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream = nil
+			req := httptest.NewRequest(http.MethodGet, "/function-1", nil)
+			req.Header.Set("Authorization", tt.authorization)
+			req.Header.Set(auth.HeaderEmployeeRole, "spoofed")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want != http.StatusOK {
+				if got := rec.Header().Get("Content-Type"); got != utils.ProblemContentType {
+					t.Errorf("Content-Type = %q, want %q", got, utils.ProblemContentType)
+				}
+				if upstream != nil {
+					t.Error("request was forwarded without a valid token")
+				}
+				return
+			}
+			if got := upstream.Get(auth.HeaderEmployeeID); got != "7" {
+				t.Errorf("%s = %q, want 7", auth.HeaderEmployeeID, got)
+			}
+			if got := upstream.Get(auth.HeaderEmployeeEmail); got != "ada@example.com" {
+				t.Errorf("%s = %q, want ada@example.com", auth.HeaderEmployeeEmail, got)
+			}
+			if got := upstream.Values(auth.HeaderEmployeeRole); len(got) != 1 || got[0] != "admin" {
+				t.Errorf("%s = %q, want only admin", auth.HeaderEmployeeRole, got)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	forwarded := false
+	next := func(w http.ResponseWriter, r *http.Request) { forwarded = true }
+
+	r := mux.NewRouter()
+	r.HandleFunc("/function-2/{id}", authorize(policy.Default(), policy.Get, next))
+	r.HandleFunc("/function-3", authorize(policy.Default(), policy.Create, next))
+
+	tests := []struct {
+		name string
+		path string
+		role string
+		want int
+	}{
+		{"admin reads anyone", "/function-2/9", "admin", http.StatusOK},
+		{"employee reads self", "/function-2/4", "employee", http.StatusOK},
+		{"employee reads other", "/function-2/9", "employee", http.StatusForbidden},
+		{"manager creates", "/function-3", "manager", http.StatusForbidden},
+		{"hr creates", "/function-3", "hr", http.StatusOK},
+		{"anonymous", "/function-3", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forwarded = false
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.role != "" {
+				req.Header.Set(auth.HeaderEmployeeID, "4")
+				req.Header.Set(auth.HeaderEmployeeRole, tt.role)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if forwarded != (tt.want == http.StatusOK) {
+				t.Errorf("forwarded = %v, want %v", forwarded, tt.want == http.StatusOK)
+			}
+		})
+	}
+}
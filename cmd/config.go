@@ -0,0 +1,175 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"example.com/task3gcp/shared/policy"
+)
+
+// Route authentication requirements.
+const (
+	// AuthToken routes need a valid access token and are checked against the
+	// access policy.
+	AuthToken = "token"
+	// AuthNone routes are public, like login.
+	AuthNone = "none"
+)
+
+// defaultTimeout applies to routes that do not set a timeout.
+const defaultTimeout = 30 * time.Second
+
+//go:embed routes.json
+var defaultRoutes []byte
+
+// Config is the gateway configuration read from the route file.
+type Config struct {
+	// Listen is the address the gateway serves on.
+	Listen string `json:"listen"`
+
+	// Vars are the default values of the ${NAME} references in upstream
+	// URLs. Environment variables of the same name take precedence.
+	Vars map[string]string `json:"vars"`
+
+	// Routes are matched in order.
+	Routes []Route `json:"routes"`
+}
+
+// Route forwards requests for one path pattern to a Cloud Function.
+type Route struct {
+	// Path is a gorilla/mux path template, e.g. /function-2/{id}.
+	Path    string   `json:"path"`
+	Methods []string `json:"methods"`
+
+	// Function is the entry point name of the handler, e.g. GetAllEmployees.
+	// Local mode serves the route with that handler instead of the upstream.
+	Function string   `json:"function"`
+	Upstream string   `json:"upstream"`
+	Timeout  Duration `json:"timeout"`
+
+	// Auth is AuthToken (the default) or AuthNone.
+	Auth string `json:"auth"`
+
+	// Operation is the policy operation token routes are authorized for.
+	Operation policy.Operation `json:"operation"`
+}
+
+// Duration is a time.Duration written as a string such as "10s" in JSON.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	value, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(value)
+	return nil
+}
+
+// loadConfig reads the route file named by GATEWAY_CONFIG, or the built-in
+// routes.json, and applies the environment overrides: GATEWAY_LISTEN (or
+// PORT) replaces the listen address and environment variables replace the
+// vars used in upstream URLs.
+func loadConfig() (*Config, error) {
+	data := defaultRoutes
+	if path := os.Getenv("GATEWAY_CONFIG"); path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	cfg, err := parseConfig(data, os.LookupEnv)
+	if err != nil {
+		if path := os.Getenv("GATEWAY_CONFIG"); path != "" {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// parseConfig decodes and validates a route file, resolving overrides with
+// lookupEnv.
+func parseConfig(data []byte, lookupEnv func(string) (string, bool)) (*Config, error) {
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+
+	if listen, ok := lookupEnv("GATEWAY_LISTEN"); ok && listen != "" {
+		cfg.Listen = listen
+	} else if port, ok := lookupEnv("PORT"); ok && port != "" {
+		cfg.Listen = ":" + port
+	}
+	if cfg.Listen == "" {
+		return nil, errors.New("no listen address")
+	}
+
+	for i := range cfg.Routes {
+		route := &cfg.Routes[i]
+		var missing []string
+		route.Upstream = os.Expand(route.Upstream, func(name string) string {
+			if value, ok := lookupEnv(name); ok {
+				return value
+			}
+			if value, ok := cfg.Vars[name]; ok {
+				return value
+			}
+			missing = append(missing, name)
+			return ""
+		})
+		if len(missing) > 0 {
+			return nil, fmt.Errorf("route %s: undefined variables %s", route.Path, strings.Join(missing, ", "))
+		}
+		if err := route.validate(); err != nil {
+			return nil, fmt.Errorf("route %s: %w", route.Path, err)
+		}
+	}
+	return &cfg, nil
+}
+
+// validate checks a route and fills in its defaults.
+func (route *Route) validate() error {
+	if !strings.HasPrefix(route.Path, "/") {
+		return errors.New("path must start with /")
+	}
+	if len(route.Methods) == 0 {
+		return errors.New("no methods")
+	}
+	for i, method := range route.Methods {
+		route.Methods[i] = strings.ToUpper(method)
+	}
+	upstream, err := url.Parse(route.Upstream)
+	if err != nil || upstream.Host == "" || (upstream.Scheme != "http" && upstream.Scheme != "https") {
+		return fmt.Errorf("invalid upstream %q", route.Upstream)
+	}
+	if route.Timeout < 0 {
+		return errors.New("negative timeout")
+	}
+	if route.Timeout == 0 {
+		route.Timeout = Duration(defaultTimeout)
+	}
+	switch route.Auth {
+	case "":
+		route.Auth = AuthToken
+		fallthrough
+	case AuthToken:
+		if route.Operation == "" {
+			return errors.New("token routes need an operation")
+		}
+	case AuthNone:
+	default:
+		return fmt.Errorf("unknown auth %q", route.Auth)
+	}
+	return nil
+}
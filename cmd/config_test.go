@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// env returns a lookupEnv function backed by vars.
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+func TestDefaultRoutes(t *testing.T) {
+	cfg, err := parseConfig(defaultRoutes, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Listen != ":8085" {
+		t.Errorf("listen = %q, want :8085", cfg.Listen)
+	}
+	if len(cfg.Routes) != 23 {
+		t.Fatalf("got %d routes, want 23", len(cfg.Routes))
+	}
+	route := cfg.Routes[4]
+	if route.Path != "/function-2/{id}" || route.Upstream != "https://us-central1-task3gcp.cloudfunctions.net/function-2" ||
+		route.Auth != AuthToken || time.Duration(route.Timeout) != 10*time.Second {
+		t.Errorf("function-2 route = %+v", route)
+	}
+}
+
+func TestConfigOverrides(t *testing.T) {
+	cfg, err := parseConfig(defaultRoutes, env(map[string]string{
+		"FUNCTIONS_BASE_URL": "http://localhost:9000",
+		"PORT":               "8080",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Listen != ":8080" {
+		t.Errorf("listen = %q, want :8080 from PORT", cfg.Listen)
+	}
+	for _, route := range cfg.Routes {
+		if !strings.HasPrefix(route.Upstream, "http://localhost:9000/") {
+			t.Errorf("route %s upstream = %q, want the overridden base URL", route.Path, route.Upstream)
+		}
+	}
+
+	cfg, err = parseConfig(defaultRoutes, env(map[string]string{"GATEWAY_LISTEN": "127.0.0.1:9090", "PORT": "8080"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Listen != "127.0.0.1:9090" {
+		t.Errorf("listen = %q, want GATEWAY_LISTEN to win over PORT", cfg.Listen)
+	}
+}
+
+func TestConfigDefaults(t *testing.T) {
+	cfg, err := parseConfig([]byte(`{"listen":":1","routes":[{"path":"/x","methods":["get"],"upstream":"http://x","operation":"employees.list"}]}`), env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	route := cfg.Routes[0]
+	if route.Auth != AuthToken || time.Duration(route.Timeout) != defaultTimeout || route.Methods[0] != "GET" {
+		t.Errorf("route = %+v, want token auth, default timeout and upper case methods", route)
+	}
+}
+
+func TestConfigInvalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"malformed":        `{"routes":`,
+		"no listen":        `{"routes":[]}`,
+		"relative path":    `{"listen":":1","routes":[{"path":"x","methods":["GET"],"upstream":"http://x","auth":"none"}]}`,
+		"no methods":       `{"listen":":1","routes":[{"path":"/x","upstream":"http://x","auth":"none"}]}`,
+		"bad upstream":     `{"listen":":1","routes":[{"path":"/x","methods":["GET"],"upstream":"x","auth":"none"}]}`,
+		"undefined var":    `{"listen":":1","routes":[{"path":"/x","methods":["GET"],"upstream":"${NOPE}/x","auth":"none"}]}`,
+		"bad timeout":      `{"listen":":1","routes":[{"path":"/x","methods":["GET"],"upstream":"http://x","timeout":"soon","auth":"none"}]}`,
+		"unknown auth":     `{"listen":":1","routes":[{"path":"/x","methods":["GET"],"upstream":"http://x","auth":"basic"}]}`,
+		"token without op": `{"listen":":1","routes":[{"path":"/x","methods":["GET"],"upstream":"http://x","auth":"token"}]}`,
+	} {
+		if _, err := parseConfig([]byte(data), env(nil)); err == nil {
+			t.Errorf("%s: parseConfig succeeded, want an error", name)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+// Command emsctl runs one-off maintenance tasks against the employee
+// database. It reads the project, credentials and emulator settings like the
+// functions do (see the shared config package).
+//
+// Usage:
+//
+//	go run ./cmd/emsctl [-project id] <command> [arguments]
+//
+// Commands:
+//
+//	rehash-passwords  replace plaintext passwords with bcrypt hashes
+//	check-emails      report employees that share an email, ignoring case
+//	index-emails      add the email index entries of existing employees
+//	import            create employees from a CSV file; run
+//	                  "emsctl import -h" for its flags
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"example.com/task3gcp/shared/config"
+	"example.com/task3gcp/shared/importer"
+	"example.com/task3gcp/shared/repository"
+)
+
+// command runs a subcommand with the arguments that follow its name.
+type command func(ctx context.Context, repo *repository.FirestoreRepository, args []string) error
+
+// commands maps each subcommand name to the function that runs it.
+var commands = map[string]command{
+	"rehash-passwords": noArgs(rehashPasswords),
+	"check-emails":     noArgs(checkEmails),
+	"index-emails":     noArgs(indexEmails),
+	"import":           importEmployees,
+}
+
+func main() {
+	project := flag.String("project", "", "Google Cloud project that holds the Firestore database (overrides GOOGLE_CLOUD_PROJECT and CONFIG_FILE)")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+	run, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "emsctl: unknown command %q\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+	if *project != "" {
+		cfg.ProjectID = *project
+	}
+
+	ctx := context.Background()
+	client, err := cfg.NewFirestoreClient(ctx)
+	if err != nil {
+		log.Fatal("Failed to create Firestore client: ", err)
+	}
+	repo := repository.NewFirestoreRepository(client)
+	defer repo.Close()
+
+	if err := run(ctx, repo, flag.Args()[1:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: emsctl [-project id] <command> [arguments]")
+	fmt.Fprintln(os.Stderr, "\nflags:")
+	flag.PrintDefaults()
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(os.Stderr, "  "+name)
+	}
+}
+
+// noArgs adapts a subcommand that takes no arguments.
+func noArgs(run func(ctx context.Context, repo *repository.FirestoreRepository) error) command {
+	return func(ctx context.Context, repo *repository.FirestoreRepository, args []string) error {
+		if len(args) > 0 {
+			return fmt.Errorf("unexpected arguments %q", args)
+		}
+		return run(ctx, repo)
+	}
+}
+
+// rehashPasswords replaces the plaintext passwords written before passwords
+// were hashed. It is idempotent, so it can be rerun after a failure.
+func rehashPasswords(ctx context.Context, repo *repository.FirestoreRepository) error {
+	rehashed, err := repo.RehashPasswords(ctx)
+	log.Printf("Rehashed %d passwords", rehashed)
+	return err
+}
+
+// errDuplicateEmails makes check-emails exit with a failure status when it
+// finds duplicates.
+var errDuplicateEmails = errors.New("duplicate emails found")
+
+// checkEmails lists the employees that share an email. Unique emails are
+// only enforced for writes made since the email index was introduced, so
+// older data can still contain duplicates; rename them, then run
+// index-emails.
+func checkEmails(ctx context.Context, repo *repository.FirestoreRepository) error {
+	duplicates, err := repo.DuplicateEmails(ctx)
+	if err != nil {
+		return err
+	}
+	emails := make([]string, 0, len(duplicates))
+	for email := range duplicates {
+		emails = append(emails, email)
+	}
+	sort.Strings(emails)
+	for _, email := range emails {
+		fmt.Printf("%s\t%s\n", email, joinIDs(duplicates[email]))
+	}
+	log.Printf("Found %d duplicate emails", len(duplicates))
+	if len(duplicates) > 0 {
+		return errDuplicateEmails
+	}
+	return nil
+}
+
+// indexEmails claims the email of every employee in the email index, so
+// that creates and updates check against existing employees too.
+func indexEmails(ctx context.Context, repo *repository.FirestoreRepository) error {
+	indexed, err := repo.IndexEmails(ctx)
+	log.Printf("Indexed %d emails", indexed)
+	return err
+}
+
+func joinIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ",")
+}
+
+// errRejectedRows makes import exit with a failure status when rows were
+// rejected, so scripts notice incomplete migrations.
+var errRejectedRows = errors.New("some rows were rejected")
+
+// importEmployees creates employees from a CSV file, such as an export of
+// the legacy HR system. Rejected rows are written to the -report file, which
+// can be fixed and imported again.
+func importEmployees(ctx context.Context, repo *repository.FirestoreRepository, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	mapping := flags.String("map", "", "columns of renamed fields, as field=Column pairs separated by commas, e.g. firstName=Given Name,email=Mail")
+	dryRun := flags.Bool("dry-run", false, "validate the file without storing anything")
+	report := flags.String("report", "", "write the rejected rows with their errors to this CSV file")
+	batchSize := flags.Int("batch-size", importer.DefaultBatchSize, "number of employees written at once")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: emsctl import [-map mapping] [-dry-run] [-report file] [-batch-size n] file.csv")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	opts := importer.Options{DryRun: *dryRun, BatchSize: *batchSize}
+	var err error
+	if opts.Mapping, err = importer.ParseMapping(*mapping); err != nil {
+		return err
+	}
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	result, err := importer.Import(ctx, repo, file, opts)
+	if result != nil {
+		verb := "Imported"
+		if *dryRun {
+			verb = "Would import"
+		}
+		log.Printf("%s %d of %d rows, rejected %d", verb, result.Imported, result.Rows, len(result.Rejected))
+		for _, row := range result.Rejected {
+			log.Print(row)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if *report != "" {
+		out, err := os.Create(*report)
+		if err != nil {
+			return err
+		}
+		if err := result.WriteReport(out); err != nil {
+			out.Close()
+			return err
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+	}
+	if len(result.Rejected) > 0 {
+		return errRejectedRows
+	}
+	return nil
+}
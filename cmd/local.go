@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+
+	"example.com/task3gcp/function1"
+	"example.com/task3gcp/function2"
+	"example.com/task3gcp/function3"
+	"example.com/task3gcp/function4"
+	"example.com/task3gcp/function5"
+	"example.com/task3gcp/function6"
+	"example.com/task3gcp/function7"
+	"example.com/task3gcp/function8"
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/config"
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/repository"
+	"example.com/task3gcp/shared/utils"
+)
+
+// localHandlers are the Cloud Function entry points that local mode serves
+// in process, by the name they are registered under.
+var localHandlers = map[string]http.HandlerFunc{
+	"GetAllEmployees":            function1.GetAllEmployees,
+	"ExportEmployeesHandler":     function1.ExportEmployeesHandler,
+	"GetEmployeeByID":            function2.GetEmployeeByID,
+	"EmployeeHistoryHandler":     function2.EmployeeHistoryHandler,
+	"EmployeeVersionHandler":     function2.EmployeeVersionHandler,
+	"CreateEmployeeHandler":      function3.CreateEmployeeHandler,
+	"ImportEmployeesHandler":     function3.ImportEmployeesHandler,
+	"UpdateEmployeeHandler":      function4.UpdateEmployeeHandler,
+	"PatchEmployeeHandler":       function4.PatchEmployeeHandler,
+	"RollbackEmployeeHandler":    function4.RollbackEmployeeHandler,
+	"DeleteEmployeeHandler":      function5.DeleteEmployeeHandler,
+	"RestoreEmployeeHandler":     function5.RestoreEmployeeHandler,
+	"PurgeEmployeesHandler":      function5.PurgeEmployeesHandler,
+	"SearchEmployeesHandler":     function6.SearchEmployeesHandler,
+	"LoginHandler":               function7.LoginHandler,
+	"RefreshHandler":             function7.RefreshHandler,
+	"ListDepartmentsHandler":     function8.ListDepartmentsHandler,
+	"GetDepartmentHandler":       function8.GetDepartmentHandler,
+	"CreateDepartmentHandler":    function8.CreateDepartmentHandler,
+	"UpdateDepartmentHandler":    function8.UpdateDepartmentHandler,
+	"DeleteDepartmentHandler":    function8.DeleteDepartmentHandler,
+	"DepartmentEmployeesHandler": function8.DepartmentEmployeesHandler,
+}
+
+// Local mode stores.
+const (
+	// storeMemory keeps employees in memory for the life of the process.
+	storeMemory = "memory"
+	// storeFirestore uses Firestore as deployed functions do. Set
+	// FIRESTORE_EMULATOR_HOST to use the emulator.
+	storeFirestore = "firestore"
+)
+
+// localMode is the state of a gateway that serves the handlers in process
+// instead of proxying to Cloud Functions.
+type localMode struct {
+	// key signs and verifies tokens.
+	key ed25519.PrivateKey
+}
+
+// startLocal points every handler at the chosen store, seeds it and sets up
+// the token signing key. The key comes from JWT_KEY_FILE when that file
+// exists; otherwise a temporary key is generated.
+func startLocal(store, seedFile string) (*localMode, error) {
+	switch store {
+	case storeMemory:
+		repo := repository.NewMemoryRepository()
+		if seedFile != "" {
+			if err := seed(repo, seedFile); err != nil {
+				return nil, err
+			}
+		}
+		useRepository(func() (repository.EmployeeRepository, error) { return repo, nil })
+		function8.UseDepartments(func() (repository.DepartmentRepository, error) { return repo, nil })
+	case storeFirestore:
+		// The handlers share utils.Firestore, whose client uses the emulator
+		// configured by FIRESTORE_EMULATOR_HOST or CONFIG_FILE
+		if seedFile != "" {
+			return nil, errors.New("-seed is only supported with -store=memory")
+		}
+		cfg, err := config.Current()
+		if err != nil {
+			return nil, err
+		}
+		if cfg.EmulatorHost == "" {
+			log.Print("No Firestore emulator is configured; local mode is using the real Firestore database")
+		}
+	default:
+		return nil, fmt.Errorf("unknown store %q, want %s or %s", store, storeMemory, storeFirestore)
+	}
+
+	key, err := auth.LoadPrivateKey(auth.KeyFile())
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Printf("%s not found; signing tokens with a temporary key", auth.KeyFile())
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		return nil, err
+	}
+	function7.UseSigningKey(key)
+	// The handlers verify the access tokens the gateway passes on
+	utils.UseVerifier(auth.NewVerifier(key.Public().(ed25519.PublicKey)))
+	return &localMode{key: key}, nil
+}
+
+// useRepository makes every handler use the same repository.
+func useRepository(open func() (repository.EmployeeRepository, error)) {
+	function1.UseRepository(open)
+	function2.UseRepository(open)
+	function3.UseRepository(open)
+	function4.UseRepository(open)
+	function5.UseRepository(open)
+	function6.UseRepository(open)
+	function7.UseRepository(open)
+	function8.UseRepository(open)
+}
+
+// seed creates the employees listed in a JSON file. Passwords are given in
+// plaintext and hashed on create; IDs are assigned in file order.
+func seed(repo repository.EmployeeRepository, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var employees []models.Employee
+	if err := json.Unmarshal(data, &employees); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, employee := range employees {
+		if _, err := repo.Create(context.Background(), employee); err != nil {
+			return err
+		}
+	}
+	log.Printf("Seeded %d employees from %s", len(employees), path)
+	return nil
+}
+
+// handler returns the in-process handler for route.
+func (l *localMode) handler(route Route) (http.HandlerFunc, error) {
+	handler, ok := localHandlers[route.Function]
+	if !ok {
+		return nil, fmt.Errorf("route %s: unknown function %q", route.Path, route.Function)
+	}
+	return handler, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"example.com/task3gcp/shared/auth"
+)
+
+func TestLocalMode(t *testing.T) {
+	// Without a key file local mode signs tokens with a temporary key.
+	t.Setenv("JWT_KEY_FILE", filepath.Join(t.TempDir(), "missing.pem"))
+	t.Setenv("GATEWAY_CONFIG", "")
+
+	local, err := startLocal(storeMemory, "local-seed.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, router, err := loadGateway(local)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/function-7",
+		strings.NewReader(`{"email": "manager@example.com", "password": "changeme"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login: status = %d: %s", rec.Code, rec.Body)
+	}
+	var pair auth.TokenPair
+	if err := json.NewDecoder(rec.Body).Decode(&pair); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/function-1", http.StatusOK},
+		{http.MethodGet, "/function-2/1", http.StatusOK},
+		{http.MethodGet, "/function-2/99", http.StatusNotFound},
+		{http.MethodGet, "/function-6?role=admin", http.StatusOK},
+		{http.MethodGet, "/employees/search?role=admin", http.StatusOK},
+		{http.MethodDelete, "/function-5/1", http.StatusForbidden},
+		{http.MethodGet, "/function-2/1/versions/1", http.StatusForbidden},
+		{http.MethodPost, "/function-4/1/rollback?to=1", http.StatusForbidden},
+		{http.MethodGet, "/function-8", http.StatusOK},
+		{http.MethodPost, "/function-8", http.StatusForbidden},
+		{http.MethodGet, "/function-8/1/employees", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if got := serve(router, tt.method, tt.path, pair.AccessToken); got != tt.want {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestLocalModeUnknownFunction(t *testing.T) {
+	local := &localMode{}
+	if _, err := local.handler(Route{Path: "/x", Function: "Nope"}); err == nil {
+		t.Error("handler for an unknown function: err = nil")
+	}
+	if _, err := startLocal("postgres", ""); err == nil {
+		t.Error("startLocal with an unknown store: err = nil")
+	}
+}
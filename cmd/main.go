@@ -1,69 +1,196 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"regexp"
+	"syscall"
+	"time"
 
 	_ "task3gcp/docs" // Import generated docs
 
+	"example.com/task3gcp/shared/utils"
+
 	"github.com/gorilla/mux"
-	httpSwagger "github.com/swaggo/http-swagger"
 )
 
 // @title Employee Management System UI
 // @version 1.0
 // @description Google Cloud Platform to serve Cloud functions seamlessly
 // @host us-central1-task3gcp.cloudfunctions.net
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Access token from the login endpoint, as "Bearer <token>"
 func main() {
-	r := mux.NewRouter()
+	local := flag.Bool("local", false, "serve the handlers in process instead of proxying to Cloud Functions")
+	store := flag.String("store", storeMemory, "repository for -local: memory, or firestore (set FIRESTORE_EMULATOR_HOST for the emulator)")
+	seedFile := flag.String("seed", "", "JSON file of employees to create in the -local memory store")
+	flag.Parse()
+
+	var gw gateway
+	if *local {
+		var err error
+		if gw.local, err = startLocal(*store, *seedFile); err != nil {
+			log.Fatal("Failed to start local mode: ", err)
+		}
+	}
 
-	// Serve Swagger documentation and UI
-	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
+	cfg, router, err := loadGateway(gw.local)
+	if err != nil {
+		log.Fatal("Failed to load gateway configuration: ", err)
+	}
+	gw.router.Store(router)
+	go gw.reloadOnSignal(cfg.Listen)
 
-	r.HandleFunc("/function-1", forwardRequest("https://us-central1-task3gcp.cloudfunctions.net/function-1", http.MethodGet)).Methods(http.MethodGet)
-	r.HandleFunc("/function-2/{id}", forwardRequest("https://us-central1-task3gcp.cloudfunctions.net/function-2", http.MethodGet)).Methods(http.MethodGet)
-	r.HandleFunc("/function-3", forwardRequest("https://us-central1-task3gcp.cloudfunctions.net/function-3", http.MethodPost)).Methods(http.MethodPost)
-	r.HandleFunc("/function-4/{id}", forwardRequest("https://us-central1-task3gcp.cloudfunctions.net/function-4", http.MethodPut)).Methods(http.MethodPut)
-	r.HandleFunc("/function-5/{id}", forwardRequest("https://us-central1-task3gcp.cloudfunctions.net/function-5", http.MethodDelete)).Methods(http.MethodDelete)
+	http.Handle("/", &gw)
 
-	http.Handle("/", r)
+	// Stop on SIGINT or SIGTERM, letting requests in flight finish and
+	// closing the Firestore client the handlers share in local mode
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	server := &http.Server{Addr: cfg.Listen}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Print("Failed to shut down the gateway: ", err)
+		}
+		if err := utils.Firestore.Shutdown(shutdownCtx); err != nil {
+			log.Print("Failed to close the Firestore client: ", err)
+		}
+	}()
 
-	fmt.Println("Head over to http://localhost:8085/swagger/index.html to view Swagger documentation.")
-	log.Fatal(http.ListenAndServe(":8085", nil))
+	fmt.Printf("Head over to http://localhost%s/swagger/index.html to view Swagger documentation.\n", cfg.Listen)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
 }
 
-func forwardRequest(targetURL, method string) http.HandlerFunc {
+func forwardRequest(targetURL string, timeout time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		// Build the Cloud Function URL, keeping path parameters and the query string
+		upstream, err := upstreamURL(targetURL, r)
+		if err != nil {
+			log.Print("Failed to build the upstream URL: ", err)
+			utils.RespondWithError(w, r, http.StatusInternalServerError, "Failed to build the upstream request")
+			return
+		}
+
 		// Create a new request to the Cloud Function URL
-		req, err := http.NewRequest(method, targetURL, r.Body)
+		req, err := http.NewRequestWithContext(r.Context(), r.Method, upstream.String(), r.Body)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			log.Print("Failed to build the upstream request: ", err)
+			utils.RespondWithError(w, r, http.StatusInternalServerError, "Failed to build the upstream request")
 			return
 		}
 		defer r.Body.Close()
 
 		// Copy headers from the original request to the new request
 		for key, value := range r.Header {
-			req.Header[key] = value
+			if !isHopByHop(key) {
+				req.Header[key] = value
+			}
 		}
+		setForwardedHeaders(req.Header, r)
 
 		// Send the request to the Cloud Function URL
-		client := http.Client{}
+		client := http.Client{Timeout: timeout}
 		resp, err := client.Do(req)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			// The error names the function's URL, so clients only get a
+			// fixed message
+			log.Print("Failed to reach the upstream function: ", err)
+			utils.RespondWithError(w, r, http.StatusBadGateway, "Upstream unavailable")
 			return
 		}
 		defer resp.Body.Close()
 
 		// Copy the response from the Cloud Function to the original response writer
 		for key, value := range resp.Header {
-			w.Header()[key] = value
+			if !isHopByHop(key) {
+				w.Header()[key] = value
+			}
 		}
 		w.WriteHeader(resp.StatusCode)
 		io.Copy(w, resp.Body)
 	}
 
 }
+
+// routeVariable matches the {name} or {name:pattern} parts of a route template.
+var routeVariable = regexp.MustCompile(`\{([^}:]+)`)
+
+// upstreamURL returns the URL a request is forwarded to: targetURL with the
+// matched route's path variables appended in template order, so
+// /function-5/7/restore reaches <target>/7, and with the query string of r.
+func upstreamURL(targetURL string, r *http.Request) (*url.URL, error) {
+	upstream, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			vars := mux.Vars(r)
+			for _, match := range routeVariable.FindAllStringSubmatch(template, -1) {
+				upstream = upstream.JoinPath(vars[match[1]])
+			}
+		}
+	}
+
+	switch {
+	case upstream.RawQuery == "":
+		upstream.RawQuery = r.URL.RawQuery
+	case r.URL.RawQuery != "":
+		upstream.RawQuery += "&" + r.URL.RawQuery
+	}
+	return upstream, nil
+}
+
+// setForwardedHeaders records the original client, host and scheme in the
+// X-Forwarded-* headers, extending values set by a proxy in front of the
+// gateway.
+func setForwardedHeaders(h http.Header, r *http.Request) {
+	if client, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		if prior := r.Header.Get("X-Forwarded-For"); prior != "" {
+			client = prior + ", " + client
+		}
+		h.Set("X-Forwarded-For", client)
+	}
+	if h.Get("X-Forwarded-Host") == "" {
+		h.Set("X-Forwarded-Host", r.Host)
+	}
+	if h.Get("X-Forwarded-Proto") == "" {
+		proto := "http"
+		if r.TLS != nil {
+			proto = "https"
+		}
+		h.Set("X-Forwarded-Proto", proto)
+	}
+}
+
+// hopByHopHeaders apply to a single connection and must not be forwarded.
+var hopByHopHeaders = map[string]bool{
+	"Connection":          true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+}
+
+func isHopByHop(header string) bool {
+	return hopByHopHeaders[http.CanonicalHeaderKey(header)]
+}
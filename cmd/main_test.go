@@ -0,0 +1,133 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// upstreamRequest is what the fake Cloud Function received.
+type upstreamRequest struct {
+	method string
+	uri    string
+	header http.Header
+	body   string
+}
+
+func newUpstream(t *testing.T) (*httptest.Server, *upstreamRequest) {
+	t.Helper()
+	got := &upstreamRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		*got = upstreamRequest{method: r.Method, uri: r.URL.RequestURI(), header: r.Header.Clone(), body: string(body)}
+		w.Header().Set("X-Upstream", "yes")
+		w.WriteHeader(http.StatusTeapot)
+		io.WriteString(w, "from upstream")
+	}))
+	t.Cleanup(server.Close)
+	return server, got
+}
+
+func TestForwardRequestRewritesURL(t *testing.T) {
+	upstream, got := newUpstream(t)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/function-1", forwardRequest(upstream.URL+"/function-1", time.Second))
+	r.HandleFunc("/function-2/{id}", forwardRequest(upstream.URL+"/function-2", time.Second))
+	r.HandleFunc("/function-5/{id}/restore", forwardRequest(upstream.URL+"/function-5-restore", time.Second))
+	r.HandleFunc("/function-6", forwardRequest(upstream.URL+"/function-6?source=gateway", time.Second))
+
+	tests := []struct {
+		method, path, want string
+	}{
+		{http.MethodGet, "/function-1?limit=2&orderBy=lastName,-id", "/function-1?limit=2&orderBy=lastName,-id"},
+		{http.MethodGet, "/function-2/7?includeDeleted=true", "/function-2/7?includeDeleted=true"},
+		{http.MethodPost, "/function-5/7/restore", "/function-5-restore/7"},
+		{http.MethodGet, "/function-6?firstName=ad", "/function-6?source=gateway&firstName=ad"},
+		{http.MethodGet, "/function-2/a%20b", "/function-2/a%20b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if got.uri != tt.want {
+				t.Errorf("upstream URI = %q, want %q", got.uri, tt.want)
+			}
+			if got.method != tt.method {
+				t.Errorf("upstream method = %s, want %s", got.method, tt.method)
+			}
+			if rec.Code != http.StatusTeapot || rec.Body.String() != "from upstream" || rec.Header().Get("X-Upstream") != "yes" {
+				t.Errorf("response = %d %q %v, want the upstream response", rec.Code, rec.Body, rec.Header())
+			}
+		})
+	}
+}
+
+func TestForwardRequestHeaders(t *testing.T) {
+	upstream, got := newUpstream(t)
+	handler := forwardRequest(upstream.URL, time.Second)
+
+	req := httptest.NewRequest(http.MethodPut, "http://gateway.example.com/function-4/7", strings.NewReader(`{"firstName":"Ada"}`))
+	req.RemoteAddr = "203.0.113.9:51234"
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connection", "keep-alive")
+	handler(httptest.NewRecorder(), req)
+
+	if got.body != `{"firstName":"Ada"}` {
+		t.Errorf("upstream body = %q", got.body)
+	}
+	for header, want := range map[string]string{
+		"Authorization":     "Bearer token",
+		"Content-Type":      "application/json",
+		"X-Forwarded-For":   "203.0.113.9",
+		"X-Forwarded-Host":  "gateway.example.com",
+		"X-Forwarded-Proto": "http",
+	} {
+		if value := got.header.Get(header); value != want {
+			t.Errorf("upstream %s = %q, want %q", header, value, want)
+		}
+	}
+	if value := got.header.Get("Connection"); value == "keep-alive" {
+		t.Errorf("hop-by-hop Connection header was forwarded")
+	}
+
+	// A proxy in front of the gateway has already set the forwarding headers.
+	req = httptest.NewRequest(http.MethodPut, "/function-4/7", nil)
+	req.RemoteAddr = "10.0.0.2:443"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("X-Forwarded-Host", "api.example.com")
+	handler(httptest.NewRecorder(), req)
+
+	for header, want := range map[string]string{
+		"X-Forwarded-For":   "198.51.100.1, 10.0.0.2",
+		"X-Forwarded-Host":  "api.example.com",
+		"X-Forwarded-Proto": "https",
+	} {
+		if value := got.header.Get(header); value != want {
+			t.Errorf("behind a proxy: upstream %s = %q, want %q", header, value, want)
+		}
+	}
+}
+
+func TestForwardRequestUnreachableUpstream(t *testing.T) {
+	upstream, _ := newUpstream(t)
+	upstream.Close()
+
+	rec := httptest.NewRecorder()
+	forwardRequest(upstream.URL+"/function-1", time.Second)(rec, httptest.NewRequest(http.MethodGet, "/function-1", nil))
+
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadGateway)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "Upstream unavailable") || strings.Contains(body, upstream.URL) {
+		t.Errorf("body = %s, want a fixed detail without the upstream URL", body)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/policy"
+	"example.com/task3gcp/shared/utils"
+
+	"github.com/gorilla/mux"
+	httpSwagger "github.com/swaggo/http-swagger"
+)
+
+// newRouter builds the gateway handler for the routes in cfg. Routes are
+// forwarded to their upstream, or served in process when local is not nil.
+func newRouter(cfg *Config, verifier *auth.Verifier, access *policy.Policy, local *localMode) (*mux.Router, error) {
+	r := mux.NewRouter()
+	r.Use(requestID)
+	r.NotFoundHandler = requestID(problemHandler(http.StatusNotFound, "No route matches the request"))
+	r.MethodNotAllowedHandler = requestID(problemHandler(http.StatusMethodNotAllowed, "Method not allowed for this route"))
+
+	// Serve Swagger documentation and UI
+	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
+
+	for _, route := range cfg.Routes {
+		target := forwardRequest(route.Upstream, time.Duration(route.Timeout))
+		if local != nil {
+			var err error
+			if target, err = local.handler(route); err != nil {
+				return nil, err
+			}
+		}
+
+		var handler http.Handler
+		if route.Auth == AuthNone {
+			handler = stripIdentity(target)
+		} else {
+			handler = authenticate(verifier)(authorize(access, route.Operation, target))
+		}
+		r.Handle(route.Path, handler).Methods(route.Methods...)
+	}
+	return r, nil
+}
+
+// requestID gives every request an X-Request-Id, keeping the one the client
+// sent, and echoes it in the response. forwardRequest passes it on so the
+// gateway and Cloud Function logs of a request can be matched up.
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := utils.RequestID(r)
+		r.Header.Set(utils.HeaderRequestID, id)
+		w.Header().Set(utils.HeaderRequestID, id)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// problemHandler answers every request with a problem of the given status.
+func problemHandler(status int, message string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		utils.RespondWithError(w, r, status, message)
+	})
+}
+
+// loadGateway reads the route file, the token verification key and the
+// access policy and returns the configuration and the router built from them.
+func loadGateway(local *localMode) (*Config, *mux.Router, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	// Tokens are verified with the key in JWT_KEY_FILE (default jwt_key.pem)
+	var verificationKey ed25519.PublicKey
+	if local != nil {
+		verificationKey = local.key.Public().(ed25519.PublicKey)
+	} else if verificationKey, err = auth.LoadPublicKey(auth.KeyFile()); err != nil {
+		return nil, nil, err
+	}
+	// Role permissions come from POLICY_FILE, or the built-in policy
+	access, err := policy.FromEnv()
+	if err != nil {
+		return nil, nil, err
+	}
+	router, err := newRouter(cfg, auth.NewVerifier(verificationKey), access, local)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, router, nil
+}
+
+// gateway serves the current router. Reloading swaps the router atomically,
+// so requests in flight finish on the routes they started with.
+type gateway struct {
+	router atomic.Pointer[mux.Router]
+
+	// local is set when the gateway serves the handlers in process.
+	local *localMode
+}
+
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.router.Load().ServeHTTP(w, r)
+}
+
+// reloadOnSignal reloads the configuration whenever the process receives
+// SIGHUP.
+func (g *gateway) reloadOnSignal(listen string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		g.reload(listen)
+	}
+}
+
+// reload loads the configuration again and switches to the new routes. A
+// configuration that fails to load is logged and the previous one stays in
+// use. The listen address cannot change without a restart.
+func (g *gateway) reload(listen string) error {
+	cfg, router, err := loadGateway(g.local)
+	if err != nil {
+		log.Print("Reload failed, keeping the current configuration: ", err)
+		return err
+	}
+	if cfg.Listen != listen {
+		log.Printf("Reload cannot change the listen address from %s to %s; restart the gateway", listen, cfg.Listen)
+	}
+	g.router.Store(router)
+	log.Printf("Configuration reloaded: %d routes", len(cfg.Routes))
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/utils"
+)
+
+// writeKey writes a new signing key to a temporary key file, points
+// JWT_KEY_FILE at it and returns the key.
+func writeKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwt_key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("JWT_KEY_FILE", path)
+	return key
+}
+
+// writeConfig writes a route file to path and points GATEWAY_CONFIG at it.
+func writeConfig(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GATEWAY_CONFIG", path)
+}
+
+func serve(h http.Handler, method, path, token string) int {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestGatewayRoutesAndReload(t *testing.T) {
+	key := writeKey(t)
+	pair, err := auth.NewIssuer(key).Issue(models.Employee{ID: 1, Role: "employee"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstream, _ := newUpstream(t)
+	t.Setenv("FUNCTIONS_BASE_URL", upstream.URL)
+
+	path := filepath.Join(t.TempDir(), "routes.json")
+	writeConfig(t, path, `{
+		"listen": ":8085",
+		"routes": [
+			{"path": "/login", "methods": ["POST"], "upstream": "${FUNCTIONS_BASE_URL}/login", "auth": "none"},
+			{"path": "/employees/{id}", "methods": ["GET"], "upstream": "${FUNCTIONS_BASE_URL}/get", "operation": "employees.get"}
+		]
+	}`)
+
+	cfg, router, err := loadGateway(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gw gateway
+	gw.router.Store(router)
+
+	tests := []struct {
+		method, path, token string
+		want                int
+	}{
+		{http.MethodPost, "/login", "", http.StatusTeapot},
+		{http.MethodGet, "/login", "", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/employees/1", "", http.StatusUnauthorized},
+		{http.MethodGet, "/employees/1", pair.AccessToken, http.StatusTeapot},
+		{http.MethodGet, "/employees/2", pair.AccessToken, http.StatusForbidden},
+		{http.MethodGet, "/function-1", pair.AccessToken, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if got := serve(&gw, tt.method, tt.path, tt.token); got != tt.want {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.path, got, tt.want)
+		}
+	}
+
+	// A broken file keeps the current routes.
+	writeConfig(t, path, `{"listen": ":8085", "routes": [`)
+	if err := gw.reload(cfg.Listen); err == nil {
+		t.Error("reload of a malformed file succeeded")
+	}
+	if got := serve(&gw, http.MethodPost, "/login", ""); got != http.StatusTeapot {
+		t.Errorf("after failed reload: POST /login status = %d, want %d", got, http.StatusTeapot)
+	}
+
+	writeConfig(t, path, `{
+		"listen": ":8085",
+		"routes": [
+			{"path": "/auth/login", "methods": ["POST"], "upstream": "${FUNCTIONS_BASE_URL}/login", "auth": "none"}
+		]
+	}`)
+	if err := gw.reload(cfg.Listen); err != nil {
+		t.Fatal(err)
+	}
+	if got := serve(&gw, http.MethodPost, "/auth/login", ""); got != http.StatusTeapot {
+		t.Errorf("after reload: POST /auth/login status = %d, want %d", got, http.StatusTeapot)
+	}
+	if got := serve(&gw, http.MethodPost, "/login", ""); got != http.StatusNotFound {
+		t.Errorf("after reload: POST /login status = %d, want %d", got, http.StatusNotFound)
+	}
+}
+
+func TestGatewayRequestID(t *testing.T) {
+	writeKey(t)
+	upstream, got := newUpstream(t)
+	t.Setenv("FUNCTIONS_BASE_URL", upstream.URL)
+	writeConfig(t, filepath.Join(t.TempDir(), "routes.json"), `{
+		"listen": ":8085",
+		"routes": [{"path": "/login", "methods": ["POST"], "upstream": "${FUNCTIONS_BASE_URL}/login", "auth": "none"}]
+	}`)
+	_, router, err := loadGateway(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.Header.Set(utils.HeaderRequestID, "req-1")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if id := got.header.Get(utils.HeaderRequestID); id != "req-1" {
+		t.Errorf("upstream %s = %q, want the client's req-1", utils.HeaderRequestID, id)
+	}
+	if id := rec.Header().Get(utils.HeaderRequestID); id != "req-1" {
+		t.Errorf("response %s = %q, want req-1", utils.HeaderRequestID, id)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if rec.Code != http.StatusNotFound || rec.Header().Get("Content-Type") != utils.ProblemContentType {
+		t.Errorf("unknown route: status = %d, Content-Type = %q, want a 404 problem", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if rec.Header().Get(utils.HeaderRequestID) == "" {
+		t.Errorf("unknown route: response has no %s", utils.HeaderRequestID)
+	}
+}
@@ -15,117 +15,194 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/employees": {
-            "get": {
-                "description": "Get a list of all employees",
+        "/auth/login": {
+            "post": {
+                "description": "Verify an employee's email and password and issue a short-lived access token and a refresh token",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Get all employees",
+                "summary": "Log in",
+                "parameters": [
+                    {
+                        "description": "Email and password",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/function7.credentials"
+                        }
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/controller.Employee"
-                            }
+                            "$ref": "#/definitions/auth.TokenPair"
                         }
+                    },
+                    "400": {
+                        "description": "Invalid request payload"
+                    },
+                    "401": {
+                        "description": "Invalid email or password"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Firestore unavailable, retry later"
                     }
                 }
-            },
+            }
+        },
+        "/auth/refresh": {
             "post": {
-                "description": "Create a new employee",
+                "description": "Exchange a valid refresh token for a new access token and refresh token. The employee is read again, so role changes and deletions take effect.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Create a new employee",
+                "summary": "Refresh tokens",
                 "parameters": [
                     {
-                        "description": "Employee object to be created",
-                        "name": "employee",
+                        "description": "Refresh token from a previous login or refresh",
+                        "name": "refresh",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/controller.Employee"
+                            "$ref": "#/definitions/function7.refreshRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Employee created successfully",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/auth.TokenPair"
                         }
                     },
                     "400": {
                         "description": "Invalid request payload"
                     },
+                    "401": {
+                        "description": "Invalid refresh token"
+                    },
                     "500": {
                         "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Firestore unavailable, retry later"
                     }
                 }
             }
         },
-        "/employees/search": {
+        "/departments": {
             "get": {
-                "description": "Search employees based on the specified field and value",
+                "description": "Get every department, ordered by ID",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Search employees by field and value",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Field to search (e.g., FirstName, LastName, Email, Role)",
-                        "name": "field",
-                        "in": "query",
-                        "required": true
-                    },
+                "summary": "Get all departments",
+                "security": [
                     {
-                        "type": "string",
-                        "description": "Value to search for",
-                        "name": "value",
-                        "in": "query",
-                        "required": true
+                        "BearerAuth": []
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/controller.Employee"
-                            }
+                            "$ref": "#/definitions/function8.departmentList"
+                        }
+                    },
+                    "401": {
+                        "description": "Authentication required"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Firestore unavailable, retry later"
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new department. Codes are stored in upper case and must be unique; headEmployeeId, when set, must be an employee that is not deleted",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Create a new department",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "description": "Department object to be created",
+                        "name": "department",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Department"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Department created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/models.Department"
                         }
                     },
                     "400": {
-                        "description": "Bad Request: Invalid field or value"
+                        "description": "Invalid request payload"
+                    },
+                    "401": {
+                        "description": "Authentication required"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "409": {
+                        "description": "Department code already in use"
                     },
                     "500": {
                         "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Firestore unavailable, retry later"
                     }
                 }
             }
         },
-        "/employees/{id}": {
+        "/departments/{id}": {
             "get": {
-                "description": "Get an employee by ID",
+                "description": "Get a department by ID",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Get an employee by ID",
+                "summary": "Get a department by ID",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "parameters": [
                     {
                         "type": "number",
-                        "description": "ID",
+                        "description": "Department ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -135,81 +212,106 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/controller.Employee"
+                            "$ref": "#/definitions/models.Department"
                         }
                     },
                     "400": {
-                        "description": "Invalid employee ID"
+                        "description": "Invalid department ID"
+                    },
+                    "401": {
+                        "description": "Authentication required"
+                    },
+                    "403": {
+                        "description": "Forbidden"
                     },
                     "404": {
-                        "description": "Employee not found"
+                        "description": "Department not found"
                     },
                     "500": {
                         "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Firestore unavailable, retry later"
                     }
                 }
             },
             "put": {
-                "description": "Update an existing employee by ID",
+                "description": "Replace an existing department by ID, with the same checks as creating one",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Update an existing employee",
+                "summary": "Update an existing department",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "parameters": [
                     {
                         "type": "number",
-                        "description": "Employee ID to be updated",
+                        "description": "Department ID to be updated",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Updated employee object",
-                        "name": "employee",
+                        "description": "Updated department object",
+                        "name": "department",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/controller.Employee"
+                            "$ref": "#/definitions/models.Department"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Employee updated successfully",
+                        "description": "Department updated successfully",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.Department"
                         }
                     },
                     "400": {
-                        "description": "Invalid request payload"
+                        "description": "Invalid department ID"
+                    },
+                    "401": {
+                        "description": "Authentication required"
+                    },
+                    "403": {
+                        "description": "Forbidden"
                     },
                     "404": {
-                        "description": "Employee not found"
+                        "description": "Department not found"
+                    },
+                    "409": {
+                        "description": "Department code already in use"
                     },
                     "500": {
                         "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Firestore unavailable, retry later"
                     }
                 }
             },
             "delete": {
-                "description": "Delete an existing employee by ID",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Delete a department by ID. Departments that employees still belong to, including soft-deleted ones, cannot be deleted; reassign or purge those employees first",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Delete an existing employee",
+                "summary": "Delete a department",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "parameters": [
                     {
                         "type": "number",
-                        "description": "Employee ID to be deleted",
+                        "description": "Department ID to be deleted",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -217,7 +319,7 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Employee deleted successfully",
+                        "description": "Department deleted successfully",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -226,41 +328,1204 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Invalid employee ID"
+                        "description": "Invalid department ID"
+                    },
+                    "401": {
+                        "description": "Authentication required"
+                    },
+                    "403": {
+                        "description": "Forbidden"
                     },
                     "404": {
-                        "description": "Employee not found"
+                        "description": "Department not found"
+                    },
+                    "409": {
+                        "description": "Department still has employees"
                     },
                     "500": {
                         "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Firestore unavailable, retry later"
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "controller.Employee": {
-            "type": "object",
-            "properties": {
-                "email": {
-                    "type": "string"
-                },
-                "firstName": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "lastName": {
-                    "type": "string"
-                },
-                "password": {
-                    "type": "string"
-                },
-                "role": {
-                    "type": "string"
+        },
+        "/departments/{id}/employees": {
+            "get": {
+                "description": "Get a page of the employees of a department. It takes the query parameters of the employee listing, with departmentId set to the department",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get the employees of a department",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "number",
+                        "description": "Department ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of employees per page (1-1000)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token from a previous response's nextPageToken",
+                        "name": "pageToken",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated fields, '-' prefix for descending, e.g. lastName,-id",
+                        "name": "orderBy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include soft-deleted employees",
+                        "name": "includeDeleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/function8.employeePage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid department ID or query parameters"
+                    },
+                    "401": {
+                        "description": "Authentication required"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "404": {
+                        "description": "Department not found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Firestore unavailable, retry later"
+                    }
                 }
             }
+        },
+        "/employees": {
+            "get": {
+                "description": "Get a page of employees. Soft-deleted employees are hidden unless includeDeleted is true. Pass the returned nextPageToken as pageToken, with the same orderBy, to fetch the next page.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get all employees",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of employees per page (1-1000)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token from a previous response's nextPageToken",
+                        "name": "pageToken",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated fields, '-' prefix for descending, e.g. lastName,-id",
+                        "name": "orderBy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only employees with this role (also firstName, lastName, email, id, departmentId)",
+                        "name": "role",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include soft-deleted employees",
+                        "name": "includeDeleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/function1.employeePage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters"
+                    },
+                    "401": {
+                        "description": "Authentication required"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Firestore unavailable, retry later"
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new employee. The response holds the stored employee, with its ID, and its ETag and Location headers name its version and URL",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Create a new employee",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "description": "Employee object to be created",
+                        "name": "employee",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Employee"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Employee created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/models.EmployeeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload"
+                    },
+                    "401": {
+                        "description": "Authentication required"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "409": {
+                        "description": "Email already in use"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Firestore unavailable, retry later"
+                    }
+                }
+            }
+        },
+        "/employees/export": {
+            "get": {
+                "description": "Download every employee matching the filters of the list endpoint, streamed page by page. csv (the default) opens in spreadsheet applications; ndjson has one employee per line; json is an array. Passwords are never exported",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/json"
+                ],
+                "summary": "Export employees",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson",
+                            "json"
+                        ],
+                        "type": "string",
+                        "description": "csv, ndjson or json",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated fields, '-' prefix for descending, e.g. lastName,-id",
+                        "name": "orderBy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only employees with this role (also firstName, lastName, email, id, departmentId)",
+                        "name": "role",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include soft-deleted employees",
+                        "name": "includeDeleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.EmployeeResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters"
+                    },
+                    "401": {
+                        "description": "Authentication required"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Firestore unavailable, retry later"
+                    }
+                }
+            }
+        },
+        "/employees/import": {
+            "post": {
+                "description": "Create employees from the CSV file in the multipart field \"file\". Columns are matched to the employee fields firstName, lastName, email, password, role and the optional departmentId by name, ignoring case, unless mapping renames them. Every row is validated like a created employee; rejected rows are listed in the response, or returned as a CSV error report when the request accepts text/csv",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "summary": "Import employees from CSV",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "CSV file with a header row",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Columns of renamed fields, as field=Column pairs separated by commas, e.g. firstName=Given Name,email=Mail",
+                        "name": "mapping",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate the file without storing anything",
+                        "name": "dryRun",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Import summary, or the CSV report of rejected rows",
+                        "schema": {
+                            "$ref": "#/definitions/importer.Result"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid file or mapping"
+                    },
+                    "401": {
+                        "description": "Authentication required"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Firestore unavailable, retry later"
+                    }
+                }
+            }
+        },
+        "/employees/search": {
+            "get": {
+                "description": "Search employees based on the specified field and value. Fields can also be combined, e.g. firstName=ad&role=manager. Soft-deleted employees are hidden unless includeDeleted is true.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Search employees by field and value",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Field to search (e.g., FirstName, LastName, Email, Role)",
+                        "name": "field",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Value to search for",
+                        "name": "value",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "First name to match",
+                        "name": "firstName",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last name to match",
+                        "name": "lastName",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Email to match",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Role to match",
+                        "name": "role",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "exact (default) or prefix for case-insensitive prefix matching",
+                        "name": "match",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include soft-deleted employees",
+                        "name": "includeDeleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of results (1-1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.EmployeeResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request: Invalid field or value"
+                    },
+                    "401": {
+                        "description": "Authentication required"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Firestore unavailable, retry later"
+                    }
+                }
+            }
+        },
+        "/employees/{id}": {
+            "get": {
+                "description": "Get an employee by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get an employee by ID",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "number",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a cached copy",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.EmployeeResponse"
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified since the given ETag"
+                    },
+                    "400": {
+                        "description": "Invalid employee ID"
+                    },
+                    "401": {
+                        "description": "Authentication required"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "404": {
+                        "description": "Employee not found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Firestore unavailable, retry later"
+                    }
+                }
+            },
+            "put": {
+                "description": "Update an existing employee by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Update an existing employee",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "number",
+                        "description": "Employee ID to be updated",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated employee object",
+                        "name": "employee",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Employee"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Update only if the employee still has this ETag",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Employee updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/models.EmployeeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload"
+                    },
+                    "401": {
+                        "description": "Authentication required"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "404": {
+                        "description": "Employee not found"
+                    },
+                    "409": {
+                        "description": "Email already in use"
+                    },
+                    "412": {
+                        "description": "Employee changed since the given ETag"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Firestore unavailable, retry later"
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete an existing employee by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete an existing employee",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "number",
+                        "description": "Employee ID to be deleted",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Delete only if the employee still has this ETag",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Employee deleted successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid employee ID"
+                    },
+                    "401": {
+                        "description": "Authentication required"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "404": {
+                        "description": "Employee not found"
+                    },
+                    "412": {
+                        "description": "Employee changed since the given ETag"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Firestore unavailable, retry later"
+                    }
+                }
+            },
+            "patch": {
+                "description": "Apply a JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902) to the employee as returned by GET. Only the patched employee is validated and only the changed fields are written, so the password need not be sent. id, deleted and deletedAt cannot be patched",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Partially update an employee",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "number",
+                        "description": "Employee ID to be updated",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Merge patch or JSON Patch document",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Update only if the employee still has this ETag",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Employee updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/models.EmployeeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid employee ID, patch or resulting employee"
+                    },
+                    "401": {
+                        "description": "Authentication required"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "404": {
+                        "description": "Employee not found"
+                    },
+                    "409": {
+                        "description": "Email already in use"
+                    },
+                    "412": {
+                        "description": "Employee changed since the given ETag"
+                    },
+                    "415": {
+                        "description": "Unsupported patch media type"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Firestore unavailable, retry later"
+                    }
+                }
+            }
+        },
+        "/employees/{id}/history": {
+            "get": {
+                "description": "List every change to the employee, oldest first, with the caller that made it and the fields it changed. Password values are redacted. Soft-deleted employees keep their history",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get the change history of an employee",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "number",
+                        "description": "Employee ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/function2.employeeHistory"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid employee ID"
+                    },
+                    "401": {
+                        "description": "Authentication required"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "404": {
+                        "description": "Employee not found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Firestore unavailable, retry later"
+                    }
+                }
+            }
+        },
+        "/employees/{id}/rollback": {
+            "post": {
+                "description": "Make the firstName, lastName, email, role and departmentId of version \"to\" current again. The rollback is written as a new version and recorded in the audit log; the password and the deletion state are kept. Deleted employees must be restored first",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Roll an employee back to an earlier version",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "number",
+                        "description": "Employee ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Version to roll back to",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Roll back only if the employee still has this ETag",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Employee rolled back successfully",
+                        "schema": {
+                            "$ref": "#/definitions/models.EmployeeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid employee ID or version"
+                    },
+                    "401": {
+                        "description": "Authentication required"
+                    },
+                    "403": {
+                        "description": "Forbidden, or the rollback would change a field the caller may not update"
+                    },
+                    "404": {
+                        "description": "Employee or version not found"
+                    },
+                    "409": {
+                        "description": "The version's email is now used by another employee"
+                    },
+                    "412": {
+                        "description": "Employee changed since the given ETag"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Firestore unavailable, retry later"
+                    }
+                }
+            }
+        },
+        "/employees/{id}/versions/{n}": {
+            "get": {
+                "description": "Get the employee as it was at version n. Every write keeps a numbered version; versions written before versions were kept are not found, except the current one. Deleted employees keep their versions",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get a version of an employee",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "number",
+                        "description": "Employee ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Version number",
+                        "name": "n",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.EmployeeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid employee ID or version"
+                    },
+                    "401": {
+                        "description": "Authentication required"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "404": {
+                        "description": "Employee or version not found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Firestore unavailable, retry later"
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "auth.TokenPair": {
+            "type": "object",
+            "properties": {
+                "accessToken": {
+                    "type": "string"
+                },
+                "expiresIn": {
+                    "description": "ExpiresIn is the lifetime of the access token in seconds.",
+                    "type": "integer"
+                },
+                "refreshToken": {
+                    "type": "string"
+                },
+                "tokenType": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.FieldError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code is the check that failed, such as required or email.",
+                    "type": "string"
+                },
+                "field": {
+                    "description": "Field is the JSON name of the field.",
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "function1.employeePage": {
+            "type": "object",
+            "properties": {
+                "employees": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.EmployeeResponse"
+                    }
+                },
+                "nextPageToken": {
+                    "type": "string"
+                }
+            }
+        },
+        "function2.employeeHistory": {
+            "type": "object",
+            "properties": {
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.AuditEntry"
+                    }
+                }
+            }
+        },
+        "function7.credentials": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "function7.refreshRequest": {
+            "type": "object",
+            "properties": {
+                "refreshToken": {
+                    "type": "string"
+                }
+            }
+        },
+        "function8.departmentList": {
+            "type": "object",
+            "properties": {
+                "departments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Department"
+                    }
+                }
+            }
+        },
+        "function8.employeePage": {
+            "type": "object",
+            "properties": {
+                "employees": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.EmployeeResponse"
+                    }
+                },
+                "nextPageToken": {
+                    "type": "string"
+                }
+            }
+        },
+        "importer.Result": {
+            "type": "object",
+            "properties": {
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "imported": {
+                    "description": "Imported is the number of employees stored, or that would have been\nstored by a dry run.",
+                    "type": "integer"
+                },
+                "rejected": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/importer.RowError"
+                    }
+                },
+                "rows": {
+                    "description": "Rows is the number of rows read, not counting the header.",
+                    "type": "integer"
+                }
+            }
+        },
+        "importer.RowError": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.FieldError"
+                    }
+                },
+                "line": {
+                    "description": "Line is the line of the file the row starts on; the header is line 1.",
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Department": {
+            "type": "object",
+            "required": [
+                "code",
+                "name"
+            ],
+            "properties": {
+                "code": {
+                    "description": "Code is the department's short unique name, such as ENG. Codes are\ncompared ignoring case (see NormalizeCode).",
+                    "type": "string",
+                    "maxLength": 20
+                },
+                "costCenter": {
+                    "type": "string"
+                },
+                "headEmployeeId": {
+                    "description": "HeadEmployeeID is the ID of the employee who heads the department, or\nzero for none.",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Employee": {
+            "type": "object",
+            "required": [
+                "email",
+                "firstName",
+                "lastName",
+                "password",
+                "role"
+            ],
+            "properties": {
+                "deleted": {
+                    "type": "boolean"
+                },
+                "deletedAt": {
+                    "type": "string"
+                },
+                "departmentId": {
+                    "description": "DepartmentID is the ID of the employee's department, or zero for none.\nThe repository only stores IDs of existing departments.",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "email": {
+                    "type": "string"
+                },
+                "firstName": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lastName": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string",
+                    "maxLength": 72,
+                    "minLength": 6
+                },
+                "role": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version counts the writes to the employee; the repository increments\nit on every change. It is the employee's ETag.",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.EmployeeResponse": {
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "type": "boolean"
+                },
+                "deletedAt": {
+                    "type": "string"
+                },
+                "departmentId": {
+                    "type": "integer"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "firstName": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lastName": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "repository.Actor": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "employeeId": {
+                    "type": "integer"
+                },
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "repository.AuditEntry": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "$ref": "#/definitions/repository.Actor"
+                },
+                "changes": {
+                    "description": "Changes lists the fields the operation changed, in the order of\nmodels.ChangedFields.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.FieldChange"
+                    }
+                },
+                "employeeId": {
+                    "type": "integer"
+                },
+                "operation": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version is the employee's version after the change.",
+                    "type": "integer"
+                }
+            }
+        },
+        "repository.FieldChange": {
+            "type": "object",
+            "properties": {
+                "after": {},
+                "before": {},
+                "field": {
+                    "type": "string"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "description": "Access token from the login endpoint, as \"Bearer <token>\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -1,35 +1,36 @@
 package function4
 
 import (
-	"context"
 	"encoding/json"
 	"log"
 	"net/http"
 	"strconv"
 
-	"example.com/task3gcp/models"
-	"example.com/task3gcp/utils"
+	"example.com/task3gcp/shared/config"
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/policy"
+	"example.com/task3gcp/shared/repository"
+	"example.com/task3gcp/shared/utils"
 
 	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
-	"github.com/go-playground/validator/v10"
-	"github.com/gorilla/mux"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
-type Employee struct {
-	ID        int    `json:"id"`
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
-	Email     string `json:"email"`
-	Password  string `json:"password"`
-	Role      string `json:"role"`
-}
+// openRepository returns the employee store used by the handler, backed by
+// the Firestore client shared by every invocation. It is a variable so tests
+// can substitute an in-memory repository.
+var openRepository = utils.OpenRepository
 
-var validate = validator.New()
+// UseRepository replaces the Firestore repository, e.g. with an in-memory one
+// when the gateway runs the handlers in local mode.
+func UseRepository(open func() (repository.EmployeeRepository, error)) {
+	openRepository = open
+}
 
 func init() {
+	config.MustLoadInFunction()
 	functions.HTTP("UpdateEmployeeHandler", UpdateEmployeeHandler)
+	functions.HTTP("PatchEmployeeHandler", PatchEmployeeHandler)
+	functions.HTTP("RollbackEmployeeHandler", RollbackEmployeeHandler)
 }
 
 // UpdateEmployeeHandler updates an existing employee by ID.
@@ -38,104 +39,100 @@ func init() {
 // @Accept json
 // @Produce json
 // @Param id path number true "Employee ID to be updated"
-// @Param employee body Employee true "Updated employee object"
-// @Success 200 {object} map[string]string "Employee updated successfully"
+// @Param employee body models.Employee true "Updated employee object"
+// @Param If-Match header string false "Update only if the employee still has this ETag"
+// @Success 200 {object} models.EmployeeResponse "Employee updated successfully"
 // @Failure 400 "Invalid employee ID"
 // @Failure 400 "Invalid request payload"
 // @Failure 404 "Employee not found"
+// @Failure 409 "Email already in use"
+// @Failure 412 "Employee changed since the given ETag"
 // @Failure 500 "Internal Server Error"
+// @Failure 503 "Firestore unavailable, retry later"
+// @Failure 401 "Authentication required"
+// @Failure 403 "Forbidden"
+// @Security BearerAuth
 // @Router /function-4/{id} [put]
 // UpdateEmployee updates the employee details in Firestore based on the provided ID.
 func UpdateEmployeeHandler(w http.ResponseWriter, r *http.Request) {
 	utils.InitLogger()
 	log.Print("Request is being Processed for UpdateEmployeeHandler")
 
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
+	id, err := strconv.Atoi(utils.EmployeeID(r))
 	if err != nil {
 		log.Print("Invalid employee ID:", err)
-		respondWithError(w, http.StatusBadRequest, "Invalid employee ID")
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid employee ID")
 		return
 	}
 
 	log.Print("Request received: UpdateEmployeeHandler, ID:", id)
 
+	rule, ok := utils.Authorize(w, r, policy.Update, id)
+	if !ok {
+		return
+	}
+
 	var updatedEmployee models.Employee
 	err = json.NewDecoder(r.Body).Decode(&updatedEmployee)
 	if err != nil {
 		log.Print("Invalid request payload:", err)
-		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
 
 	log.Print("Request payload decoded")
 
 	// Validate input data
-	if err := validate.Struct(updatedEmployee); err != nil {
+	if err := updatedEmployee.Validate(); err != nil {
 		log.Print("Validation error:", err)
-		respondWithError(w, http.StatusBadRequest, err.Error())
+		utils.RespondWithDomainError(w, r, err, "Invalid employee")
 		return
 	}
 
 	log.Print("Input data validated")
 
-	client, err := utils.CreateFirestoreClient()
+	repo, err := openRepository()
 	if err != nil {
 		log.Print("Failed to create Firestore client:", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		utils.RespondWithDomainError(w, r, err, "Failed to create Firestore client")
 		return
 	}
-	defer client.Close()
+	defer repo.Close()
 
 	log.Print("Firestore client created")
 
-	// Define a query to retrieve the document with the specified "ID" field value
-	query := client.Collection("employees").Where("ID", "==", id).Limit(1)
-
-	// Run the query
-	iter := query.Documents(context.Background())
-	doc, err := iter.Next()
+	// Exclude ID field from the updated data
+	updatedEmployee.ID = id
 
+	// Some roles may update their own record but not every field of it
+	existing, err := repo.Get(r.Context(), id)
+	if err == nil && existing.Deleted {
+		err = repository.ErrNotFound
+	}
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			log.Print("Employee not found:", err)
-			respondWithError(w, http.StatusNotFound, "Employee not found")
-			return
-		}
 		log.Print("Failed to retrieve employee from Firestore:", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve employee from Firestore")
+		utils.RespondWithDomainError(w, r, err, "Failed to retrieve employee from Firestore")
+		return
+	}
+	if err := rule.CheckUpdate(existing, updatedEmployee); err != nil {
+		log.Print("Request denied by access policy:", err)
+		utils.RespondWithError(w, r, http.StatusForbidden, err.Error())
+		return
+	}
+	if updatedEmployee.Version, ok = utils.CheckIfMatch(w, r, existing); !ok {
 		return
 	}
 
-	log.Print("Employee retrieved from Firestore")
-
-	// Exclude ID field from the updated data
-	updatedEmployee.ID = id
-
-	_, err = doc.Ref.Set(context.Background(), updatedEmployee)
+	updatedEmployee, err = repo.Update(utils.CallerContext(r), updatedEmployee)
 	if err != nil {
 		log.Print("Failed to update employee in Firestore:", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to update employee in Firestore")
+		utils.RespondWithDomainError(w, r, err, "Failed to update employee in Firestore")
 		return
 	}
 
 	log.Print("Employee updated successfully in Firestore")
+	utils.SetETag(w, updatedEmployee)
 
-	respondWithJSON(w, http.StatusOK, updatedEmployee)
+	utils.RespondWithJSON(w, http.StatusOK, updatedEmployee.Response())
 	log.Print("Response Sent: UpdateEmployeeHandler")
 }
-
-func respondWithError(w http.ResponseWriter, code int, message string) {
-	respondWithJSON(w, code, map[string]string{"error": message})
-}
-
-func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
-	response, err := json.Marshal(payload)
-	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	w.Write(response)
-}
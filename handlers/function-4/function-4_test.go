@@ -0,0 +1,373 @@
+package function4
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/repository"
+	"example.com/task3gcp/shared/utils"
+	"github.com/gorilla/mux"
+)
+
+func useRepository(t *testing.T, repo repository.EmployeeRepository) {
+	t.Helper()
+	original := openRepository
+	openRepository = func() (repository.EmployeeRepository, error) { return repo, nil }
+	t.Cleanup(func() { openRepository = original })
+}
+
+// testKey signs the access tokens of test requests; TestMain makes the
+// handlers verify them, as they verify the tokens the gateway forwards.
+var testKey ed25519.PrivateKey
+
+func TestMain(m *testing.M) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		panic(err)
+	}
+	testKey = key
+	utils.UseVerifier(auth.NewVerifier(key.Public().(ed25519.PublicKey)))
+	os.Exit(m.Run())
+}
+
+// newRequest returns a request carrying the access token the gateway
+// forwards for an authenticated admin.
+func newRequest(method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	setCaller(req, 1, "admin")
+	return req
+}
+
+// setCaller makes req carry an access token for the employee with the given
+// ID and role.
+func setCaller(req *http.Request, id int, role string) {
+	pair, err := auth.NewIssuer(testKey).Issue(models.Employee{ID: id, Role: role})
+	if err != nil {
+		panic(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+}
+
+func TestUpdateEmployeeHandler(t *testing.T) {
+	repo := repository.NewMemoryRepository(models.Employee{ID: 5, FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Password: "secret1", Role: "employee"})
+	useRepository(t, repo)
+
+	body := `{"firstName":"Ada","lastName":"King","email":"ada@example.com","password":"secret1","role":"manager"}`
+	tests := []struct {
+		name string
+		id   string
+		body string
+		want int
+	}{
+		{"updated", "5", body, http.StatusOK},
+		{"not found", "6", body, http.StatusNotFound},
+		{"invalid id", "x", body, http.StatusBadRequest},
+		{"invalid payload", "5", `{"firstName":"Ada"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newRequest(http.MethodPut, "/"+tt.id, strings.NewReader(tt.body))
+			req = mux.SetURLVars(req, map[string]string{"id": tt.id})
+			rec := httptest.NewRecorder()
+			UpdateEmployeeHandler(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if strings.Contains(rec.Body.String(), `"password":`) {
+				t.Errorf("response exposes the password: %s", rec.Body)
+			}
+		})
+	}
+
+	employee, err := repo.Get(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("Get(5): %v", err)
+	}
+	if employee.LastName != "King" || employee.Role != "manager" {
+		t.Errorf("stored employee = %+v", employee)
+	}
+	if employee.Password == "secret1" || !employee.CheckPassword("secret1") {
+		t.Errorf("stored password = %q, want a bcrypt hash", employee.Password)
+	}
+}
+
+func TestUpdateEmployeeHandlerPolicy(t *testing.T) {
+	repo := repository.NewMemoryRepository(
+		models.Employee{ID: 5, FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Password: "secret1", Role: "employee", Version: 1},
+		models.Employee{ID: 6, FirstName: "Alan", LastName: "Turing", Email: "alan@example.com", Password: "secret1", Role: "employee"},
+	)
+	useRepository(t, repo)
+
+	tests := []struct {
+		name string
+		id   string
+		role string
+		body string
+		want int
+	}{
+		{"own record", "5", "employee", `{"firstName":"Ada","lastName":"King","email":"ada@example.com","password":"secret1","role":"employee"}`, http.StatusOK},
+		{"own role", "5", "employee", `{"firstName":"Ada","lastName":"King","email":"ada@example.com","password":"secret1","role":"admin"}`, http.StatusForbidden},
+		{"other record", "6", "employee", `{"firstName":"Alan","lastName":"King","email":"alan@example.com","password":"secret1","role":"employee"}`, http.StatusForbidden},
+		{"hr grants admin", "6", "hr", `{"firstName":"Alan","lastName":"Turing","email":"alan@example.com","password":"secret1","role":"admin"}`, http.StatusForbidden},
+		{"anonymous", "5", "", `{"firstName":"Ada","lastName":"King","email":"ada@example.com","password":"secret1","role":"employee"}`, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/"+tt.id, strings.NewReader(tt.body))
+			req = mux.SetURLVars(req, map[string]string{"id": tt.id})
+			if tt.role != "" {
+				setCaller(req, 5, tt.role)
+			}
+			rec := httptest.NewRecorder()
+			UpdateEmployeeHandler(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+
+	if employee, _ := repo.Get(context.Background(), 5); employee.Role != "employee" {
+		t.Errorf("employee 5 role = %q, want employee", employee.Role)
+	}
+}
+
+func TestPatchEmployeeHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        int
+		wantRole    string
+	}{
+		{"merge patch", "application/merge-patch+json", `{"role":"manager"}`, http.StatusOK, "manager"},
+		{"json patch", "application/json-patch+json", `[{"op":"test","path":"/role","value":"employee"},{"op":"replace","path":"/role","value":"manager"}]`, http.StatusOK, "manager"},
+		{"failed test", "application/json-patch+json", `[{"op":"test","path":"/role","value":"admin"},{"op":"replace","path":"/role","value":"manager"}]`, http.StatusBadRequest, "employee"},
+		{"invalid result", "application/merge-patch+json", `{"email":"ada"}`, http.StatusBadRequest, "employee"},
+		{"removed field", "application/merge-patch+json", `{"lastName":null}`, http.StatusBadRequest, "employee"},
+		{"unknown field", "application/merge-patch+json", `{"salary":1}`, http.StatusBadRequest, "employee"},
+		{"read-only field", "application/merge-patch+json", `{"deleted":true}`, http.StatusBadRequest, "employee"},
+		{"plain json", "application/json", `{"role":"manager"}`, http.StatusUnsupportedMediaType, "employee"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryRepository(models.Employee{ID: 5, FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Password: "secret1", Role: "employee"})
+			useRepository(t, repo)
+
+			req := newRequest(http.MethodPatch, "/5", strings.NewReader(tt.body))
+			req = mux.SetURLVars(req, map[string]string{"id": "5"})
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			PatchEmployeeHandler(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			employee, _ := repo.Get(context.Background(), 5)
+			if employee.Role != tt.wantRole || employee.LastName != "Lovelace" || employee.Deleted {
+				t.Errorf("stored employee = %+v, want role %s and nothing else changed", employee, tt.wantRole)
+			}
+			if employee.Password != "secret1" {
+				t.Errorf("stored password = %q, want it unchanged", employee.Password)
+			}
+		})
+	}
+}
+
+func TestPatchEmployeeHandlerDetail(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		detail      string
+	}{
+		{"failed test", "application/json-patch+json", `[{"op":"test","path":"/role","value":"admin"}]`, "invalid JSON Patch: "},
+		{"malformed patch", "application/merge-patch+json", `{"role":`, "invalid merge patch: "},
+		{"unknown field", "application/merge-patch+json", `{"salary":1}`, `invalid merge patch result: json: unknown field "salary"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useRepository(t, repository.NewMemoryRepository(models.Employee{ID: 5, FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Password: "secret1", Role: "employee"}))
+
+			req := newRequest(http.MethodPatch, "/5", strings.NewReader(tt.body))
+			req = mux.SetURLVars(req, map[string]string{"id": "5"})
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			PatchEmployeeHandler(rec, req)
+
+			var problem utils.Problem
+			if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+				t.Fatalf("decode problem: %v", err)
+			}
+			if rec.Code != http.StatusBadRequest || !strings.HasPrefix(problem.Detail, tt.detail) {
+				t.Errorf("status = %d, detail = %q, want 400 and a detail starting with %q", rec.Code, problem.Detail, tt.detail)
+			}
+		})
+	}
+}
+
+func TestPatchEmployeeHandlerPassword(t *testing.T) {
+	repo := repository.NewMemoryRepository(models.Employee{ID: 5, FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Password: "secret1", Role: "employee"})
+	useRepository(t, repo)
+
+	req := newRequest(http.MethodPatch, "/5", strings.NewReader(`{"password":"secret2"}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "5"})
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	rec := httptest.NewRecorder()
+	PatchEmployeeHandler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), `"password":`) {
+		t.Errorf("response exposes the password: %s", rec.Body)
+	}
+	if employee, _ := repo.Get(context.Background(), 5); !employee.CheckPassword("secret2") {
+		t.Errorf("stored password = %q, want a bcrypt hash of secret2", employee.Password)
+	}
+}
+
+func TestUpdateEmployeeHandlerIfMatch(t *testing.T) {
+	repo := repository.NewMemoryRepository(models.Employee{ID: 5, FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Password: "secret1", Role: "employee", Version: 4})
+	useRepository(t, repo)
+
+	update := func(handler http.HandlerFunc, method, contentType, body, ifMatch string) *httptest.ResponseRecorder {
+		req := mux.SetURLVars(newRequest(method, "/5", strings.NewReader(body)), map[string]string{"id": "5"})
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("If-Match", ifMatch)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+	body := `{"firstName":"Ada","lastName":"King","email":"ada@example.com","password":"secret1","role":"employee"}`
+
+	if rec := update(UpdateEmployeeHandler, http.MethodPut, "application/json", body, `"3"`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("PUT with a stale ETag: status = %d, want %d", rec.Code, http.StatusPreconditionFailed)
+	}
+	rec := update(UpdateEmployeeHandler, http.MethodPut, "application/json", body, `"4"`)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"5"` {
+		t.Fatalf("PUT with the current ETag: status = %d, ETag = %s, want 200 and \"5\"", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	// The first writer wins; the second still holds version 4.
+	if rec := update(PatchEmployeeHandler, http.MethodPatch, "application/merge-patch+json", `{"role":"manager"}`, `"4"`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("PATCH with a stale ETag: status = %d, want %d", rec.Code, http.StatusPreconditionFailed)
+	}
+	rec = update(PatchEmployeeHandler, http.MethodPatch, "application/merge-patch+json", `{"role":"manager"}`, `"5"`)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"6"` {
+		t.Fatalf("PATCH with the current ETag: status = %d, ETag = %s, want 200 and \"6\"", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	employee, _ := repo.Get(context.Background(), 5)
+	if employee.LastName != "King" || employee.Role != "manager" || employee.Version != 6 {
+		t.Errorf("stored employee = %+v, want both writes at version 6", employee)
+	}
+}
+
+// racingRepository changes the employee's role right after the handler has
+// read it, as a concurrent request would.
+type racingRepository struct {
+	*repository.MemoryRepository
+	raced bool
+}
+
+func (r *racingRepository) Get(ctx context.Context, id int) (models.Employee, error) {
+	employee, err := r.MemoryRepository.Get(ctx, id)
+	if err == nil && !r.raced {
+		r.raced = true
+		_, err = r.MemoryRepository.Patch(ctx, models.Employee{ID: id, Role: "admin"}, []string{"role"})
+	}
+	return employee, err
+}
+
+func TestUpdateEmployeeHandlerConcurrentChange(t *testing.T) {
+	body := `{"firstName":"Ada","lastName":"King","email":"ada@example.com","password":"secret1","role":"employee"}`
+	for name, handler := range map[string]http.HandlerFunc{"PUT": UpdateEmployeeHandler, "PATCH": PatchEmployeeHandler} {
+		t.Run(name, func(t *testing.T) {
+			repo := &racingRepository{MemoryRepository: repository.NewMemoryRepository(
+				models.Employee{ID: 5, FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Password: "secret1", Role: "employee", Version: 1},
+			)}
+			useRepository(t, repo)
+
+			req := newRequest(name, "/5", strings.NewReader(body))
+			if name == "PATCH" {
+				req = newRequest(name, "/5", strings.NewReader(`{"lastName":"King"}`))
+				req.Header.Set("Content-Type", "application/merge-patch+json")
+			}
+			setCaller(req, 5, "employee")
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			// Without If-Match the write is still conditioned on the version read
+			if rec.Code != http.StatusPreconditionFailed {
+				t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusPreconditionFailed, rec.Body)
+			}
+			if employee, _ := repo.MemoryRepository.Get(context.Background(), 5); employee.Role != "admin" || employee.LastName != "Lovelace" {
+				t.Errorf("stored employee = %+v, want the concurrent change kept", employee)
+			}
+		})
+	}
+}
+
+func TestRollbackEmployeeHandler(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	useRepository(t, repo)
+	created, err := repo.Create(context.Background(), models.Employee{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Password: "secret1", Role: "employee"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Patch(context.Background(), models.Employee{ID: created.ID, LastName: "King", Role: "manager"}, []string{"lastName", "role"}); err != nil {
+		t.Fatal(err)
+	}
+
+	rollback := func(target, ifMatch, role string) *httptest.ResponseRecorder {
+		req := newRequest(http.MethodPost, target, nil)
+		setCaller(req, 1, role)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		RollbackEmployeeHandler(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name       string
+		target     string
+		ifMatch    string
+		role       string
+		wantStatus int
+	}{
+		{"missing version", "/1", "", "admin", http.StatusBadRequest},
+		{"invalid version", "/1?to=first", "", "admin", http.StatusBadRequest},
+		{"unknown version", "/1?to=9", "", "admin", http.StatusNotFound},
+		{"unknown employee", "/99?to=1", "", "admin", http.StatusNotFound},
+		{"stale ETag", "/1?to=1", `"1"`, "admin", http.StatusPreconditionFailed},
+		{"manager", "/1?to=1", "", "manager", http.StatusForbidden},
+		{"hr restoring a role", "/1?to=1", `"2"`, "hr", http.StatusForbidden},
+		{"success", "/1?to=1", `"2"`, "admin", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := rollback(tt.target, tt.ifMatch, tt.role)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+
+	employee, _ := repo.Get(context.Background(), created.ID)
+	if employee.LastName != "Lovelace" || employee.Role != "employee" || employee.Version != 3 {
+		t.Errorf("stored employee = %+v, want version 1 restored as version 3", employee)
+	}
+}
@@ -0,0 +1,179 @@
+package function4
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"example.com/task3gcp/shared/domain"
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/policy"
+	"example.com/task3gcp/shared/repository"
+	"example.com/task3gcp/shared/utils"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+// Patch document media types accepted by PatchEmployeeHandler.
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// maxPatchSize caps the size of a patch document.
+const maxPatchSize = 1 << 20
+
+// PatchEmployeeHandler changes some fields of an existing employee by ID.
+// @Summary Partially update an employee
+// @Description Apply a JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902) to the employee as returned by GET. Only the patched employee is validated and only the changed fields are written, so the password need not be sent. id, deleted and deletedAt cannot be patched
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param id path number true "Employee ID to be updated"
+// @Param patch body object true "Merge patch or JSON Patch document"
+// @Param If-Match header string false "Update only if the employee still has this ETag"
+// @Success 200 {object} models.EmployeeResponse "Employee updated successfully"
+// @Failure 400 "Invalid employee ID, patch or resulting employee"
+// @Failure 404 "Employee not found"
+// @Failure 409 "Email already in use"
+// @Failure 412 "Employee changed since the given ETag"
+// @Failure 415 "Unsupported patch media type"
+// @Failure 500 "Internal Server Error"
+// @Failure 503 "Firestore unavailable, retry later"
+// @Failure 401 "Authentication required"
+// @Failure 403 "Forbidden"
+// @Security BearerAuth
+// @Router /function-4/{id} [patch]
+func PatchEmployeeHandler(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+	log.Print("Request is being Processed for PatchEmployeeHandler")
+
+	id, err := strconv.Atoi(utils.EmployeeID(r))
+	if err != nil {
+		log.Print("Invalid employee ID:", err)
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid employee ID")
+		return
+	}
+
+	log.Print("Request received: PatchEmployeeHandler, ID:", id)
+
+	rule, ok := utils.Authorize(w, r, policy.Update, id)
+	if !ok {
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != mergePatchType && mediaType != jsonPatchType {
+		utils.RespondWithError(w, r, http.StatusUnsupportedMediaType, "Content-Type must be "+mergePatchType+" or "+jsonPatchType)
+		return
+	}
+	patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchSize))
+	if err != nil {
+		log.Print("Invalid request payload:", err)
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	repo, err := openRepository()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to create Firestore client")
+		return
+	}
+	defer repo.Close()
+
+	existing, err := repo.Get(r.Context(), id)
+	if err == nil && existing.Deleted {
+		err = repository.ErrNotFound
+	}
+	if err != nil {
+		log.Print("Failed to retrieve employee from Firestore:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to retrieve employee from Firestore")
+		return
+	}
+
+	patched, err := applyPatch(existing, mediaType, patch)
+	if err != nil {
+		log.Print("Invalid patch:", err)
+		utils.RespondWithDomainError(w, r, err, "Invalid patch")
+		return
+	}
+	fields := models.ChangedFields(existing, patched)
+	log.Print("Patch changes fields:", fields)
+
+	// Only the result must be a valid employee
+	if err := patched.Validate(); err != nil {
+		log.Print("Validation error:", err)
+		utils.RespondWithDomainError(w, r, err, "Invalid employee")
+		return
+	}
+	if err := rule.CheckUpdate(existing, patched); err != nil {
+		log.Print("Request denied by access policy:", err)
+		utils.RespondWithError(w, r, http.StatusForbidden, err.Error())
+		return
+	}
+	version, ok := utils.CheckIfMatch(w, r, existing)
+	if !ok {
+		return
+	}
+
+	if len(fields) > 0 {
+		patched.Version = version
+		patched, err = repo.Patch(utils.CallerContext(r), patched, fields)
+		if err != nil {
+			log.Print("Failed to patch employee in Firestore:", err)
+			utils.RespondWithDomainError(w, r, err, "Failed to patch employee in Firestore")
+			return
+		}
+	}
+
+	log.Print("Employee patched successfully in Firestore")
+	utils.SetETag(w, patched)
+
+	utils.RespondWithJSON(w, http.StatusOK, patched.Response())
+	log.Print("Response Sent: PatchEmployeeHandler")
+}
+
+// applyPatch applies a patch document of the given media type to the API
+// representation of employee. The representation has no password, so the
+// stored hash is kept unless the patch sets a new password.
+func applyPatch(employee models.Employee, mediaType string, patch []byte) (models.Employee, error) {
+	original, err := json.Marshal(employee.Response())
+	if err != nil {
+		return models.Employee{}, err
+	}
+
+	var document []byte
+	format := "JSON Patch"
+	if mediaType == mergePatchType {
+		format = "merge patch"
+		document, err = jsonpatch.MergePatch(original, patch)
+	} else {
+		var ops jsonpatch.Patch
+		if ops, err = jsonpatch.DecodePatch(patch); err == nil {
+			document, err = ops.Apply(original)
+		}
+	}
+	if err != nil {
+		return models.Employee{}, domain.New(domain.Validation, "invalid "+format+": "+err.Error())
+	}
+
+	var patched models.Employee
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		return models.Employee{}, domain.New(domain.Validation, "invalid "+format+" result: "+err.Error())
+	}
+	var set map[string]json.RawMessage
+	if err := json.Unmarshal(document, &set); err != nil {
+		return models.Employee{}, domain.New(domain.Validation, "invalid "+format+" result: "+err.Error())
+	}
+	if _, ok := set["password"]; !ok {
+		patched.Password = employee.Password
+	}
+	return patched, nil
+}
@@ -0,0 +1,107 @@
+package function4
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"example.com/task3gcp/shared/policy"
+	"example.com/task3gcp/shared/repository"
+	"example.com/task3gcp/shared/utils"
+)
+
+// RollbackEmployeeHandler restores an earlier version of an employee, e.g. to
+// undo an accidental bulk edit.
+// @Summary Roll an employee back to an earlier version
+// @Description Make the firstName, lastName, email, role and departmentId of version "to" current again. The rollback is written as a new version and recorded in the audit log; the password and the deletion state are kept. Deleted employees must be restored first
+// @Produce json
+// @Param id path number true "Employee ID"
+// @Param to query number true "Version to roll back to"
+// @Param If-Match header string false "Roll back only if the employee still has this ETag"
+// @Success 200 {object} models.EmployeeResponse "Employee rolled back successfully"
+// @Failure 400 "Invalid employee ID or version"
+// @Failure 404 "Employee or version not found"
+// @Failure 409 "The version's email is now used by another employee"
+// @Failure 412 "Employee changed since the given ETag"
+// @Failure 500 "Internal Server Error"
+// @Failure 503 "Firestore unavailable, retry later"
+// @Failure 401 "Authentication required"
+// @Failure 403 "Forbidden, or the rollback would change a field the caller may not update"
+// @Security BearerAuth
+// @Router /function-4/{id}/rollback [post]
+func RollbackEmployeeHandler(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+	log.Print("Request is being Processed for RollbackEmployeeHandler")
+
+	id, err := strconv.Atoi(utils.EmployeeID(r))
+	if err != nil {
+		log.Print("Invalid employee ID:", err)
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid employee ID")
+		return
+	}
+	to, err := strconv.Atoi(r.URL.Query().Get("to"))
+	if err != nil || to < 1 {
+		log.Print("Invalid version:", r.URL.Query().Get("to"))
+		utils.RespondWithError(w, r, http.StatusBadRequest, "to must be a version number")
+		return
+	}
+
+	log.Printf("Request received: RollbackEmployeeHandler, ID: %d, to version %d", id, to)
+
+	if _, ok := utils.Authorize(w, r, policy.Rollback, id); !ok {
+		return
+	}
+	// A rollback rewrites the restored fields, so the caller's update rule
+	// decides which of them it may change
+	rule, ok := utils.Authorize(w, r, policy.Update, id)
+	if !ok {
+		return
+	}
+
+	repo, err := openRepository()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to create Firestore client")
+		return
+	}
+	defer repo.Close()
+
+	existing, err := repo.Get(r.Context(), id)
+	if err == nil && existing.Deleted {
+		err = repository.ErrNotFound
+	}
+	if err != nil {
+		log.Print("Failed to retrieve employee from Firestore:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to retrieve employee from Firestore")
+		return
+	}
+	version, ok := utils.CheckIfMatch(w, r, existing)
+	if !ok {
+		return
+	}
+
+	target, err := repo.Version(r.Context(), id, to)
+	if err != nil {
+		log.Print("Failed to retrieve employee version from Firestore:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to retrieve employee version from Firestore")
+		return
+	}
+	if err := rule.CheckUpdate(existing, target); err != nil {
+		log.Print("Request denied by access policy:", err)
+		utils.RespondWithError(w, r, http.StatusForbidden, err.Error())
+		return
+	}
+
+	employee, err := repo.Rollback(utils.CallerContext(r), id, to, version)
+	if err != nil {
+		log.Print("Failed to roll back employee in Firestore:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to roll back employee in Firestore")
+		return
+	}
+
+	log.Print("Employee rolled back successfully in Firestore")
+	utils.SetETag(w, employee)
+
+	utils.RespondWithJSON(w, http.StatusOK, employee.Response())
+	log.Print("Response Sent: RollbackEmployeeHandler")
+}
@@ -1,92 +1,99 @@
 package function5
 
 import (
-	"context"
-	"encoding/json"
 	"log"
 	"net/http"
 	"strconv"
 
-	"example.com/task3gcp/utils"
-	"github.com/gorilla/mux"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"example.com/task3gcp/shared/config"
+	"example.com/task3gcp/shared/policy"
+	"example.com/task3gcp/shared/repository"
+	"example.com/task3gcp/shared/utils"
+	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
 )
 
-// DeleteEmployeeHandler deletes an existing employee by ID.
+func init() {
+	config.MustLoadInFunction()
+	functions.HTTP("DeleteEmployeeHandler", DeleteEmployeeHandler)
+	functions.HTTP("RestoreEmployeeHandler", RestoreEmployeeHandler)
+	functions.HTTP("PurgeEmployeesHandler", PurgeEmployeesHandler)
+}
+
+// openRepository returns the employee store used by the handler, backed by
+// the Firestore client shared by every invocation. It is a variable so tests
+// can substitute an in-memory repository.
+var openRepository = utils.OpenRepository
+
+// UseRepository replaces the Firestore repository, e.g. with an in-memory one
+// when the gateway runs the handlers in local mode.
+func UseRepository(open func() (repository.EmployeeRepository, error)) {
+	openRepository = open
+}
+
+// DeleteEmployeeHandler soft-deletes an existing employee by ID.
 // @Summary Delete an existing employee
-// @Description Delete an existing employee by ID
+// @Description Soft-delete an existing employee by ID. The record is kept with deleted=true until it is restored or purged
 // @Accept json
 // @Produce json
 // @Param id path number true "Employee ID to be deleted"
+// @Param If-Match header string false "Delete only if the employee still has this ETag"
 // @Success 200 {object} map[string]string "Employee deleted successfully"
 // @Failure 400 "Invalid employee ID"
 // @Failure 404 "Employee not found"
+// @Failure 412 "Employee changed since the given ETag"
 // @Failure 500 "Internal Server Error"
+// @Failure 503 "Firestore unavailable, retry later"
+// @Failure 401 "Authentication required"
+// @Failure 403 "Forbidden"
+// @Security BearerAuth
 // @Router /function-5/{id} [delete]
 func DeleteEmployeeHandler(w http.ResponseWriter, r *http.Request) {
 	utils.InitLogger()
 	utils.InfoLog("Request is being Processed for DeleteEmployeeHandler")
 
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
+	id, err := strconv.Atoi(utils.EmployeeID(r))
 	if err != nil {
 		log.Print("Invalid employee ID:", err)
-		respondWithError(w, http.StatusBadRequest, "Invalid employee ID")
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid employee ID")
 		return
 	}
 
-	client, err := utils.CreateFirestoreClient()
+	if _, ok := utils.Authorize(w, r, policy.Delete, id); !ok {
+		return
+	}
+
+	repo, err := openRepository()
 	if err != nil {
 		log.Print("Failed to create Firestore client:", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		utils.RespondWithDomainError(w, r, err, "Failed to create Firestore client")
 		return
 	}
-	defer client.Close()
+	defer repo.Close()
 
 	utils.InfoLog("Request received: DeleteEmployeeHandler")
 
-	// Define a query to retrieve the document with the specified "ID" field value
-	query := client.Collection("employees").Where("ID", "==", id).Limit(1)
-
-	// Run the query
-	iter := query.Documents(context.Background())
-	doc, err := iter.Next()
-
+	existing, err := repo.Get(r.Context(), id)
+	if err == nil && existing.Deleted {
+		err = repository.ErrNotFound
+	}
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			utils.InfoLog("Employee not found")
-			respondWithError(w, http.StatusNotFound, "Employee not found")
-			return
-		}
 		log.Print("Failed to retrieve employee from Firestore:", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve employee from Firestore")
+		utils.RespondWithDomainError(w, r, err, "Failed to retrieve employee from Firestore")
+		return
+	}
+	version, ok := utils.CheckIfMatch(w, r, existing)
+	if !ok {
 		return
 	}
 
-	_, err = doc.Ref.Delete(context.Background())
+	err = repo.Delete(utils.CallerContext(r), id, version)
 	if err != nil {
 		log.Print("Failed to delete employee from Firestore:", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to delete employee from Firestore")
+		utils.RespondWithDomainError(w, r, err, "Failed to delete employee from Firestore")
 		return
 	}
 
 	utils.InfoLog("Employee deleted successfully")
-	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Employee deleted successfully"})
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Employee deleted successfully"})
 	utils.InfoLog("Response Sent")
 }
-
-func respondWithError(w http.ResponseWriter, code int, message string) {
-	respondWithJSON(w, code, map[string]string{"error": message})
-}
-
-func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
-	response, err := json.Marshal(payload)
-	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	w.Write(response)
-}
@@ -0,0 +1,224 @@
+package function5
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/repository"
+	"example.com/task3gcp/shared/utils"
+	"github.com/gorilla/mux"
+)
+
+func useRepository(t *testing.T, repo repository.EmployeeRepository) {
+	t.Helper()
+	original := openRepository
+	openRepository = func() (repository.EmployeeRepository, error) { return repo, nil }
+	t.Cleanup(func() { openRepository = original })
+}
+
+// testKey signs the access tokens of test requests; TestMain makes the
+// handlers verify them, as they verify the tokens the gateway forwards.
+var testKey ed25519.PrivateKey
+
+func TestMain(m *testing.M) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		panic(err)
+	}
+	testKey = key
+	utils.UseVerifier(auth.NewVerifier(key.Public().(ed25519.PublicKey)))
+	os.Exit(m.Run())
+}
+
+// newRequest returns a request carrying the access token the gateway
+// forwards for an authenticated admin.
+func newRequest(method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	setCaller(req, 1, "admin")
+	return req
+}
+
+// setCaller makes req carry an access token for the employee with the given
+// ID and role.
+func setCaller(req *http.Request, id int, role string) {
+	pair, err := auth.NewIssuer(testKey).Issue(models.Employee{ID: id, Role: role})
+	if err != nil {
+		panic(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+}
+
+func TestDeleteEmployeeHandler(t *testing.T) {
+	repo := repository.NewMemoryRepository(models.Employee{ID: 9, FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Role: "admin"})
+	useRepository(t, repo)
+
+	tests := []struct {
+		name string
+		id   string
+		want int
+	}{
+		{"deleted", "9", http.StatusOK},
+		{"already deleted", "9", http.StatusNotFound},
+		{"invalid id", "nine", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := mux.SetURLVars(newRequest(http.MethodDelete, "/"+tt.id, nil), map[string]string{"id": tt.id})
+			rec := httptest.NewRecorder()
+			DeleteEmployeeHandler(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+
+	employee, err := repo.Get(context.Background(), 9)
+	if err != nil {
+		t.Fatalf("Get(9) after soft delete: %v", err)
+	}
+	if !employee.Deleted || employee.DeletedAt == nil {
+		t.Errorf("employee after delete = %+v, want Deleted with DeletedAt set", employee)
+	}
+}
+
+func TestDeleteEmployeeHandlerIfMatch(t *testing.T) {
+	repo := repository.NewMemoryRepository(models.Employee{ID: 9, FirstName: "Ada", Version: 2})
+	useRepository(t, repo)
+
+	for _, tt := range []struct {
+		ifMatch string
+		want    int
+	}{
+		{`"1"`, http.StatusPreconditionFailed},
+		{`W/"2"`, http.StatusPreconditionFailed},
+		{`"2"`, http.StatusOK},
+	} {
+		req := mux.SetURLVars(newRequest(http.MethodDelete, "/9", nil), map[string]string{"id": "9"})
+		req.Header.Set("If-Match", tt.ifMatch)
+		rec := httptest.NewRecorder()
+		DeleteEmployeeHandler(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("If-Match %s: status = %d, want %d", tt.ifMatch, rec.Code, tt.want)
+		}
+	}
+
+	if employee, _ := repo.Get(context.Background(), 9); !employee.Deleted || employee.Version != 3 {
+		t.Errorf("employee after delete = %+v, want deleted at version 3", employee)
+	}
+}
+
+func TestRestoreEmployeeHandler(t *testing.T) {
+	repo := repository.NewMemoryRepository(
+		models.Employee{ID: 1, FirstName: "Ada"},
+		models.Employee{ID: 2, FirstName: "Alan"},
+	)
+	useRepository(t, repo)
+	if err := repo.Delete(context.Background(), 1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		want int
+	}{
+		{"restored", "1", http.StatusOK},
+		{"not deleted", "2", http.StatusConflict},
+		{"not found", "3", http.StatusNotFound},
+		{"invalid id", "one", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := mux.SetURLVars(newRequest(http.MethodPost, "/"+tt.id+"/restore", nil), map[string]string{"id": tt.id})
+			rec := httptest.NewRecorder()
+			RestoreEmployeeHandler(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+
+	if employee, _ := repo.Get(context.Background(), 1); employee.Deleted {
+		t.Errorf("employee 1 is still deleted after restore")
+	}
+}
+
+func TestPurgeEmployeesHandler(t *testing.T) {
+	t.Setenv("PURGE_RETENTION_DAYS", "2")
+	deletedAt := time.Now().AddDate(0, 0, -3)
+	repo := repository.NewMemoryRepository(
+		models.Employee{ID: 1, Deleted: true, DeletedAt: &deletedAt},
+		models.Employee{ID: 2},
+	)
+	useRepository(t, repo)
+
+	tests := []struct {
+		name   string
+		role   string
+		query  string
+		want   int
+		purged string
+	}{
+		{"anonymous", "", "", http.StatusUnauthorized, ""},
+		{"not admin", "hr", "", http.StatusForbidden, ""},
+		{"invalid retention", "admin", "?olderThanDays=-1", http.StatusBadRequest, ""},
+		{"shorter than the retention period", "admin", "?olderThanDays=0", http.StatusBadRequest, ""},
+		{"within a longer retention period", "admin", "?olderThanDays=4", http.StatusOK, `{"purged":0}`},
+		{"purged", "admin", "", http.StatusOK, `{"purged":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newRequest(http.MethodPost, "/purge"+tt.query, nil)
+			if tt.role == "" {
+				req.Header.Del("Authorization")
+			} else {
+				setCaller(req, 1, tt.role)
+			}
+			rec := httptest.NewRecorder()
+			PurgeEmployeesHandler(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); tt.purged != "" && got != tt.purged {
+				t.Errorf("body = %s, want %s", got, tt.purged)
+			}
+		})
+	}
+
+	if _, err := repo.Get(context.Background(), 1); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Get(1) after purge: err = %v, want ErrNotFound", err)
+	}
+	if _, err := repo.Get(context.Background(), 2); err != nil {
+		t.Errorf("Get(2) after purge: %v", err)
+	}
+}
+
+func TestRestoreEmployeeHandlerFromPath(t *testing.T) {
+	repo := repository.NewMemoryRepository(models.Employee{ID: 7})
+	useRepository(t, repo)
+	if err := repo.Delete(context.Background(), 7, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// The gateway forwards /function-5/7/restore to <function-5-restore>/7.
+	rec := httptest.NewRecorder()
+	RestoreEmployeeHandler(rec, newRequest(http.MethodPost, "/function-5-restore/7", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+}
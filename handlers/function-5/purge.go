@@ -0,0 +1,97 @@
+package function5
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"example.com/task3gcp/shared/policy"
+	"example.com/task3gcp/shared/utils"
+)
+
+// defaultRetentionDays is how long soft-deleted employees are kept when
+// PURGE_RETENTION_DAYS is not set.
+const defaultRetentionDays = 30
+
+// PurgeEmployeesHandler permanently removes employees that were soft-deleted
+// longer ago than the retention period. Only roles granted employees.purge
+// by the access policy may call it.
+// @Summary Purge deleted employees
+// @Description Hard-delete employees that were soft-deleted before the retention period (admin only)
+// @Produce json
+// @Param olderThanDays query int false "Retention period in days, at least PURGE_RETENTION_DAYS or 30, which it defaults to"
+// @Success 200 {object} map[string]int "Number of purged employees"
+// @Failure 400 "Invalid olderThanDays value, e.g. shorter than the retention period"
+// @Failure 401 "Authentication required"
+// @Failure 403 "Forbidden"
+// @Failure 500 "Internal Server Error"
+// @Failure 503 "Firestore unavailable, retry later"
+// @Security BearerAuth
+// @Router /function-5/purge [post]
+func PurgeEmployeesHandler(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+	utils.InfoLog("Request is being Processed for PurgeEmployeesHandler")
+
+	if _, ok := utils.Authorize(w, r, policy.Purge, 0); !ok {
+		return
+	}
+
+	retentionDays, err := retentionPeriod(r)
+	if err != nil {
+		log.Print("Invalid olderThanDays value:", err)
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid olderThanDays value")
+		return
+	}
+
+	repo, err := openRepository()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to create Firestore client")
+		return
+	}
+	defer repo.Close()
+
+	cutoff := time.Now().UTC().AddDate(0, 0, -retentionDays)
+	purged, err := repo.Purge(utils.CallerContext(r), cutoff)
+	if err != nil {
+		log.Print("Failed to purge employees from Firestore:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to purge employees from Firestore")
+		return
+	}
+
+	utils.InfoLog("Deleted employees purged successfully")
+	utils.RespondWithJSON(w, http.StatusOK, map[string]int{"purged": purged})
+	utils.InfoLog("Response Sent")
+}
+
+// retentionPeriod returns the number of days deleted employees are kept:
+// PURGE_RETENTION_DAYS, or defaultRetentionDays when it is not set, unless
+// the olderThanDays query parameter asks to keep them longer. Shorter
+// periods are rejected, so no caller can purge an employee before the
+// configured retention period is over.
+func retentionPeriod(r *http.Request) (int, error) {
+	minimum := defaultRetentionDays
+	if value := os.Getenv("PURGE_RETENTION_DAYS"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("invalid PURGE_RETENTION_DAYS %q", value)
+		}
+		minimum = days
+	}
+
+	value := r.URL.Query().Get("olderThanDays")
+	if value == "" {
+		return minimum, nil
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if days < minimum {
+		return 0, fmt.Errorf("olderThanDays must be at least %d", minimum)
+	}
+	return days, nil
+}
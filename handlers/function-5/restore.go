@@ -0,0 +1,63 @@
+package function5
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"example.com/task3gcp/shared/policy"
+	"example.com/task3gcp/shared/utils"
+)
+
+// RestoreEmployeeHandler undoes the soft delete of an employee by ID.
+// @Summary Restore a deleted employee
+// @Description Restore a soft-deleted employee by ID
+// @Produce json
+// @Param id path number true "Employee ID to be restored"
+// @Success 200 {object} models.EmployeeResponse
+// @Failure 400 "Invalid employee ID"
+// @Failure 404 "Employee not found"
+// @Failure 409 "Employee is not deleted"
+// @Failure 500 "Internal Server Error"
+// @Failure 503 "Firestore unavailable, retry later"
+// @Failure 401 "Authentication required"
+// @Failure 403 "Forbidden"
+// @Security BearerAuth
+// @Router /function-5/{id}/restore [post]
+func RestoreEmployeeHandler(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+	utils.InfoLog("Request is being Processed for RestoreEmployeeHandler")
+
+	id, err := strconv.Atoi(utils.EmployeeID(r))
+	if err != nil {
+		log.Print("Invalid employee ID:", err)
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid employee ID")
+		return
+	}
+
+	if _, ok := utils.Authorize(w, r, policy.Restore, id); !ok {
+		return
+	}
+
+	repo, err := openRepository()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to create Firestore client")
+		return
+	}
+	defer repo.Close()
+
+	utils.InfoLog("Request received: RestoreEmployeeHandler")
+
+	employee, err := repo.Restore(utils.CallerContext(r), id)
+	if err != nil {
+		log.Print("Failed to restore employee in Firestore:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to restore employee in Firestore")
+		return
+	}
+
+	utils.InfoLog("Employee restored successfully")
+	utils.SetETag(w, employee)
+	utils.RespondWithJSON(w, http.StatusOK, employee.Response())
+	utils.InfoLog("Response Sent")
+}
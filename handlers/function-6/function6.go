@@ -0,0 +1,86 @@
+package function6
+
+import (
+	"log"
+	"net/http"
+
+	"example.com/task3gcp/shared/config"
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/policy"
+	"example.com/task3gcp/shared/repository"
+	"example.com/task3gcp/shared/utils"
+
+	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
+)
+
+func init() {
+	config.MustLoadInFunction()
+	functions.HTTP("SearchEmployeesHandler", SearchEmployeesHandler)
+}
+
+// openRepository returns the employee store used by the handler, backed by
+// the Firestore client shared by every invocation. It is a variable so tests
+// can substitute an in-memory repository.
+var openRepository = utils.OpenRepository
+
+// UseRepository replaces the Firestore repository, e.g. with an in-memory one
+// when the gateway runs the handlers in local mode.
+func UseRepository(open func() (repository.EmployeeRepository, error)) {
+	openRepository = open
+}
+
+// SearchEmployeesHandler searches employees by one or more fields.
+// @Summary Search employees by field and value
+// @Description Search employees based on the specified field and value. Fields can also be combined, e.g. firstName=ad&role=manager.
+// @Produce json
+// @Param field query string false "Field to search (e.g., FirstName, LastName, Email, Role)"
+// @Param value query string false "Value to search for"
+// @Param firstName query string false "First name to match"
+// @Param lastName query string false "Last name to match"
+// @Param email query string false "Email to match"
+// @Param role query string false "Role to match"
+// @Param match query string false "exact (default) or prefix for case-insensitive prefix matching"
+// @Param includeDeleted query bool false "Include soft-deleted employees"
+// @Param limit query int false "Maximum number of results (1-1000)"
+// @Success 200 {array} models.EmployeeResponse
+// @Failure 400 "Bad Request: Invalid field or value"
+// @Failure 500 "Internal Server Error"
+// @Failure 503 "Firestore unavailable, retry later"
+// @Failure 401 "Authentication required"
+// @Failure 403 "Forbidden"
+// @Security BearerAuth
+// @Router /employees/search [get]
+func SearchEmployeesHandler(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+	utils.InfoLog("Request is being Processed for SearchEmployeesHandler")
+
+	opts, err := repository.SearchOptionsFromQuery(r.URL.Query())
+	if err != nil {
+		log.Print("Invalid search query:", err)
+		utils.RespondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, ok := utils.Authorize(w, r, policy.Search, 0); !ok {
+		return
+	}
+
+	repo, err := openRepository()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to create Firestore client")
+		return
+	}
+	defer repo.Close()
+
+	employees, err := repo.Search(r.Context(), opts)
+	if err != nil {
+		log.Print("Failed to search employees in Firestore:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to search employees in Firestore")
+		return
+	}
+
+	utils.InfoLog("Sending response: SearchEmployeesHandler")
+	utils.RespondWithJSON(w, http.StatusOK, models.NewEmployeeResponses(employees))
+	utils.InfoLog("Response Sent")
+}
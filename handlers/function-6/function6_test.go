@@ -0,0 +1,114 @@
+package function6
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/repository"
+	"example.com/task3gcp/shared/utils"
+)
+
+func useRepository(t *testing.T, repo repository.EmployeeRepository) {
+	t.Helper()
+	original := openRepository
+	openRepository = func() (repository.EmployeeRepository, error) { return repo, nil }
+	t.Cleanup(func() { openRepository = original })
+}
+
+// testKey signs the access tokens of test requests; TestMain makes the
+// handlers verify them, as they verify the tokens the gateway forwards.
+var testKey ed25519.PrivateKey
+
+func TestMain(m *testing.M) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		panic(err)
+	}
+	testKey = key
+	utils.UseVerifier(auth.NewVerifier(key.Public().(ed25519.PublicKey)))
+	os.Exit(m.Run())
+}
+
+// newRequest returns a request carrying the access token the gateway
+// forwards for an authenticated admin.
+func newRequest(method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	setCaller(req, 1, "admin")
+	return req
+}
+
+// setCaller makes req carry an access token for the employee with the given
+// ID and role.
+func setCaller(req *http.Request, id int, role string) {
+	pair, err := auth.NewIssuer(testKey).Issue(models.Employee{ID: id, Role: role})
+	if err != nil {
+		panic(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+}
+
+func TestSearchEmployeesHandler(t *testing.T) {
+	useRepository(t, repository.NewMemoryRepository(
+		models.Employee{ID: 1, FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Role: "manager"},
+		models.Employee{ID: 2, FirstName: "Adam", LastName: "Smith", Email: "adam@example.com", Role: "employee"},
+		models.Employee{ID: 3, FirstName: "Alan", LastName: "Turing", Email: "alan@example.com", Role: "manager"},
+	))
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"field=FirstName&value=Ada", []int{1}},
+		{"field=email&value=ALAN@&match=prefix", []int{3}},
+		{"firstName=ad&match=prefix", []int{1, 2}},
+		{"firstName=a&role=manager&match=prefix", []int{1, 3}},
+		{"role=manager&limit=1", []int{1}},
+		{"lastName=Hopper", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			SearchEmployeesHandler(rec, newRequest(http.MethodGet, "/?"+tt.query, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+			}
+			var employees []models.Employee
+			if err := json.NewDecoder(rec.Body).Decode(&employees); err != nil {
+				t.Fatal(err)
+			}
+			if len(employees) != len(tt.want) {
+				t.Fatalf("got %d employees, want IDs %v", len(employees), tt.want)
+			}
+			for i, employee := range employees {
+				if employee.ID != tt.want[i] {
+					t.Errorf("employee %d has ID %d, want %d", i, employee.ID, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSearchEmployeesHandlerInvalid(t *testing.T) {
+	useRepository(t, repository.NewMemoryRepository())
+
+	for _, query := range []string{
+		"",
+		"field=Password&value=secret",
+		"field=FirstName",
+		"firstName=Ada&match=fuzzy",
+		"firstName=Ada&limit=0",
+	} {
+		rec := httptest.NewRecorder()
+		SearchEmployeesHandler(rec, newRequest(http.MethodGet, "/?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET ?%s: status = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package function7
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/config"
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/repository"
+	"example.com/task3gcp/shared/utils"
+
+	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
+)
+
+func init() {
+	config.MustLoadInFunction()
+	functions.HTTP("LoginHandler", LoginHandler)
+	functions.HTTP("RefreshHandler", RefreshHandler)
+}
+
+// openRepository returns the employee store used by the handler, backed by
+// the Firestore client shared by every invocation. It is a variable so tests
+// can substitute an in-memory repository.
+var openRepository = utils.OpenRepository
+
+// UseRepository replaces the Firestore repository, e.g. with an in-memory one
+// when the gateway runs the handlers in local mode.
+func UseRepository(open func() (repository.EmployeeRepository, error)) {
+	openRepository = open
+}
+
+// loadSigningKey returns the key tokens are signed with. It is a variable so
+// tests can use a generated key instead of the key file.
+var loadSigningKey = func() (ed25519.PrivateKey, error) {
+	return auth.LoadPrivateKey(auth.KeyFile())
+}
+
+// UseSigningKey makes the handlers sign with key instead of reading the key
+// file.
+func UseSigningKey(key ed25519.PrivateKey) {
+	loadSigningKey = func() (ed25519.PrivateKey, error) { return key, nil }
+}
+
+// unknownEmployee is checked when no employee has the given email, so an
+// unknown email takes as long to reject as a wrong password.
+var unknownEmployee = func() models.Employee {
+	employee := models.Employee{Password: "no employee has this password"}
+	employee.HashPassword()
+	return employee
+}()
+
+type credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// LoginHandler exchanges an employee's email and password for tokens.
+// @Summary Log in
+// @Description Verify an employee's email and password and issue a short-lived access token and a refresh token
+// @Accept json
+// @Produce json
+// @Param credentials body credentials true "Email and password"
+// @Success 200 {object} auth.TokenPair
+// @Failure 400 "Invalid request payload"
+// @Failure 401 "Invalid email or password"
+// @Failure 500 "Internal Server Error"
+// @Failure 503 "Firestore unavailable, retry later"
+// @Router /auth/login [post]
+func LoginHandler(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+	log.Print("Request is being Processed for LoginHandler")
+
+	var creds credentials
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil || creds.Email == "" || creds.Password == "" {
+		log.Print("Invalid request payload:", err)
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	repo, err := openRepository()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to create Firestore client")
+		return
+	}
+	defer repo.Close()
+
+	// Emails are unique ignoring case, so Ada@Example.com logs in as
+	// ada@example.com
+	employee, err := repo.GetByEmail(r.Context(), creds.Email)
+	found := err == nil
+	if errors.Is(err, repository.ErrNotFound) {
+		employee = unknownEmployee
+	} else if err != nil {
+		log.Print("Failed to look up employee in Firestore:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to look up employee in Firestore")
+		return
+	}
+	if !employee.CheckPassword(creds.Password) || !found {
+		log.Print("Login failed for ", creds.Email)
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Invalid email or password")
+		return
+	}
+
+	issueTokens(w, r, employee)
+	log.Print("Response Sent: LoginHandler")
+}
+
+// issueTokens responds with a new token pair for employee.
+func issueTokens(w http.ResponseWriter, r *http.Request, employee models.Employee) {
+	key, err := loadSigningKey()
+	if err != nil {
+		log.Print("Failed to load signing key:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to load signing key")
+		return
+	}
+	pair, err := auth.NewIssuer(key).Issue(employee)
+	if err != nil {
+		log.Print("Failed to sign tokens:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to sign tokens")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, pair)
+}
@@ -0,0 +1,136 @@
+package function7
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/repository"
+)
+
+func useRepository(t *testing.T, repo repository.EmployeeRepository) {
+	t.Helper()
+	original := openRepository
+	openRepository = func() (repository.EmployeeRepository, error) { return repo, nil }
+	t.Cleanup(func() { openRepository = original })
+}
+
+// useKey makes the handlers sign with a generated key and returns a verifier
+// for it.
+func useKey(t *testing.T) *auth.Verifier {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := loadSigningKey
+	loadSigningKey = func() (ed25519.PrivateKey, error) { return key, nil }
+	t.Cleanup(func() { loadSigningKey = original })
+	return auth.NewVerifier(key.Public().(ed25519.PublicKey))
+}
+
+// seed returns a repository holding one employee whose password is secret1.
+func seed(t *testing.T) *repository.MemoryRepository {
+	t.Helper()
+	repo := repository.NewMemoryRepository()
+	_, err := repo.Create(context.Background(), models.Employee{
+		FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Password: "secret1", Role: "admin",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func post(handler http.HandlerFunc, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	return rec
+}
+
+func decodePair(t *testing.T, rec *httptest.ResponseRecorder) auth.TokenPair {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var pair auth.TokenPair
+	if err := json.NewDecoder(rec.Body).Decode(&pair); err != nil {
+		t.Fatal(err)
+	}
+	return pair
+}
+
+func TestLoginHandler(t *testing.T) {
+	useRepository(t, seed(t))
+	verifier := useKey(t)
+
+	pair := decodePair(t, post(LoginHandler, `{"email":"ada@example.com","password":"secret1"}`))
+	claims, err := verifier.Verify(pair.AccessToken, auth.AccessToken)
+	if err != nil {
+		t.Fatalf("access token: %v", err)
+	}
+	if claims.Subject != "1" || claims.Email != "ada@example.com" || claims.Role != "admin" {
+		t.Errorf("claims = %+v", claims)
+	}
+	if _, err := verifier.Verify(pair.RefreshToken, auth.RefreshToken); err != nil {
+		t.Errorf("refresh token: %v", err)
+	}
+
+	// Emails are compared ignoring case and surrounding spaces
+	pair = decodePair(t, post(LoginHandler, `{"email":" Ada@Example.COM","password":"secret1"}`))
+	if claims, err := verifier.Verify(pair.AccessToken, auth.AccessToken); err != nil || claims.Subject != "1" {
+		t.Errorf("login with a differently cased email: claims = %+v, err = %v", claims, err)
+	}
+
+	for body, want := range map[string]int{
+		`{"email":"ada@example.com","password":"wrong"}`:    http.StatusUnauthorized,
+		`{"email":"alan@example.com","password":"secret1"}`: http.StatusUnauthorized,
+		`{"email":"ada@example.com"}`:                       http.StatusBadRequest,
+		`{"email":`:                                         http.StatusBadRequest,
+	} {
+		if rec := post(LoginHandler, body); rec.Code != want {
+			t.Errorf("POST %s: status = %d, want %d", body, rec.Code, want)
+		}
+	}
+}
+
+func TestRefreshHandler(t *testing.T) {
+	repo := seed(t)
+	useRepository(t, repo)
+	verifier := useKey(t)
+
+	login := decodePair(t, post(LoginHandler, `{"email":"ada@example.com","password":"secret1"}`))
+
+	// The refreshed tokens carry the employee's current role.
+	employee, _ := repo.Get(context.Background(), 1)
+	employee.Role = "manager"
+	if _, err := repo.Update(context.Background(), employee); err != nil {
+		t.Fatal(err)
+	}
+	refreshed := decodePair(t, post(RefreshHandler, `{"refreshToken":"`+login.RefreshToken+`"}`))
+	claims, err := verifier.Verify(refreshed.AccessToken, auth.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Role != "manager" {
+		t.Errorf("refreshed role = %q, want manager", claims.Role)
+	}
+
+	if rec := post(RefreshHandler, `{"refreshToken":"`+login.AccessToken+`"}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("access token used as refresh token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	if err := repo.Delete(context.Background(), 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if rec := post(RefreshHandler, `{"refreshToken":"`+login.RefreshToken+`"}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("refresh for deleted employee: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,82 @@
+package function7
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/repository"
+	"example.com/task3gcp/shared/utils"
+)
+
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// RefreshHandler exchanges a refresh token for a new token pair.
+// @Summary Refresh tokens
+// @Description Exchange a valid refresh token for a new access token and refresh token. The employee is read again, so role changes and deletions take effect.
+// @Accept json
+// @Produce json
+// @Param refresh body refreshRequest true "Refresh token from a previous login or refresh"
+// @Success 200 {object} auth.TokenPair
+// @Failure 400 "Invalid request payload"
+// @Failure 401 "Invalid refresh token"
+// @Failure 500 "Internal Server Error"
+// @Failure 503 "Firestore unavailable, retry later"
+// @Router /auth/refresh [post]
+func RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+	log.Print("Request is being Processed for RefreshHandler")
+
+	var body refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.RefreshToken == "" {
+		log.Print("Invalid request payload:", err)
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	key, err := loadSigningKey()
+	if err != nil {
+		log.Print("Failed to load signing key:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to load signing key")
+		return
+	}
+	claims, err := auth.NewVerifier(key.Public().(ed25519.PublicKey)).Verify(body.RefreshToken, auth.RefreshToken)
+	if err != nil {
+		log.Print("Invalid refresh token:", err)
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Invalid refresh token")
+		return
+	}
+	id, _ := claims.EmployeeID()
+
+	repo, err := openRepository()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to create Firestore client")
+		return
+	}
+	defer repo.Close()
+
+	employee, err := repo.Get(r.Context(), id)
+	if err == nil && employee.Deleted {
+		err = repository.ErrNotFound
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			log.Print("Employee of refresh token no longer exists:", id)
+			utils.RespondWithError(w, r, http.StatusUnauthorized, "Invalid refresh token")
+			return
+		}
+		log.Print("Failed to retrieve employee from Firestore:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to retrieve employee from Firestore")
+		return
+	}
+
+	issueTokens(w, r, employee)
+	log.Print("Response Sent: RefreshHandler")
+}
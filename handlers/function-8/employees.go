@@ -0,0 +1,104 @@
+package function8
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/policy"
+	"example.com/task3gcp/shared/repository"
+	"example.com/task3gcp/shared/utils"
+)
+
+// employeePage is the response body of DepartmentEmployeesHandler, the same
+// page GetAllEmployees returns.
+type employeePage struct {
+	Employees     []models.EmployeeResponse `json:"employees"`
+	NextPageToken string                    `json:"nextPageToken,omitempty"`
+}
+
+// DepartmentEmployeesHandler returns a page of the employees of a department.
+// @Summary Get the employees of a department
+// @Description Get a page of the employees of a department. It takes the query parameters of the employee listing, with departmentId set to the department
+// @Produce json
+// @Param id path number true "Department ID"
+// @Param limit query int false "Maximum number of employees per page (1-1000)"
+// @Param pageToken query string false "Token from a previous response's nextPageToken"
+// @Param orderBy query string false "Comma separated fields, '-' prefix for descending, e.g. lastName,-id"
+// @Param includeDeleted query bool false "Include soft-deleted employees"
+// @Success 200 {object} employeePage
+// @Failure 400 "Invalid department ID"
+// @Failure 400 "Invalid query parameters"
+// @Failure 404 "Department not found"
+// @Failure 500 "Internal Server Error"
+// @Failure 503 "Firestore unavailable, retry later"
+// @Failure 401 "Authentication required"
+// @Failure 403 "Forbidden"
+// @Security BearerAuth
+// @Router /function-8/{id}/employees [get]
+func DepartmentEmployeesHandler(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+	log.Print("Request is being Processed for DepartmentEmployeesHandler")
+
+	// Behind the gateway a Cloud Function sees /<id>, the route variables
+	// only, so the ID is the last path segment there too
+	id, err := strconv.Atoi(utils.EmployeeID(r))
+	if err != nil {
+		log.Print("Invalid department ID:", err)
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid department ID")
+		return
+	}
+
+	query := r.URL.Query()
+	query.Set("departmentId", strconv.Itoa(id))
+	opts, err := repository.ListOptionsFromQuery(query)
+	if err != nil {
+		log.Print("Invalid query parameters:", err)
+		utils.RespondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, ok := utils.Authorize(w, r, policy.GetDepartment, 0); !ok {
+		return
+	}
+	if _, ok := utils.Authorize(w, r, policy.List, 0); !ok {
+		return
+	}
+
+	departments, err := openDepartments()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to create Firestore client")
+		return
+	}
+	defer departments.Close()
+
+	// An unknown department is not found rather than an empty page
+	if _, err := departments.GetDepartment(r.Context(), id); err != nil {
+		log.Print("Failed to retrieve department from Firestore:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to retrieve department from Firestore")
+		return
+	}
+
+	repo, err := openRepository()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to create Firestore client")
+		return
+	}
+	defer repo.Close()
+
+	page, err := repo.List(r.Context(), opts)
+	if err != nil {
+		log.Print("Failed to retrieve employees from Firestore:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to retrieve employees from Firestore")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, employeePage{
+		Employees:     models.NewEmployeeResponses(page.Employees),
+		NextPageToken: page.NextPageToken,
+	})
+	log.Print("Response Sent: DepartmentEmployeesHandler")
+}
@@ -0,0 +1,311 @@
+package function8
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"example.com/task3gcp/shared/config"
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/policy"
+	"example.com/task3gcp/shared/repository"
+	"example.com/task3gcp/shared/utils"
+
+	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
+)
+
+func init() {
+	config.MustLoadInFunction()
+	functions.HTTP("ListDepartmentsHandler", ListDepartmentsHandler)
+	functions.HTTP("GetDepartmentHandler", GetDepartmentHandler)
+	functions.HTTP("CreateDepartmentHandler", CreateDepartmentHandler)
+	functions.HTTP("UpdateDepartmentHandler", UpdateDepartmentHandler)
+	functions.HTTP("DeleteDepartmentHandler", DeleteDepartmentHandler)
+	functions.HTTP("DepartmentEmployeesHandler", DepartmentEmployeesHandler)
+}
+
+// openDepartments returns the department store used by the handlers, backed
+// by the Firestore client shared by every invocation. It is a variable so
+// tests can substitute an in-memory repository.
+var openDepartments = utils.OpenDepartmentRepository
+
+// UseDepartments replaces the Firestore department repository, e.g. with an
+// in-memory one when the gateway runs the handlers in local mode.
+func UseDepartments(open func() (repository.DepartmentRepository, error)) {
+	openDepartments = open
+}
+
+// openRepository returns the employee store DepartmentEmployeesHandler lists
+// from. It is a variable so tests can substitute an in-memory repository.
+var openRepository = utils.OpenRepository
+
+// UseRepository replaces the Firestore employee repository, e.g. with an
+// in-memory one when the gateway runs the handlers in local mode.
+func UseRepository(open func() (repository.EmployeeRepository, error)) {
+	openRepository = open
+}
+
+// departmentList is the response body of ListDepartmentsHandler.
+type departmentList struct {
+	Departments []models.Department `json:"departments"`
+}
+
+// ListDepartmentsHandler returns every department.
+// @Summary Get all departments
+// @Description Get every department, ordered by ID
+// @Produce json
+// @Success 200 {object} departmentList
+// @Failure 500 "Internal Server Error"
+// @Failure 503 "Firestore unavailable, retry later"
+// @Failure 401 "Authentication required"
+// @Failure 403 "Forbidden"
+// @Security BearerAuth
+// @Router /function-8 [get]
+func ListDepartmentsHandler(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+	log.Print("Request is being Processed for ListDepartmentsHandler")
+
+	if _, ok := utils.Authorize(w, r, policy.ListDepartments, 0); !ok {
+		return
+	}
+
+	repo, err := openDepartments()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to create Firestore client")
+		return
+	}
+	defer repo.Close()
+
+	departments, err := repo.ListDepartments(r.Context())
+	if err != nil {
+		log.Print("Failed to retrieve departments from Firestore:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to retrieve departments from Firestore")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, departmentList{Departments: departments})
+	log.Print("Response Sent: ListDepartmentsHandler")
+}
+
+// GetDepartmentHandler returns a department by ID.
+// @Summary Get a department by ID
+// @Description Get a department by ID
+// @Produce json
+// @Param id path number true "Department ID"
+// @Success 200 {object} models.Department
+// @Failure 400 "Invalid department ID"
+// @Failure 404 "Department not found"
+// @Failure 500 "Internal Server Error"
+// @Failure 503 "Firestore unavailable, retry later"
+// @Failure 401 "Authentication required"
+// @Failure 403 "Forbidden"
+// @Security BearerAuth
+// @Router /function-8/{id} [get]
+func GetDepartmentHandler(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+	log.Print("Request is being Processed for GetDepartmentHandler")
+
+	// Department routes name their ID variable id too
+	id, err := strconv.Atoi(utils.EmployeeID(r))
+	if err != nil {
+		log.Print("Invalid department ID:", err)
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid department ID")
+		return
+	}
+
+	if _, ok := utils.Authorize(w, r, policy.GetDepartment, 0); !ok {
+		return
+	}
+
+	repo, err := openDepartments()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to create Firestore client")
+		return
+	}
+	defer repo.Close()
+
+	department, err := repo.GetDepartment(r.Context(), id)
+	if err != nil {
+		log.Print("Failed to retrieve department from Firestore:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to retrieve department from Firestore")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, department)
+	log.Print("Response Sent: GetDepartmentHandler")
+}
+
+// CreateDepartmentHandler creates a new department.
+// @Summary Create a new department
+// @Description Create a new department. Codes are stored in upper case and must be unique; headEmployeeId, when set, must be an employee that is not deleted
+// @Accept json
+// @Produce json
+// @Param department body models.Department true "Department object to be created"
+// @Success 201 {object} models.Department "Department created successfully"
+// @Failure 400 "Invalid request payload"
+// @Failure 409 "Department code already in use"
+// @Failure 500 "Internal Server Error"
+// @Failure 503 "Firestore unavailable, retry later"
+// @Failure 401 "Authentication required"
+// @Failure 403 "Forbidden"
+// @Security BearerAuth
+// @Router /function-8 [post]
+func CreateDepartmentHandler(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+	log.Print("Request is being Processed for CreateDepartmentHandler")
+
+	if _, ok := utils.Authorize(w, r, policy.CreateDepartment, 0); !ok {
+		return
+	}
+
+	department, ok := decodeDepartment(w, r)
+	if !ok {
+		return
+	}
+
+	repo, err := openDepartments()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to create Firestore client")
+		return
+	}
+	defer repo.Close()
+
+	department, err = repo.CreateDepartment(r.Context(), department)
+	if err != nil {
+		log.Print("Failed to create department in Firestore:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to create department in Firestore")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, department)
+	log.Print("Response Sent: CreateDepartmentHandler")
+}
+
+// UpdateDepartmentHandler replaces an existing department by ID.
+// @Summary Update an existing department
+// @Description Replace an existing department by ID, with the same checks as creating one
+// @Accept json
+// @Produce json
+// @Param id path number true "Department ID to be updated"
+// @Param department body models.Department true "Updated department object"
+// @Success 200 {object} models.Department "Department updated successfully"
+// @Failure 400 "Invalid department ID"
+// @Failure 400 "Invalid request payload"
+// @Failure 404 "Department not found"
+// @Failure 409 "Department code already in use"
+// @Failure 500 "Internal Server Error"
+// @Failure 503 "Firestore unavailable, retry later"
+// @Failure 401 "Authentication required"
+// @Failure 403 "Forbidden"
+// @Security BearerAuth
+// @Router /function-8/{id} [put]
+func UpdateDepartmentHandler(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+	log.Print("Request is being Processed for UpdateDepartmentHandler")
+
+	id, err := strconv.Atoi(utils.EmployeeID(r))
+	if err != nil {
+		log.Print("Invalid department ID:", err)
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid department ID")
+		return
+	}
+
+	if _, ok := utils.Authorize(w, r, policy.UpdateDepartment, 0); !ok {
+		return
+	}
+
+	department, ok := decodeDepartment(w, r)
+	if !ok {
+		return
+	}
+	department.ID = id
+
+	repo, err := openDepartments()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to create Firestore client")
+		return
+	}
+	defer repo.Close()
+
+	department, err = repo.UpdateDepartment(r.Context(), department)
+	if err != nil {
+		log.Print("Failed to update department in Firestore:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to update department in Firestore")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, department)
+	log.Print("Response Sent: UpdateDepartmentHandler")
+}
+
+// DeleteDepartmentHandler deletes a department by ID.
+// @Summary Delete a department
+// @Description Delete a department by ID. Departments that employees still belong to, including soft-deleted ones, cannot be deleted; reassign or purge those employees first
+// @Produce json
+// @Param id path number true "Department ID to be deleted"
+// @Success 200 {object} map[string]string "Department deleted successfully"
+// @Failure 400 "Invalid department ID"
+// @Failure 404 "Department not found"
+// @Failure 409 "Department still has employees"
+// @Failure 500 "Internal Server Error"
+// @Failure 503 "Firestore unavailable, retry later"
+// @Failure 401 "Authentication required"
+// @Failure 403 "Forbidden"
+// @Security BearerAuth
+// @Router /function-8/{id} [delete]
+func DeleteDepartmentHandler(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+	log.Print("Request is being Processed for DeleteDepartmentHandler")
+
+	id, err := strconv.Atoi(utils.EmployeeID(r))
+	if err != nil {
+		log.Print("Invalid department ID:", err)
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid department ID")
+		return
+	}
+
+	if _, ok := utils.Authorize(w, r, policy.DeleteDepartment, 0); !ok {
+		return
+	}
+
+	repo, err := openDepartments()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to create Firestore client")
+		return
+	}
+	defer repo.Close()
+
+	if err := repo.DeleteDepartment(r.Context(), id); err != nil {
+		log.Print("Failed to delete department from Firestore:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to delete department from Firestore")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Department deleted successfully"})
+	log.Print("Response Sent: DeleteDepartmentHandler")
+}
+
+// decodeDepartment reads and validates the department in the request body,
+// writing the error response when it is invalid.
+func decodeDepartment(w http.ResponseWriter, r *http.Request) (models.Department, bool) {
+	var department models.Department
+	if err := json.NewDecoder(r.Body).Decode(&department); err != nil {
+		log.Print("Invalid request payload:", err)
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid request payload")
+		return models.Department{}, false
+	}
+	defer r.Body.Close()
+
+	if err := department.Validate(); err != nil {
+		log.Print("Validation error:", err)
+		utils.RespondWithDomainError(w, r, err, "Invalid department")
+		return models.Department{}, false
+	}
+	return department, true
+}
@@ -0,0 +1,194 @@
+package function8
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/repository"
+	"example.com/task3gcp/shared/utils"
+	"github.com/gorilla/mux"
+)
+
+// useRepository makes the handlers use repo for both employees and
+// departments.
+func useRepository(t *testing.T, repo *repository.MemoryRepository) {
+	t.Helper()
+	originalRepository, originalDepartments := openRepository, openDepartments
+	openRepository = func() (repository.EmployeeRepository, error) { return repo, nil }
+	openDepartments = func() (repository.DepartmentRepository, error) { return repo, nil }
+	t.Cleanup(func() { openRepository, openDepartments = originalRepository, originalDepartments })
+}
+
+// testKey signs the access tokens of test requests; TestMain makes the
+// handlers verify them, as they verify the tokens the gateway forwards.
+var testKey ed25519.PrivateKey
+
+func TestMain(m *testing.M) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		panic(err)
+	}
+	testKey = key
+	utils.UseVerifier(auth.NewVerifier(key.Public().(ed25519.PublicKey)))
+	os.Exit(m.Run())
+}
+
+// newRequest returns a request carrying the access token the gateway
+// forwards for an authenticated caller with the given role.
+func newRequest(method, target, role string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	setCaller(req, 1, role)
+	return req
+}
+
+// setCaller makes req carry an access token for the employee with the given
+// ID and role.
+func setCaller(req *http.Request, id int, role string) {
+	pair, err := auth.NewIssuer(testKey).Issue(models.Employee{ID: id, Role: role})
+	if err != nil {
+		panic(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+}
+
+func TestDepartmentHandlers(t *testing.T) {
+	repo := repository.NewMemoryRepository(
+		models.Employee{ID: 1, FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Role: "admin"},
+	)
+	useRepository(t, repo)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		id      string
+		role    string
+		body    string
+		want    int
+	}{
+		{"create", CreateDepartmentHandler, http.MethodPost, "", "admin", `{"name":"Engineering","code":"eng","costCenter":"CC-100","headEmployeeId":1}`, http.StatusCreated},
+		{"create with a taken code", CreateDepartmentHandler, http.MethodPost, "", "admin", `{"name":"Engines","code":"ENG"}`, http.StatusConflict},
+		{"create with an unknown head", CreateDepartmentHandler, http.MethodPost, "", "admin", `{"name":"Sales","code":"SAL","headEmployeeId":9}`, http.StatusBadRequest},
+		{"create without a name", CreateDepartmentHandler, http.MethodPost, "", "admin", `{"code":"SAL"}`, http.StatusBadRequest},
+		{"create as an employee", CreateDepartmentHandler, http.MethodPost, "", "employee", `{"name":"Sales","code":"SAL"}`, http.StatusForbidden},
+		{"get", GetDepartmentHandler, http.MethodGet, "1", "employee", "", http.StatusOK},
+		{"get unknown", GetDepartmentHandler, http.MethodGet, "9", "admin", "", http.StatusNotFound},
+		{"get invalid id", GetDepartmentHandler, http.MethodGet, "one", "admin", "", http.StatusBadRequest},
+		{"list", ListDepartmentsHandler, http.MethodGet, "", "manager", "", http.StatusOK},
+		{"update", UpdateDepartmentHandler, http.MethodPut, "1", "hr", `{"name":"Engineering","code":"ENG","costCenter":"CC-200"}`, http.StatusOK},
+		{"update unknown", UpdateDepartmentHandler, http.MethodPut, "9", "admin", `{"name":"Sales","code":"SAL"}`, http.StatusNotFound},
+		{"update as a manager", UpdateDepartmentHandler, http.MethodPut, "1", "manager", `{"name":"Engineering","code":"ENG"}`, http.StatusForbidden},
+		{"delete as an employee", DeleteDepartmentHandler, http.MethodDelete, "1", "employee", "", http.StatusForbidden},
+		{"delete", DeleteDepartmentHandler, http.MethodDelete, "1", "admin", "", http.StatusOK},
+		{"delete again", DeleteDepartmentHandler, http.MethodDelete, "1", "admin", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newRequest(tt.method, "/"+tt.id, tt.role, strings.NewReader(tt.body))
+			if tt.id != "" {
+				req = mux.SetURLVars(req, map[string]string{"id": tt.id})
+			}
+			rec := httptest.NewRecorder()
+			tt.handler(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.name != "update" {
+				return
+			}
+			var department models.Department
+			if err := json.NewDecoder(rec.Body).Decode(&department); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if department.ID != 1 || department.CostCenter != "CC-200" || department.HeadEmployeeID != 0 {
+				t.Errorf("updated department = %+v", department)
+			}
+		})
+	}
+}
+
+func TestDepartmentEmployeesHandler(t *testing.T) {
+	repo := repository.NewMemoryRepository(
+		models.Employee{ID: 1, FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Role: "admin"},
+		models.Employee{ID: 2, FirstName: "Alan", LastName: "Turing", Email: "alan@example.com", Role: "employee"},
+		models.Employee{ID: 3, FirstName: "Grace", LastName: "Hopper", Email: "grace@example.com", Role: "employee"},
+	)
+	useRepository(t, repo)
+	ctx := context.Background()
+	engineering, err := repo.CreateDepartment(ctx, models.Department{Name: "Engineering", Code: "ENG"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateDepartment(ctx, models.Department{Name: "Sales", Code: "SAL"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int{2, 3} {
+		employee, err := repo.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		employee.DepartmentID = engineering.ID
+		if _, err := repo.Update(ctx, employee); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		id    string
+		query string
+		role  string
+		want  int
+		ids   []int
+	}{
+		{"employees", "1", "", "manager", http.StatusOK, []int{2, 3}},
+		{"paged and ordered", "1", "?orderBy=-id&limit=1", "admin", http.StatusOK, []int{3}},
+		{"departmentId is the path's", "1", "?departmentId=2", "admin", http.StatusOK, []int{2, 3}},
+		{"no employees", "2", "", "admin", http.StatusOK, []int{}},
+		{"unknown department", "9", "", "admin", http.StatusNotFound, nil},
+		{"invalid query", "1", "?limit=0", "admin", http.StatusBadRequest, nil},
+		{"invalid id", "eng", "", "admin", http.StatusBadRequest, nil},
+		{"as an employee", "1", "", "employee", http.StatusForbidden, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The gateway forwards /function-8/{id}/employees as /<id>
+			req := newRequest(http.MethodGet, "/"+tt.id+tt.query, tt.role, nil)
+			rec := httptest.NewRecorder()
+			DepartmentEmployeesHandler(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			var page employeePage
+			if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			ids := []int{}
+			for _, employee := range page.Employees {
+				ids = append(ids, employee.ID)
+			}
+			if len(ids) != len(tt.ids) {
+				t.Fatalf("employees = %v, want %v", ids, tt.ids)
+			}
+			for i := range ids {
+				if ids[i] != tt.ids[i] {
+					t.Fatalf("employees = %v, want %v", ids, tt.ids)
+				}
+			}
+		})
+	}
+}
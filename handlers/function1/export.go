@@ -0,0 +1,272 @@
+package function1
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/policy"
+	"example.com/task3gcp/shared/repository"
+	"example.com/task3gcp/shared/utils"
+)
+
+// exportPageSize is the number of employees read from the repository at a
+// time, so an export never holds the whole collection in memory. It is a
+// variable so tests can export several pages.
+var exportPageSize = 500
+
+// exportFormats maps each export format to its content type.
+var exportFormats = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"ndjson": "application/x-ndjson",
+	"json":   "application/json",
+}
+
+// ExportEmployeesHandler streams every employee matching the list filters as
+// a file download.
+// @Summary Export employees
+// @Description Download every employee matching the filters of the list endpoint, streamed page by page. csv (the default) opens in spreadsheet applications; ndjson has one employee per line; json is an array. Passwords are never exported
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce json
+// @Param format query string false "csv, ndjson or json" Enums(csv, ndjson, json)
+// @Param orderBy query string false "Comma separated fields, '-' prefix for descending, e.g. lastName,-id"
+// @Param role query string false "Only employees with this role (also firstName, lastName, email, id, departmentId)"
+// @Param includeDeleted query bool false "Include soft-deleted employees"
+// @Success 200 {array} models.EmployeeResponse
+// @Failure 400 "Invalid query parameters"
+// @Failure 500 "Internal Server Error"
+// @Failure 503 "Firestore unavailable, retry later"
+// @Failure 401 "Authentication required"
+// @Failure 403 "Forbidden"
+// @Security BearerAuth
+// @Router /employees/export [get]
+func ExportEmployeesHandler(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+	utils.InfoLog("Request is being Processed for ExportEmployeesHandler")
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	contentType, ok := exportFormats[format]
+	if !ok {
+		log.Print("Invalid export format:", format)
+		utils.RespondWithError(w, r, http.StatusBadRequest, "format must be csv, ndjson or json")
+		return
+	}
+
+	opts, err := repository.ListOptionsFromQuery(query)
+	if err != nil {
+		log.Print("Invalid query parameters:", err)
+		utils.RespondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Exports cover every match, one page at a time
+	opts.Limit = exportPageSize
+	opts.PageToken = ""
+
+	if _, ok := utils.Authorize(w, r, policy.List, 0); !ok {
+		return
+	}
+
+	repo, err := openRepository()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to create Firestore client")
+		return
+	}
+	defer repo.Close()
+
+	// Read the first page before answering, so that query errors still get
+	// an error response
+	page, err := repo.List(r.Context(), opts)
+	if err != nil {
+		log.Print("Failed to retrieve employees from Firestore:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to retrieve employees from Firestore")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="employees.%s"`, format))
+	w.WriteHeader(http.StatusOK)
+
+	encoder := newExportEncoder(format, w)
+	exported := 0
+	for {
+		for _, employee := range page.Employees {
+			if err := encoder.Encode(employee.Response()); err != nil {
+				log.Print("Failed to write export:", err)
+				return
+			}
+		}
+		exported += len(page.Employees)
+		if err := encoder.Flush(); err != nil {
+			log.Print("Failed to write export:", err)
+			return
+		}
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+
+		opts.PageToken = page.NextPageToken
+		if page, err = repo.List(r.Context(), opts); err != nil {
+			// The status is already sent; the truncated file, a JSON array
+			// without its closing bracket, is all the client can be told.
+			log.Printf("Export failed after %d employees: %v", exported, err)
+			return
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		log.Print("Failed to write export:", err)
+		return
+	}
+	utils.InfoLog(fmt.Sprintf("Exported %d employees", exported))
+}
+
+// exportEncoder writes employees in one export format.
+type exportEncoder interface {
+	Encode(employee models.EmployeeResponse) error
+	// Flush writes buffered employees to the response.
+	Flush() error
+	// Close ends the file.
+	Close() error
+}
+
+func newExportEncoder(format string, w io.Writer) exportEncoder {
+	switch format {
+	case "ndjson":
+		return &ndjsonEncoder{encoder: json.NewEncoder(w)}
+	case "json":
+		return &jsonArrayEncoder{w: w}
+	}
+	return &csvEncoder{writer: csv.NewWriter(w), w: w}
+}
+
+// exportColumns is the header row of CSV exports.
+var exportColumns = []string{"id", "firstName", "lastName", "email", "role", "departmentId", "deleted", "deletedAt", "version"}
+
+// csvEncoder writes a header row followed by one row per employee. The file
+// starts with a byte order mark so that spreadsheet applications read it as
+// UTF-8.
+type csvEncoder struct {
+	writer  *csv.Writer
+	w       io.Writer
+	started bool
+}
+
+func (e *csvEncoder) Encode(employee models.EmployeeResponse) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	departmentID := ""
+	if employee.DepartmentID != 0 {
+		departmentID = strconv.Itoa(employee.DepartmentID)
+	}
+	deletedAt := ""
+	if employee.DeletedAt != nil {
+		deletedAt = employee.DeletedAt.UTC().Format(time.RFC3339)
+	}
+	return e.writer.Write([]string{
+		strconv.Itoa(employee.ID),
+		spreadsheetText(employee.FirstName),
+		spreadsheetText(employee.LastName),
+		spreadsheetText(employee.Email),
+		spreadsheetText(employee.Role),
+		departmentID,
+		strconv.FormatBool(employee.Deleted),
+		deletedAt,
+		strconv.Itoa(employee.Version),
+	})
+}
+
+func (e *csvEncoder) start() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+	if _, err := io.WriteString(e.w, "\ufeff"); err != nil {
+		return err
+	}
+	return e.writer.Write(exportColumns)
+}
+
+func (e *csvEncoder) Flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvEncoder) Close() error {
+	// An empty export still has its header
+	if err := e.start(); err != nil {
+		return err
+	}
+	return e.Flush()
+}
+
+// spreadsheetText keeps a spreadsheet from evaluating text that starts like
+// a formula, by prefixing it with an apostrophe.
+func spreadsheetText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// ndjsonEncoder writes one JSON object per line.
+type ndjsonEncoder struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonEncoder) Encode(employee models.EmployeeResponse) error {
+	return e.encoder.Encode(employee)
+}
+
+func (e *ndjsonEncoder) Flush() error { return nil }
+
+func (e *ndjsonEncoder) Close() error { return nil }
+
+// jsonArrayEncoder writes a JSON array one element at a time.
+type jsonArrayEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonArrayEncoder) Encode(employee models.EmployeeResponse) error {
+	data, err := json.Marshal(employee)
+	if err != nil {
+		return err
+	}
+	separator := ",\n"
+	if e.count == 0 {
+		separator = "[\n"
+	}
+	e.count++
+	if _, err := io.WriteString(e.w, separator); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonArrayEncoder) Flush() error { return nil }
+
+func (e *jsonArrayEncoder) Close() error {
+	closing := "\n]\n"
+	if e.count == 0 {
+		closing = "[]\n"
+	}
+	_, err := io.WriteString(e.w, closing)
+	return err
+}
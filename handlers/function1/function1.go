@@ -1,71 +1,92 @@
 package function1
 
 import (
-	"context"
-	"encoding/json"
 	"log"
 	"net/http"
 
-	"example.com/task3gcp/models"
-	"example.com/task3gcp/utils"
+	"example.com/task3gcp/shared/config"
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/policy"
+	"example.com/task3gcp/shared/repository"
+	"example.com/task3gcp/shared/utils"
 
 	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
 )
 
 func init() {
-	functions.HTTP("GetAllEmployees", getAllEmployees)
+	config.MustLoadInFunction()
+	functions.HTTP("GetAllEmployees", GetAllEmployees)
+	functions.HTTP("ExportEmployeesHandler", ExportEmployeesHandler)
 }
 
-// GetAllEmployees returns a list of all employees.
+// openRepository returns the employee store used by the handler, backed by
+// the Firestore client shared by every invocation. It is a variable so tests
+// can substitute an in-memory repository.
+var openRepository = utils.OpenRepository
+
+// UseRepository replaces the Firestore repository, e.g. with an in-memory one
+// when the gateway runs the handlers in local mode.
+func UseRepository(open func() (repository.EmployeeRepository, error)) {
+	openRepository = open
+}
+
+// employeePage is the response body of GetAllEmployees.
+type employeePage struct {
+	Employees     []models.EmployeeResponse `json:"employees"`
+	NextPageToken string                    `json:"nextPageToken,omitempty"`
+}
+
+// GetAllEmployees returns a page of employees.
 // @Summary Get all employees
-// @Description Get a list of all employees
+// @Description Get a page of employees. Soft-deleted employees are hidden unless includeDeleted is true. Pass the returned nextPageToken as pageToken, with the same orderBy, to fetch the next page.
 // @Produce json
-// @Success 200 {array} Employee
+// @Param limit query int false "Maximum number of employees per page (1-1000)"
+// @Param pageToken query string false "Token from a previous response's nextPageToken"
+// @Param orderBy query string false "Comma separated fields, '-' prefix for descending, e.g. lastName,-id"
+// @Param role query string false "Only employees with this role (also firstName, lastName, email, id, departmentId)"
+// @Param includeDeleted query bool false "Include soft-deleted employees"
+// @Success 200 {object} employeePage
+// @Failure 400 "Invalid query parameters"
+// @Failure 500 "Internal Server Error"
+// @Failure 503 "Firestore unavailable, retry later"
+// @Failure 401 "Authentication required"
+// @Failure 403 "Forbidden"
+// @Security BearerAuth
 // @Router /employees [get]
-func getAllEmployees(w http.ResponseWriter, r *http.Request) {
+func GetAllEmployees(w http.ResponseWriter, r *http.Request) {
 	utils.InitLogger()
 	utils.InfoLog("Request is being Processed for GetAllEmployees")
 	log.Print("Request received: GetAllEmployees")
 
-	client, err := utils.CreateFirestoreClient()
+	opts, err := repository.ListOptionsFromQuery(r.URL.Query())
 	if err != nil {
-		log.Print("Failed to create Firestore client:", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		log.Print("Invalid query parameters:", err)
+		utils.RespondWithError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
-	defer client.Close()
 
-	employees := []models.Employee{}
-	iter := client.Collection("employees").Documents(context.Background())
-	for {
-		doc, err := iter.Next()
-		if err != nil {
-			log.Print("Error fetching document:", err)
-			break
-		}
-		var employee models.Employee
-		if err := doc.DataTo(&employee); err != nil {
-			log.Print("Error parsing employee data:", err)
-			continue
-		}
-		employees = append(employees, employee)
+	if _, ok := utils.Authorize(w, r, policy.List, 0); !ok {
+		return
 	}
-	log.Print("Sending response: GetAllEmployees")
-	respondWithJSON(w, http.StatusOK, employees)
-	utils.InfoLog("Response Sent")
-}
 
-func respondWithError(w http.ResponseWriter, code int, message string) {
-	respondWithJSON(w, code, map[string]string{"error": message})
-}
+	repo, err := openRepository()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to create Firestore client")
+		return
+	}
+	defer repo.Close()
 
-func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
-	response, err := json.Marshal(payload)
+	page, err := repo.List(r.Context(), opts)
 	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		log.Print("Failed to retrieve employees from Firestore:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to retrieve employees from Firestore")
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	w.Write(response)
+	log.Print("Sending response: GetAllEmployees")
+	utils.RespondWithJSON(w, http.StatusOK, employeePage{
+		Employees:     models.NewEmployeeResponses(page.Employees),
+		NextPageToken: page.NextPageToken,
+	})
+	utils.InfoLog("Response Sent")
 }
@@ -0,0 +1,274 @@
+package function1
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/repository"
+	"example.com/task3gcp/shared/utils"
+)
+
+func useRepository(t *testing.T, repo repository.EmployeeRepository) {
+	t.Helper()
+	original := openRepository
+	openRepository = func() (repository.EmployeeRepository, error) { return repo, nil }
+	t.Cleanup(func() { openRepository = original })
+}
+
+// testKey signs the access tokens of test requests; TestMain makes the
+// handlers verify them, as they verify the tokens the gateway forwards.
+var testKey ed25519.PrivateKey
+
+func TestMain(m *testing.M) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		panic(err)
+	}
+	testKey = key
+	utils.UseVerifier(auth.NewVerifier(key.Public().(ed25519.PublicKey)))
+	os.Exit(m.Run())
+}
+
+// newRequest returns a request carrying the access token the gateway
+// forwards for an authenticated admin.
+func newRequest(method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	setCaller(req, 1, "admin")
+	return req
+}
+
+// setCaller makes req carry an access token for the employee with the given
+// ID and role.
+func setCaller(req *http.Request, id int, role string) {
+	pair, err := auth.NewIssuer(testKey).Issue(models.Employee{ID: id, Role: role})
+	if err != nil {
+		panic(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+}
+
+// list calls the handler with the given query and decodes the returned page.
+func list(t *testing.T, query string) repository.Page {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	GetAllEmployees(rec, newRequest(http.MethodGet, "/?"+query, nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET ?%s: status = %d, want %d: %s", query, rec.Code, http.StatusOK, rec.Body)
+	}
+	var page repository.Page
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	return page
+}
+
+func ids(employees []models.Employee) []int {
+	result := make([]int, len(employees))
+	for i, employee := range employees {
+		result[i] = employee.ID
+	}
+	return result
+}
+
+func TestGetAllEmployees(t *testing.T) {
+	useRepository(t, repository.NewMemoryRepository(
+		models.Employee{ID: 1, FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Role: "admin"},
+		models.Employee{ID: 2, FirstName: "Alan", LastName: "Turing", Email: "alan@example.com", Role: "employee"},
+		models.Employee{ID: 3, FirstName: "Grace", LastName: "Hopper", Email: "grace@example.com", Role: "employee", Deleted: true},
+	))
+
+	page := list(t, "")
+	if got := ids(page.Employees); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("employees = %v, want IDs 1 and 2", got)
+	}
+	if page.NextPageToken != "" {
+		t.Errorf("nextPageToken = %q, want none without a limit", page.NextPageToken)
+	}
+
+	if got := ids(list(t, "includeDeleted=true").Employees); len(got) != 3 {
+		t.Errorf("includeDeleted=true returned %v, want all 3 employees", got)
+	}
+}
+
+func TestGetAllEmployeesPagination(t *testing.T) {
+	useRepository(t, repository.NewMemoryRepository(
+		models.Employee{ID: 1, LastName: "Turing", Role: "manager"},
+		models.Employee{ID: 2, LastName: "Hopper", Role: "manager"},
+		models.Employee{ID: 3, LastName: "Lovelace", Role: "employee"},
+		models.Employee{ID: 4, LastName: "Hopper", Role: "manager"},
+		models.Employee{ID: 5, LastName: "Knuth", Role: "manager"},
+	))
+
+	var got []int
+	query := url.Values{"limit": {"2"}, "orderBy": {"lastName,-id"}, "role": {"manager"}}
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("pagination did not terminate")
+		}
+		page := list(t, query.Encode())
+		got = append(got, ids(page.Employees)...)
+		if page.NextPageToken == "" {
+			break
+		}
+		query.Set("pageToken", page.NextPageToken)
+	}
+
+	want := []int{4, 2, 5, 1}
+	if len(got) != len(want) {
+		t.Fatalf("paged IDs = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("paged IDs = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestGetAllEmployeesInvalidQuery(t *testing.T) {
+	useRepository(t, repository.NewMemoryRepository())
+
+	for _, query := range []string{
+		"includeDeleted=maybe",
+		"limit=0",
+		"limit=abc",
+		"orderBy=password",
+		"id=abc",
+		"pageToken=garbage",
+		"orderBy=lastName&pageToken=" + url.QueryEscape(tokenFor(t)),
+	} {
+		rec := httptest.NewRecorder()
+		GetAllEmployees(rec, newRequest(http.MethodGet, "/?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET ?%s: status = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+// tokenFor returns a valid page token for a listing ordered by ID.
+func tokenFor(t *testing.T) string {
+	t.Helper()
+	repo := repository.NewMemoryRepository(models.Employee{ID: 1}, models.Employee{ID: 2})
+	page, err := repo.List(context.Background(), repository.ListOptions{
+		Limit:   1,
+		OrderBy: []repository.SortField{{Field: "id"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return page.NextPageToken
+}
+
+// export calls ExportEmployeesHandler with the given query and returns the
+// response body.
+func export(t *testing.T, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	ExportEmployeesHandler(rec, newRequest(http.MethodGet, "/export?"+query, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET ?%s: status = %d, want %d: %s", query, rec.Code, http.StatusOK, rec.Body)
+	}
+	return rec
+}
+
+func useExportRepository(t *testing.T) {
+	t.Helper()
+	useRepository(t, repository.NewMemoryRepository(
+		models.Employee{ID: 1, FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Password: "secret1", Role: "admin", Version: 1},
+		models.Employee{ID: 2, FirstName: "=HYPERLINK(\"x\")", LastName: "Turing", Email: "alan@example.com", Password: "secret1", Role: "employee", DepartmentID: 3, Version: 2},
+		models.Employee{ID: 3, FirstName: "Grace", LastName: "Hopper", Email: "grace@example.com", Password: "secret1", Role: "employee", Deleted: true},
+		models.Employee{ID: 4, FirstName: "Barbara", LastName: "Liskov", Email: "barbara@example.com", Password: "secret1", Role: "employee", Version: 1},
+	))
+	original := exportPageSize
+	exportPageSize = 2
+	t.Cleanup(func() { exportPageSize = original })
+}
+
+func TestExportEmployeesHandlerCSV(t *testing.T) {
+	useExportRepository(t)
+
+	rec := export(t, "role=employee")
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="employees.csv"` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, "\ufeff") {
+		t.Error("CSV export has no byte order mark")
+	}
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(body, "\ufeff"))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"id", "firstName", "lastName", "email", "role", "departmentId", "deleted", "deletedAt", "version"},
+		{"2", "'=HYPERLINK(\"x\")", "Turing", "alan@example.com", "employee", "3", "false", "", "2"},
+		{"4", "Barbara", "Liskov", "barbara@example.com", "employee", "", "false", "", "1"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("export = %q, want %q", records, want)
+	}
+	for i := range want {
+		if strings.Join(records[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("row %d = %q, want %q", i, records[i], want[i])
+		}
+	}
+}
+
+func TestExportEmployeesHandlerJSON(t *testing.T) {
+	useExportRepository(t)
+
+	for _, format := range []string{"json", "ndjson"} {
+		body := export(t, "format="+format+"&includeDeleted=true&orderBy=-id").Body.Bytes()
+		if bytes.Contains(body, []byte("password")) || bytes.Contains(body, []byte("secret1")) {
+			t.Errorf("%s export contains passwords: %s", format, body)
+		}
+
+		var employees []models.EmployeeResponse
+		if format == "json" {
+			if err := json.Unmarshal(body, &employees); err != nil {
+				t.Fatalf("decode %s: %v", body, err)
+			}
+		} else {
+			decoder := json.NewDecoder(bytes.NewReader(body))
+			for decoder.More() {
+				var employee models.EmployeeResponse
+				if err := decoder.Decode(&employee); err != nil {
+					t.Fatalf("decode %s: %v", body, err)
+				}
+				employees = append(employees, employee)
+			}
+		}
+		if len(employees) != 4 || employees[0].ID != 4 || employees[3].ID != 1 || !employees[1].Deleted {
+			t.Errorf("%s export = %+v, want employees 4 to 1", format, employees)
+		}
+	}
+
+	useRepository(t, repository.NewMemoryRepository())
+	if body := export(t, "format=json").Body.String(); body != "[]\n" {
+		t.Errorf("empty json export = %q, want an empty array", body)
+	}
+}
+
+func TestExportEmployeesHandlerInvalidQuery(t *testing.T) {
+	useRepository(t, repository.NewMemoryRepository())
+
+	for _, query := range []string{"format=xlsx", "orderBy=password", "includeDeleted=maybe"} {
+		rec := httptest.NewRecorder()
+		ExportEmployeesHandler(rec, newRequest(http.MethodGet, "/export?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET ?%s: status = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
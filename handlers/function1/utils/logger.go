@@ -1,31 +0,0 @@
-package utils
-
-import (
-	"log"
-	"os"
-)
-
-var (
-	InfoLogger  *log.Logger
-	ErrorLogger *log.Logger
-)
-
-func InitLogger() {
-	logFile, err := os.OpenFile("application.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	if err != nil {
-		log.Fatalln("Failed to open log file:", err)
-	}
-
-	InfoLogger = log.New(logFile, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile)
-	ErrorLogger = log.New(logFile, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile)
-
-	log.SetOutput(logFile)
-}
-
-func InfoLog(message string) {
-	InfoLogger.Println(message)
-}
-
-func ErrorLog(err error) {
-	ErrorLogger.Println(err)
-}
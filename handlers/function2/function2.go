@@ -1,23 +1,34 @@
 package function2
 
 import (
-	"context"
-	"encoding/json"
 	"log"
 	"net/http"
 	"strconv"
 
-	"example.com/task3gcp/models"
-	"example.com/task3gcp/utils"
+	"example.com/task3gcp/shared/config"
+	"example.com/task3gcp/shared/policy"
+	"example.com/task3gcp/shared/repository"
+	"example.com/task3gcp/shared/utils"
 
 	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
-	"github.com/gorilla/mux"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 func init() {
-	functions.HTTP("GetEmployeeByID", getEmployeeByID)
+	config.MustLoadInFunction()
+	functions.HTTP("GetEmployeeByID", GetEmployeeByID)
+	functions.HTTP("EmployeeHistoryHandler", EmployeeHistoryHandler)
+	functions.HTTP("EmployeeVersionHandler", EmployeeVersionHandler)
+}
+
+// openRepository returns the employee store used by the handler, backed by
+// the Firestore client shared by every invocation. It is a variable so tests
+// can substitute an in-memory repository.
+var openRepository = utils.OpenRepository
+
+// UseRepository replaces the Firestore repository, e.g. with an in-memory one
+// when the gateway runs the handlers in local mode.
+func UseRepository(open func() (repository.EmployeeRepository, error)) {
+	openRepository = open
 }
 
 // GetEmployeeByID returns a specific employee by ID.
@@ -25,74 +36,70 @@ func init() {
 // @Description Get an employee by ID
 // @Produce json
 // @Param id path number true "ID"
-// @Success 200 {object} Employee
+// @Param includeDeleted query bool false "Return the employee even if it is soft-deleted"
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Success 200 {object} models.EmployeeResponse
+// @Success 304 "Not modified since the given ETag"
 // @Failure 400 "Invalid employee ID"
+// @Failure 400 "Invalid includeDeleted value"
 // @Failure 404 "Employee not found"
 // @Failure 500 "Internal Server Error"
+// @Failure 503 "Firestore unavailable, retry later"
+// @Failure 401 "Authentication required"
+// @Failure 403 "Forbidden"
+// @Security BearerAuth
 // @Router /function-2/{id} [get]
-func getEmployeeByID(w http.ResponseWriter, r *http.Request) {
+func GetEmployeeByID(w http.ResponseWriter, r *http.Request) {
 	utils.InitLogger()
 	log.Print("Request is being Processed for GetEmployeeByID")
 
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
+	id, err := strconv.Atoi(utils.EmployeeID(r))
 	if err != nil {
 		log.Print("Invalid employee ID:", err)
-		respondWithError(w, http.StatusBadRequest, "Invalid employee ID")
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid employee ID")
 		return
 	}
 
+	includeDeleted := false
+	if value := r.URL.Query().Get("includeDeleted"); value != "" {
+		includeDeleted, err = strconv.ParseBool(value)
+		if err != nil {
+			log.Print("Invalid includeDeleted value:", err)
+			utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid includeDeleted value")
+			return
+		}
+	}
+
 	log.Print("Request received: GetEmployeeByID, ID:", id)
 
-	client, err := utils.CreateFirestoreClient()
+	if _, ok := utils.Authorize(w, r, policy.Get, id); !ok {
+		return
+	}
+
+	repo, err := openRepository()
 	if err != nil {
 		log.Print("Failed to create Firestore client:", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to create Firestore client")
+		utils.RespondWithDomainError(w, r, err, "Failed to create Firestore client")
 		return
 	}
-	defer client.Close()
-
-	// Define a query to retrieve the document with the specified "Id" field value
-	query := client.Collection("employees").Where("ID", "==", id)
-
-	// Run the query
-	iter := query.Documents(context.Background())
-	doc, err := iter.Next()
+	defer repo.Close()
 
+	employee, err := repo.Get(r.Context(), id)
+	if err == nil && employee.Deleted && !includeDeleted {
+		err = repository.ErrNotFound
+	}
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			log.Print("Employee not found:", err)
-			respondWithError(w, http.StatusNotFound, "Employee not found")
-			return
-		}
 		log.Print("Failed to retrieve employee from Firestore:", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve employee from Firestore")
+		utils.RespondWithDomainError(w, r, err, "Failed to retrieve employee from Firestore")
 		return
 	}
 
-	var employee models.Employee
-	if err := doc.DataTo(&employee); err != nil {
-		log.Print("Failed to parse employee data:", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to parse employee data")
+	if utils.NotModified(w, r, employee) {
+		log.Print("Employee not modified: GetEmployeeByID")
 		return
 	}
 
 	log.Print("Sending response: GetEmployeeByID")
-	respondWithJSON(w, http.StatusOK, employee)
+	utils.RespondWithJSON(w, http.StatusOK, employee.Response())
 	log.Print("Response Sent: GetEmployeeByID")
 }
-
-func respondWithError(w http.ResponseWriter, code int, message string) {
-	respondWithJSON(w, code, map[string]string{"error": message})
-}
-
-func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
-	response, err := json.Marshal(payload)
-	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	w.Write(response)
-}
@@ -0,0 +1,319 @@
+package function2
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/repository"
+	"example.com/task3gcp/shared/utils"
+	"github.com/gorilla/mux"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func useRepository(t *testing.T, repo repository.EmployeeRepository) {
+	t.Helper()
+	original := openRepository
+	openRepository = func() (repository.EmployeeRepository, error) { return repo, nil }
+	t.Cleanup(func() { openRepository = original })
+}
+
+// testKey signs the access tokens of test requests; TestMain makes the
+// handlers verify them, as they verify the tokens the gateway forwards.
+var testKey ed25519.PrivateKey
+
+func TestMain(m *testing.M) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		panic(err)
+	}
+	testKey = key
+	utils.UseVerifier(auth.NewVerifier(key.Public().(ed25519.PublicKey)))
+	os.Exit(m.Run())
+}
+
+// newRequest returns a request carrying the access token the gateway
+// forwards for an authenticated admin.
+func newRequest(method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	setCaller(req, 1, "admin")
+	return req
+}
+
+// setCaller makes req carry an access token for the employee with the given
+// ID and role.
+func setCaller(req *http.Request, id int, role string) {
+	pair, err := auth.NewIssuer(testKey).Issue(models.Employee{ID: id, Role: role})
+	if err != nil {
+		panic(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+}
+
+func TestGetEmployeeByID(t *testing.T) {
+	repo := repository.NewMemoryRepository(
+		models.Employee{ID: 7, FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Password: "secret1", Role: "admin"},
+		models.Employee{ID: 9, FirstName: "Alan", LastName: "Turing", Email: "alan@example.com", Role: "employee"},
+	)
+	useRepository(t, repo)
+	if err := repo.Delete(context.Background(), 9, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		want int
+	}{
+		{"found", "7", http.StatusOK},
+		{"not found", "8", http.StatusNotFound},
+		{"deleted", "9", http.StatusNotFound},
+		{"deleted included", "9?includeDeleted=true", http.StatusOK},
+		{"invalid includeDeleted", "7?includeDeleted=maybe", http.StatusBadRequest},
+		{"invalid id", "abc", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, _, _ := strings.Cut(tt.id, "?")
+			req := mux.SetURLVars(newRequest(http.MethodGet, "/"+tt.id, nil), map[string]string{"id": id})
+			rec := httptest.NewRecorder()
+			GetEmployeeByID(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			if strings.Contains(rec.Body.String(), "password") {
+				t.Errorf("response exposes the password: %s", rec.Body)
+			}
+			var employee models.Employee
+			if err := json.NewDecoder(rec.Body).Decode(&employee); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if employee.ID != atoi(t, id) {
+				t.Errorf("employee = %+v, want ID %s", employee, id)
+			}
+		})
+	}
+}
+
+func atoi(t *testing.T, s string) int {
+	t.Helper()
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestGetEmployeeByIDFromPath(t *testing.T) {
+	useRepository(t, repository.NewMemoryRepository(models.Employee{ID: 7, FirstName: "Ada"}))
+
+	// Forwarded by the gateway, without mux route variables.
+	rec := httptest.NewRecorder()
+	GetEmployeeByID(rec, newRequest(http.MethodGet, "/function-2/7?includeDeleted=false", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var employee models.Employee
+	if err := json.NewDecoder(rec.Body).Decode(&employee); err != nil {
+		t.Fatal(err)
+	}
+	if employee.ID != 7 {
+		t.Errorf("employee ID = %d, want 7", employee.ID)
+	}
+}
+
+// failingRepository fails every Get with err.
+type failingRepository struct {
+	repository.EmployeeRepository
+	err error
+}
+
+func (r failingRepository) Get(context.Context, int) (models.Employee, error) {
+	return models.Employee{}, r.err
+}
+
+func (r failingRepository) Close() error { return nil }
+
+func TestGetEmployeeByIDErrorKinds(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		want     int
+		wantText string
+	}{
+		{"no query result", iterator.Done, http.StatusNotFound, ""},
+		{"backend unavailable", status.Error(codes.Unavailable, "connection refused"), http.StatusServiceUnavailable, "Failed to retrieve employee from Firestore"},
+		{"internal", errors.New("decode: bad field"), http.StatusInternalServerError, "Failed to retrieve employee from Firestore"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useRepository(t, failingRepository{err: tt.err})
+			rec := httptest.NewRecorder()
+			GetEmployeeByID(rec, newRequest(http.MethodGet, "/7", nil))
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantText) {
+				t.Errorf("body = %s, want it to mention %q", rec.Body, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestGetEmployeeByIDConditional(t *testing.T) {
+	useRepository(t, repository.NewMemoryRepository(models.Employee{ID: 7, FirstName: "Ada", Version: 3}))
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		want        int
+	}{
+		{"unconditional", "", http.StatusOK},
+		{"current", `"3"`, http.StatusNotModified},
+		{"weak current", `"1", W/"3"`, http.StatusNotModified},
+		{"any", "*", http.StatusNotModified},
+		{"stale", `"2"`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := mux.SetURLVars(newRequest(http.MethodGet, "/7", nil), map[string]string{"id": "7"})
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			rec := httptest.NewRecorder()
+			GetEmployeeByID(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if got := rec.Header().Get("ETag"); got != `"3"` {
+				t.Errorf("ETag = %s, want \"3\"", got)
+			}
+			if rec.Code == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Errorf("304 response has a body: %s", rec.Body)
+			}
+		})
+	}
+}
+
+func TestEmployeeHistoryHandler(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	useRepository(t, repo)
+	ctx := auth.ContextWithIdentity(context.Background(), auth.Identity{EmployeeID: 1, Role: "admin"})
+	created, err := repo.Create(ctx, models.Employee{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Password: "secret1", Role: "employee"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(ctx, created.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	EmployeeHistoryHandler(rec, newRequest(http.MethodGet, "/"+strconv.Itoa(created.ID), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), "secret1") || strings.Contains(rec.Body.String(), "$2a$") {
+		t.Errorf("history leaks the password: %s", rec.Body)
+	}
+	var history struct {
+		Entries []repository.AuditEntry `json:"entries"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&history); err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Entries) != 2 || history.Entries[0].Operation != "create" || history.Entries[1].Operation != "delete" ||
+		history.Entries[1].Actor.EmployeeID != 1 {
+		t.Errorf("history = %+v, want the create and the delete by employee 1", history.Entries)
+	}
+
+	for target, want := range map[string]int{"/abc": http.StatusBadRequest, "/99": http.StatusNotFound} {
+		rec := httptest.NewRecorder()
+		EmployeeHistoryHandler(rec, newRequest(http.MethodGet, target, nil))
+		if rec.Code != want {
+			t.Errorf("GET %s: status = %d, want %d", target, rec.Code, want)
+		}
+	}
+
+	req := newRequest(http.MethodGet, "/"+strconv.Itoa(created.ID), nil)
+	setCaller(req, created.ID, "employee")
+	rec = httptest.NewRecorder()
+	EmployeeHistoryHandler(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("employee reading their own history: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestEmployeeVersionHandler(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	useRepository(t, repo)
+	created, err := repo.Create(context.Background(), models.Employee{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Password: "secret1", Role: "employee"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Patch(context.Background(), models.Employee{ID: created.ID, LastName: "King"}, []string{"lastName"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The gateway forwards /function-2/{id}/versions/{n} as /{id}/{n}
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantName   string
+	}{
+		{"first version", fmt.Sprintf("/%d/1", created.ID), http.StatusOK, "Lovelace"},
+		{"current version", fmt.Sprintf("/%d/2", created.ID), http.StatusOK, "King"},
+		{"future version", fmt.Sprintf("/%d/3", created.ID), http.StatusNotFound, ""},
+		{"invalid version", fmt.Sprintf("/%d/0", created.ID), http.StatusBadRequest, ""},
+		{"invalid ID", "/abc/1", http.StatusBadRequest, ""},
+		{"unknown employee", "/99/1", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			EmployeeVersionHandler(rec, newRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var employee models.EmployeeResponse
+			if err := json.NewDecoder(rec.Body).Decode(&employee); err != nil {
+				t.Fatal(err)
+			}
+			if employee.LastName != tt.wantName {
+				t.Errorf("lastName = %q, want %q", employee.LastName, tt.wantName)
+			}
+		})
+	}
+
+	// Behind the gateway router the path variables are used
+	router := mux.NewRouter()
+	router.HandleFunc("/function-2/{id}/versions/{n}", EmployeeVersionHandler)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newRequest(http.MethodGet, fmt.Sprintf("/function-2/%d/versions/1", created.ID), nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("routed request: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
@@ -1,9 +1,9 @@
-module example.com/task3gcp
+module example.com/task3gcp/function2
 
 go 1.21.0
 
 require (
-	github.com/gorilla/mux v1.8.0
+	github.com/gorilla/mux v1.8.1
 	google.golang.org/grpc v1.59.0
 )
 
@@ -14,8 +14,8 @@ require (
 	cloud.google.com/go/firestore v1.14.0 // indirect
 	cloud.google.com/go/logging v1.8.1 // indirect
 	cloud.google.com/go/longrunning v0.5.2 // indirect
-	github.com/GoogleCloudPlatform/functions-framework-go v1.8.0 // indirect
 	github.com/cloudevents/sdk-go/v2 v2.14.0 // indirect
+	github.com/golang-jwt/jwt/v5 v5.2.1 // indirect
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 	github.com/google/s2a-go v0.1.7 // indirect
 	github.com/google/uuid v1.4.0 // indirect
@@ -42,8 +42,20 @@ require (
 )
 
 require (
+	github.com/gabriel-vasile/mimetype v1.4.2 // indirect
+	github.com/go-playground/locales v0.14.1 // indirect
+	github.com/go-playground/universal-translator v0.18.1 // indirect
+	github.com/go-playground/validator/v10 v10.15.5 // indirect
 	github.com/golang/protobuf v1.5.3 // indirect
-	google.golang.org/api v0.149.0
+	github.com/leodido/go-urn v1.2.4 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20231016165738-49dd2c1f3d0b // indirect
 	google.golang.org/protobuf v1.31.0 // indirect
 )
+
+require (
+	example.com/task3gcp/shared v0.18.0
+	github.com/GoogleCloudPlatform/functions-framework-go v1.8.0
+	google.golang.org/api v0.149.0
+)
+
+replace example.com/task3gcp/shared => ../shared
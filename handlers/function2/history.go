@@ -0,0 +1,65 @@
+package function2
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"example.com/task3gcp/shared/policy"
+	"example.com/task3gcp/shared/repository"
+	"example.com/task3gcp/shared/utils"
+)
+
+// employeeHistory is the response body of EmployeeHistoryHandler.
+type employeeHistory struct {
+	Entries []repository.AuditEntry `json:"entries"`
+}
+
+// EmployeeHistoryHandler returns the audit log of an employee: who created,
+// changed, deleted or restored it, and when. Passwords are redacted.
+// @Summary Get the change history of an employee
+// @Description List every change to the employee, oldest first, with the caller that made it and the fields it changed. Password values are redacted. Soft-deleted employees keep their history
+// @Produce json
+// @Param id path number true "Employee ID"
+// @Success 200 {object} employeeHistory
+// @Failure 400 "Invalid employee ID"
+// @Failure 404 "Employee not found"
+// @Failure 500 "Internal Server Error"
+// @Failure 503 "Firestore unavailable, retry later"
+// @Failure 401 "Authentication required"
+// @Failure 403 "Forbidden"
+// @Security BearerAuth
+// @Router /function-2/{id}/history [get]
+func EmployeeHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+	log.Print("Request is being Processed for EmployeeHistoryHandler")
+
+	id, err := strconv.Atoi(utils.EmployeeID(r))
+	if err != nil {
+		log.Print("Invalid employee ID:", err)
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid employee ID")
+		return
+	}
+
+	if _, ok := utils.Authorize(w, r, policy.History, id); !ok {
+		return
+	}
+
+	repo, err := openRepository()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to create Firestore client")
+		return
+	}
+	defer repo.Close()
+
+	entries, err := repo.History(r.Context(), id)
+	if err != nil {
+		log.Print("Failed to retrieve employee history from Firestore:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to retrieve employee history from Firestore")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, employeeHistory{Entries: entries})
+	log.Print("Response Sent: EmployeeHistoryHandler")
+}
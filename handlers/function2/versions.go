@@ -0,0 +1,85 @@
+package function2
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"example.com/task3gcp/shared/policy"
+	"example.com/task3gcp/shared/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// EmployeeVersionHandler returns an employee as it was at one of its
+// versions, e.g. to review a revision before rolling back to it.
+// @Summary Get a version of an employee
+// @Description Get the employee as it was at version n. Every write keeps a numbered version; versions written before versions were kept are not found, except the current one. Deleted employees keep their versions
+// @Produce json
+// @Param id path number true "Employee ID"
+// @Param n path number true "Version number"
+// @Success 200 {object} models.EmployeeResponse
+// @Failure 400 "Invalid employee ID or version"
+// @Failure 404 "Employee or version not found"
+// @Failure 500 "Internal Server Error"
+// @Failure 503 "Firestore unavailable, retry later"
+// @Failure 401 "Authentication required"
+// @Failure 403 "Forbidden"
+// @Security BearerAuth
+// @Router /function-2/{id}/versions/{n} [get]
+func EmployeeVersionHandler(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+	log.Print("Request is being Processed for EmployeeVersionHandler")
+
+	idParam, versionParam := versionPath(r)
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		log.Print("Invalid employee ID:", err)
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid employee ID")
+		return
+	}
+	version, err := strconv.Atoi(versionParam)
+	if err != nil || version < 1 {
+		log.Print("Invalid version:", versionParam)
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid version")
+		return
+	}
+
+	// Old versions are part of the employee's history
+	if _, ok := utils.Authorize(w, r, policy.History, id); !ok {
+		return
+	}
+
+	repo, err := openRepository()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to create Firestore client")
+		return
+	}
+	defer repo.Close()
+
+	employee, err := repo.Version(r.Context(), id, version)
+	if err != nil {
+		log.Print("Failed to retrieve employee version from Firestore:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to retrieve employee version from Firestore")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, employee.Response())
+	log.Print("Response Sent: EmployeeVersionHandler")
+}
+
+// versionPath returns the employee ID and version number of the request,
+// from the route variables or, when the handler is not behind a router,
+// from the last two path segments.
+func versionPath(r *http.Request) (id, version string) {
+	if vars := mux.Vars(r); vars["n"] != "" {
+		return vars["id"], vars["n"]
+	}
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(segments) < 2 {
+		return "", ""
+	}
+	return segments[len(segments)-2], segments[len(segments)-1]
+}
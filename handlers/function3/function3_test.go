@@ -0,0 +1,340 @@
+package function3
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/importer"
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/repository"
+	"example.com/task3gcp/shared/utils"
+)
+
+func useRepository(t *testing.T, repo repository.EmployeeRepository) {
+	t.Helper()
+	original := openRepository
+	openRepository = func() (repository.EmployeeRepository, error) { return repo, nil }
+	t.Cleanup(func() { openRepository = original })
+}
+
+// testKey signs the access tokens of test requests; TestMain makes the
+// handlers verify them, as they verify the tokens the gateway forwards.
+var testKey ed25519.PrivateKey
+
+func TestMain(m *testing.M) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		panic(err)
+	}
+	testKey = key
+	utils.UseVerifier(auth.NewVerifier(key.Public().(ed25519.PublicKey)))
+	os.Exit(m.Run())
+}
+
+// newRequest returns a request carrying the access token the gateway
+// forwards for an authenticated admin.
+func newRequest(method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	setCaller(req, 1, "admin")
+	return req
+}
+
+// setCaller makes req carry an access token for the employee with the given
+// ID and role.
+func setCaller(req *http.Request, id int, role string) {
+	pair, err := auth.NewIssuer(testKey).Issue(models.Employee{ID: id, Role: role})
+	if err != nil {
+		panic(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+}
+
+func TestCreateEmployeeHandler(t *testing.T) {
+	repo := repository.NewMemoryRepository(models.Employee{ID: 3, FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Role: "admin"})
+	useRepository(t, repo)
+
+	body := `{"firstName":"Alan","lastName":"Turing","email":"alan@example.com","password":"secret1","role":"employee"}`
+	rec := httptest.NewRecorder()
+	CreateEmployeeHandler(rec, newRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+	employee, err := repo.Get(context.Background(), 4)
+	if err != nil {
+		t.Fatalf("Get(4): %v", err)
+	}
+	if employee.Email != "alan@example.com" {
+		t.Errorf("stored employee = %+v", employee)
+	}
+	if employee.Password == "secret1" || !employee.CheckPassword("secret1") {
+		t.Errorf("stored password = %q, want a bcrypt hash", employee.Password)
+	}
+
+	if etag := rec.Header().Get("ETag"); etag != utils.ETag(employee) {
+		t.Errorf("ETag = %q, want %q", etag, utils.ETag(employee))
+	}
+	if location := rec.Header().Get("Location"); location != "/function-2/4" {
+		t.Errorf("Location = %q, want /function-2/4", location)
+	}
+	var created models.EmployeeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if created.ID != 4 || created.Email != "alan@example.com" {
+		t.Errorf("response = %+v, want the stored employee", created)
+	}
+}
+
+func TestCreateEmployeeHandlerValidation(t *testing.T) {
+	useRepository(t, repository.NewMemoryRepository())
+
+	for name, body := range map[string]string{
+		"malformed":     `{"firstName":`,
+		"missing field": `{"firstName":"Alan","email":"alan@example.com","password":"secret1","role":"employee"}`,
+		"bad email":     `{"firstName":"Alan","lastName":"Turing","email":"alan","password":"secret1","role":"employee"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			CreateEmployeeHandler(rec, newRequest(http.MethodPost, "/", strings.NewReader(body)))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestCreateEmployeeHandlerRoles(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	useRepository(t, repo)
+
+	for role, want := range map[string]int{"admin": http.StatusForbidden, "manager": http.StatusCreated} {
+		body := fmt.Sprintf(`{"firstName":"Alan","lastName":"Turing","email":"alan.%s@example.com","password":"secret1","role":%q}`, role, role)
+		req := newRequest(http.MethodPost, "/", strings.NewReader(body))
+		setCaller(req, 2, "hr")
+		rec := httptest.NewRecorder()
+		CreateEmployeeHandler(rec, req)
+		if rec.Code != want {
+			t.Errorf("hr creating a %s: status = %d, want %d: %s", role, rec.Code, want, rec.Body)
+		}
+	}
+	if page, _ := repo.List(context.Background(), repository.ListOptions{}); len(page.Employees) != 1 {
+		t.Errorf("stored %d employees, want only the manager", len(page.Employees))
+	}
+}
+
+func TestCreateEmployeeHandlerFieldErrors(t *testing.T) {
+	useRepository(t, repository.NewMemoryRepository())
+
+	body := `{"firstName":"Alan","lastName":"Turing","email":"alan","password":"abc","role":"employee"}`
+	rec := httptest.NewRecorder()
+	CreateEmployeeHandler(rec, newRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	var problem utils.Problem
+	if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	if problem.Code != "validation" || len(problem.Errors) != 2 {
+		t.Fatalf("problem = %+v, want email and password errors", problem)
+	}
+	if got := problem.Errors[0]; got.Field != "email" || got.Code != "email" {
+		t.Errorf("errors[0] = %+v, want an email check on email", got)
+	}
+	if got := problem.Errors[1]; got.Field != "password" || got.Code != "min" || got.Message != "password must be at least 6 characters" {
+		t.Errorf("errors[1] = %+v, want a min check on password", got)
+	}
+}
+
+func TestCreateEmployeeHandlerDuplicateEmail(t *testing.T) {
+	repo := repository.NewMemoryRepository(models.Employee{ID: 3, FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Role: "admin"})
+	useRepository(t, repo)
+
+	body := `{"firstName":"Ada","lastName":"King","email":"ADA@Example.com","password":"secret1","role":"employee"}`
+	rec := httptest.NewRecorder()
+	CreateEmployeeHandler(rec, newRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body)
+	}
+	if _, err := repo.Get(context.Background(), 4); err != repository.ErrNotFound {
+		t.Errorf("Get(4) = %v, want the duplicate not stored", err)
+	}
+}
+
+func TestCreateEmployeeHandlerParallel(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	useRepository(t, repo)
+
+	const creates = 50
+	var wg sync.WaitGroup
+	for i := 0; i < creates; i++ {
+		wg.Add(1)
+		body := fmt.Sprintf(`{"firstName":"Alan","lastName":"Turing","email":"alan%d@example.com","password":"secret1","role":"employee"}`, i)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			CreateEmployeeHandler(rec, newRequest(http.MethodPost, "/", strings.NewReader(body)))
+			if rec.Code != http.StatusCreated {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusCreated)
+			}
+		}()
+	}
+	wg.Wait()
+
+	page, err := repo.List(context.Background(), repository.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	employees := page.Employees
+	if len(employees) != creates {
+		t.Fatalf("stored %d employees, want %d", len(employees), creates)
+	}
+	for i, employee := range employees {
+		if employee.ID != i+1 {
+			t.Fatalf("employee %d has ID %d, want %d", i, employee.ID, i+1)
+		}
+	}
+}
+
+// newUpload returns an import request that uploads file as the multipart
+// field "file".
+func newUpload(t *testing.T, target, file string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "employees.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(part, file)
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := newRequest(http.MethodPost, target, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+const importFile = "First,Last,Mail,password,role\n" +
+	"Alan,Turing,alan@example.com,secret1,employee\n" +
+	"Grace,Hopper,ada@example.com,secret1,manager\n" +
+	"Edsger,,edsger@example.com,secret1,employee\n"
+
+func TestImportEmployeesHandler(t *testing.T) {
+	repo := repository.NewMemoryRepository(models.Employee{ID: 1, Email: "ada@example.com"})
+	useRepository(t, repo)
+
+	rec := httptest.NewRecorder()
+	target := "/import?mapping=" + url.QueryEscape("firstName=First,lastName=Last,email=Mail")
+	ImportEmployeesHandler(rec, newUpload(t, target, importFile))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var result struct {
+		Rows     int
+		Imported int
+		Rejected []struct {
+			Line    int
+			Message string
+		}
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Rows != 3 || result.Imported != 1 || len(result.Rejected) != 2 || result.Rejected[0].Line != 3 || result.Rejected[1].Line != 4 {
+		t.Errorf("result = %+v, want lines 3 and 4 of 3 rows rejected", result)
+	}
+	if employee, err := repo.Get(context.Background(), 2); err != nil || employee.Email != "alan@example.com" {
+		t.Errorf("Get(2) = %+v, %v, want the imported employee", employee, err)
+	}
+}
+
+func TestImportEmployeesHandlerRoles(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	useRepository(t, repo)
+
+	file := "firstName,lastName,email,password,role\n" +
+		"Alan,Turing,alan@example.com,secret1,employee\n" +
+		"Grace,Hopper,grace@example.com,secret1,admin\n"
+	req := newUpload(t, "/import", file)
+	setCaller(req, 2, "hr")
+	rec := httptest.NewRecorder()
+	ImportEmployeesHandler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var result importer.Result
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 1 || len(result.Rejected) != 1 || result.Rejected[0].Line != 3 {
+		t.Errorf("result = %+v, want the admin on line 3 rejected", result)
+	}
+	if _, err := repo.GetByEmail(context.Background(), "grace@example.com"); err != repository.ErrNotFound {
+		t.Errorf("GetByEmail(grace): err = %v, want the admin not imported", err)
+	}
+}
+
+func TestImportEmployeesHandlerReport(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	useRepository(t, repo)
+
+	rec := httptest.NewRecorder()
+	req := newUpload(t, "/import?dryRun=true&mapping="+url.QueryEscape("firstName=First,lastName=Last,email=Mail"), importFile)
+	req.Header.Set("Accept", "text/csv")
+	ImportEmployeesHandler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if got := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(got, "attachment") {
+		t.Errorf("Content-Disposition = %q, want an attachment", got)
+	}
+	want := "First,Last,Mail,password,role,line,error\nEdsger,,edsger@example.com,,employee,4,lastName is required\n"
+	if rec.Body.String() != want {
+		t.Errorf("report = %q, want %q", rec.Body, want)
+	}
+	if page, _ := repo.List(context.Background(), repository.ListOptions{}); len(page.Employees) != 0 {
+		t.Errorf("dry run stored %d employees", len(page.Employees))
+	}
+}
+
+func TestImportEmployeesHandlerInvalid(t *testing.T) {
+	useRepository(t, repository.NewMemoryRepository())
+
+	for name, req := range map[string]*http.Request{
+		"missing column": newUpload(t, "/import", importFile),
+		"unknown field":  newUpload(t, "/import?mapping=salary=Pay", importFile),
+		"bad dryRun":     newUpload(t, "/import?dryRun=maybe", importFile),
+		"not multipart":  newRequest(http.MethodPost, "/import", strings.NewReader(importFile)),
+	} {
+		rec := httptest.NewRecorder()
+		ImportEmployeesHandler(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d: %s", name, rec.Code, http.StatusBadRequest, rec.Body)
+		}
+	}
+
+	req := newUpload(t, "/import", importFile)
+	setCaller(req, 1, "manager")
+	rec := httptest.NewRecorder()
+	ImportEmployeesHandler(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("manager: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
@@ -1,20 +1,24 @@
-module example.com/task3gcp
+module example.com/task3gcp/function3
 
 go 1.21.0
 
 require (
-	cloud.google.com/go/firestore v1.14.0
-	cloud.google.com/go/logging v1.8.1
+	cloud.google.com/go/firestore v1.14.0 // indirect
+	cloud.google.com/go/logging v1.8.1 // indirect
 	github.com/GoogleCloudPlatform/functions-framework-go v1.8.0
-	google.golang.org/api v0.149.0
-	google.golang.org/grpc v1.59.0
+	google.golang.org/api v0.149.0 // indirect
+	google.golang.org/grpc v1.59.0 // indirect
 )
 
 require (
 	github.com/cloudevents/sdk-go/v2 v2.14.0 // indirect
+	github.com/gabriel-vasile/mimetype v1.4.2 // indirect
 	github.com/go-playground/locales v0.14.1 // indirect
 	github.com/go-playground/universal-translator v0.18.1 // indirect
+	github.com/go-playground/validator/v10 v10.15.5 // indirect
+	github.com/golang-jwt/jwt/v5 v5.2.1 // indirect
 	github.com/google/uuid v1.4.0 // indirect
+	github.com/gorilla/mux v1.8.1 // indirect
 	github.com/json-iterator/go v1.1.10 // indirect
 	github.com/leodido/go-urn v1.2.4 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180228061459-e0a39a4cb421 // indirect
@@ -22,7 +26,6 @@ require (
 	go.uber.org/atomic v1.4.0 // indirect
 	go.uber.org/multierr v1.1.0 // indirect
 	go.uber.org/zap v1.10.0 // indirect
-	gopkg.in/go-playground/assert.v1 v1.2.1 // indirect
 )
 
 require (
@@ -30,7 +33,6 @@ require (
 	cloud.google.com/go/compute v1.23.1 // indirect
 	cloud.google.com/go/compute/metadata v0.2.3 // indirect
 	cloud.google.com/go/longrunning v0.5.2 // indirect
-	github.com/go-playground/validator v9.31.0+incompatible
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 	github.com/golang/protobuf v1.5.3 // indirect
 	github.com/google/s2a-go v0.1.7 // indirect
@@ -51,3 +53,7 @@ require (
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20231016165738-49dd2c1f3d0b // indirect
 	google.golang.org/protobuf v1.31.0 // indirect
 )
+
+require example.com/task3gcp/shared v0.18.0
+
+replace example.com/task3gcp/shared => ../shared
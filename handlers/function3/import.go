@@ -0,0 +1,135 @@
+package function3
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"example.com/task3gcp/shared/importer"
+	"example.com/task3gcp/shared/policy"
+	"example.com/task3gcp/shared/utils"
+)
+
+// maxImportSize caps the size of an uploaded import request.
+const maxImportSize = 64 << 20
+
+// ImportEmployeesHandler creates employees from an uploaded CSV file, e.g.
+// an export of the legacy HR system. The file is streamed into the
+// repository in batches; rows that fail validation or clash with an existing
+// email are skipped and reported.
+// @Summary Import employees from CSV
+// @Description Create employees from the CSV file in the multipart field "file". Columns are matched to the employee fields firstName, lastName, email, password, role and the optional departmentId by name, ignoring case, unless mapping renames them. Every row is validated like a created employee; rejected rows are listed in the response, or returned as a CSV error report when the request accepts text/csv
+// @Accept multipart/form-data
+// @Produce json
+// @Produce text/csv
+// @Param file formData file true "CSV file with a header row"
+// @Param mapping query string false "Columns of renamed fields, as field=Column pairs separated by commas, e.g. firstName=Given Name,email=Mail"
+// @Param dryRun query bool false "Validate the file without storing anything"
+// @Success 200 {object} importer.Result "Import summary, or the CSV report of rejected rows"
+// @Failure 400 "Invalid file or mapping"
+// @Failure 401 "Authentication required"
+// @Failure 403 "Forbidden"
+// @Failure 500 "Internal Server Error"
+// @Failure 503 "Firestore unavailable, retry later"
+// @Security BearerAuth
+// @Router /function-3/import [post]
+func ImportEmployeesHandler(w http.ResponseWriter, r *http.Request) {
+	utils.InitLogger()
+	log.Print("Request is being Processed for ImportEmployeesHandler")
+
+	rule, ok := utils.Authorize(w, r, policy.Import, 0)
+	if !ok {
+		return
+	}
+
+	opts, err := importOptions(r)
+	if err != nil {
+		log.Print("Invalid import options:", err)
+		utils.RespondWithDomainError(w, r, err, "Invalid import options")
+		return
+	}
+	// Rows with a role the caller may not grant are rejected like invalid ones
+	opts.Check = rule.CheckCreate
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	file, err := uploadedFile(r)
+	if err != nil {
+		log.Print("Invalid upload:", err)
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Expected a multipart/form-data upload with a file field")
+		return
+	}
+
+	repo, err := openRepository()
+	if err != nil {
+		log.Print("Failed to create Firestore client:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to create Firestore client")
+		return
+	}
+	defer repo.Close()
+
+	result, err := importer.Import(utils.CallerContext(r), repo, file, opts)
+	if err != nil {
+		if result != nil {
+			log.Printf("Import stopped after %d rows, %d employees imported", result.Rows, result.Imported)
+		}
+		log.Print("Failed to import employees:", err)
+		utils.RespondWithDomainError(w, r, err, "Failed to import employees")
+		return
+	}
+
+	log.Printf("Imported %d of %d rows", result.Imported, result.Rows)
+
+	if acceptsCSV(r) {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="import-errors.csv"`)
+		if err := result.WriteReport(w); err != nil {
+			log.Print("Failed to write import report:", err)
+		}
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, result)
+	log.Print("Response Sent: ImportEmployeesHandler")
+}
+
+// importOptions reads the mapping and dryRun query parameters.
+func importOptions(r *http.Request) (importer.Options, error) {
+	query := r.URL.Query()
+	mapping, err := importer.ParseMapping(query.Get("mapping"))
+	if err != nil {
+		return importer.Options{}, err
+	}
+	opts := importer.Options{Mapping: mapping}
+	if value := query.Get("dryRun"); value != "" {
+		if opts.DryRun, err = strconv.ParseBool(value); err != nil {
+			return importer.Options{}, fmt.Errorf("%w: dryRun must be true or false", importer.ErrInvalidFile)
+		}
+	}
+	return opts, nil
+}
+
+// uploadedFile returns the "file" part of a multipart request without
+// buffering it, so large files are streamed.
+func uploadedFile(r *http.Request) (io.Reader, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" {
+			return part, nil
+		}
+	}
+}
+
+// acceptsCSV reports whether the client asked for the CSV error report.
+func acceptsCSV(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Accept"))
+	return mediaType == "text/csv"
+}
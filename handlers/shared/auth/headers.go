@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Headers the gateway sets on every authenticated request it forwards, from
+// the claims of the access token. The gateway removes any client supplied
+// values first, but a function deployed at a public URL can be called
+// without it, so functions take the identity from the forwarded token
+// instead (see Claims.Identity).
+const (
+	HeaderEmployeeID    = "X-Employee-Id"
+	HeaderEmployeeEmail = "X-Employee-Email"
+	HeaderEmployeeRole  = "X-Employee-Role"
+)
+
+// SetIdentityHeaders replaces the identity headers of h with the verified
+// claims.
+func SetIdentityHeaders(h http.Header, claims *Claims) {
+	ClearIdentityHeaders(h)
+	h.Set(HeaderEmployeeID, claims.Subject)
+	h.Set(HeaderEmployeeEmail, claims.Email)
+	h.Set(HeaderEmployeeRole, claims.Role)
+}
+
+// ClearIdentityHeaders removes the identity headers from h.
+func ClearIdentityHeaders(h http.Header) {
+	h.Del(HeaderEmployeeID)
+	h.Del(HeaderEmployeeEmail)
+	h.Del(HeaderEmployeeRole)
+}
+
+// Identity is the caller identity of a request.
+type Identity struct {
+	EmployeeID int
+	Email      string
+	Role       string
+}
+
+// IdentityFromHeaders returns the identity forwarded by the gateway and
+// whether the request carried one.
+func IdentityFromHeaders(h http.Header) (Identity, bool) {
+	id, err := strconv.Atoi(h.Get(HeaderEmployeeID))
+	if err != nil {
+		return Identity{}, false
+	}
+	return Identity{EmployeeID: id, Email: h.Get(HeaderEmployeeEmail), Role: h.Get(HeaderEmployeeRole)}, true
+}
+
+// BearerToken returns the token of an "Authorization: Bearer" header.
+func BearerToken(h http.Header) (string, bool) {
+	const prefix = "Bearer "
+	value := h.Get("Authorization")
+	if len(value) <= len(prefix) || !strings.EqualFold(value[:len(prefix)], prefix) {
+		return "", false
+	}
+	return value[len(prefix):], true
+}
+
+type identityKey struct{}
+
+// ContextWithIdentity returns a copy of ctx that carries identity, so that
+// the repository can record who made a change.
+func ContextWithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the identity stored by ContextWithIdentity.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// DefaultKeyFile is the key file used when JWT_KEY_FILE is not set.
+const DefaultKeyFile = "jwt_key.pem"
+
+// KeyFile returns the path of the signing key file from the JWT_KEY_FILE
+// environment variable, or DefaultKeyFile.
+func KeyFile() string {
+	if path := os.Getenv("JWT_KEY_FILE"); path != "" {
+		return path
+	}
+	return DefaultKeyFile
+}
+
+// LoadPrivateKey reads a PEM encoded PKCS #8 Ed25519 private key, as written
+// by `openssl genpkey -algorithm ed25519`.
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an Ed25519 private key", path)
+	}
+	return privateKey, nil
+}
+
+// LoadPublicKey reads the key used to verify tokens. The file may hold the
+// PEM encoded public key or the private key itself, so a single key file is
+// enough for local development.
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type == "PRIVATE KEY" {
+		privateKey, err := LoadPrivateKey(path)
+		if err != nil {
+			return nil, err
+		}
+		return privateKey.Public().(ed25519.PublicKey), nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an Ed25519 public key", path)
+	}
+	return publicKey, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New(path + ": no PEM data found")
+	}
+	return block, nil
+}
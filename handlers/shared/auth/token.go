@@ -0,0 +1,147 @@
+// Package auth issues and verifies the signed JWTs that authenticate
+// employees. Tokens are signed with an Ed25519 key read from a local key
+// file, so no external key service is needed.
+package auth
+
+import (
+	"crypto/ed25519"
+	"strconv"
+	"time"
+
+	"example.com/task3gcp/shared/domain"
+	"example.com/task3gcp/shared/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Token types carried in the typ claim. Refresh tokens are only accepted by
+// the refresh endpoint and access tokens only by the gateway.
+const (
+	AccessToken  = "access"
+	RefreshToken = "refresh"
+)
+
+// Default token lifetimes.
+const (
+	DefaultAccessTTL  = 15 * time.Minute
+	DefaultRefreshTTL = 7 * 24 * time.Hour
+)
+
+const issuer = "task3gcp"
+
+// ErrInvalidToken is returned for tokens that are malformed, expired, signed
+// with another key or of the wrong type.
+var ErrInvalidToken = domain.New(domain.Unauthenticated, "invalid token")
+
+// Claims are the claims of an access or refresh token. The subject is the
+// employee ID.
+type Claims struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+	Type  string `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// EmployeeID returns the employee ID stored in the subject claim.
+func (c *Claims) EmployeeID() (int, error) {
+	return strconv.Atoi(c.Subject)
+}
+
+// Identity returns the caller identity carried by the claims.
+func (c *Claims) Identity() (Identity, error) {
+	id, err := c.EmployeeID()
+	if err != nil {
+		return Identity{}, err
+	}
+	return Identity{EmployeeID: id, Email: c.Email, Role: c.Role}, nil
+}
+
+// TokenPair is the response body of the login and refresh endpoints.
+type TokenPair struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	TokenType    string `json:"tokenType"`
+	// ExpiresIn is the lifetime of the access token in seconds.
+	ExpiresIn int `json:"expiresIn"`
+}
+
+// Issuer signs tokens for employees.
+type Issuer struct {
+	key        ed25519.PrivateKey
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+// NewIssuer returns an Issuer that signs with key and uses the default
+// lifetimes.
+func NewIssuer(key ed25519.PrivateKey) *Issuer {
+	return &Issuer{key: key, AccessTTL: DefaultAccessTTL, RefreshTTL: DefaultRefreshTTL}
+}
+
+// Issue returns a new access and refresh token for employee.
+func (i *Issuer) Issue(employee models.Employee) (TokenPair, error) {
+	now := time.Now()
+	access, err := i.sign(employee, AccessToken, now, i.AccessTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refresh, err := i.sign(employee, RefreshToken, now, i.RefreshTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(i.AccessTTL / time.Second),
+	}, nil
+}
+
+func (i *Issuer) sign(employee models.Employee, tokenType string, now time.Time, ttl time.Duration) (string, error) {
+	claims := Claims{
+		Email: employee.Email,
+		Role:  employee.Role,
+		Type:  tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   strconv.Itoa(employee.ID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims).SignedString(i.key)
+}
+
+// Verifier checks tokens signed by an Issuer.
+type Verifier struct {
+	key ed25519.PublicKey
+}
+
+// NewVerifier returns a Verifier for tokens signed with the private half of
+// key.
+func NewVerifier(key ed25519.PublicKey) *Verifier {
+	return &Verifier{key: key}
+}
+
+// Verify parses token and returns its claims if the signature is valid, the
+// token has not expired and it is of the given type.
+func (v *Verifier) Verify(token, tokenType string) (*Claims, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return v.key, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if claims.Type != tokenType {
+		return nil, ErrInvalidToken
+	}
+	if _, err := claims.EmployeeID(); err != nil {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
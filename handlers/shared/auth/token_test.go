@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"example.com/task3gcp/shared/models"
+)
+
+func newKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestIssueAndVerify(t *testing.T) {
+	key := newKey(t)
+	issuer := NewIssuer(key)
+	verifier := NewVerifier(key.Public().(ed25519.PublicKey))
+
+	pair, err := issuer.Issue(models.Employee{ID: 7, Email: "ada@example.com", Role: "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := verifier.Verify(pair.AccessToken, AccessToken)
+	if err != nil {
+		t.Fatalf("Verify(access): %v", err)
+	}
+	if id, _ := claims.EmployeeID(); id != 7 || claims.Email != "ada@example.com" || claims.Role != "admin" {
+		t.Errorf("claims = %+v", claims)
+	}
+	if _, err := verifier.Verify(pair.RefreshToken, RefreshToken); err != nil {
+		t.Errorf("Verify(refresh): %v", err)
+	}
+
+	// Tokens are only accepted for their own purpose.
+	if _, err := verifier.Verify(pair.RefreshToken, AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("refresh token accepted as access token: %v", err)
+	}
+	if _, err := verifier.Verify(pair.AccessToken, RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("access token accepted as refresh token: %v", err)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	key := newKey(t)
+	verifier := NewVerifier(key.Public().(ed25519.PublicKey))
+	employee := models.Employee{ID: 1, Role: "employee"}
+
+	expired := NewIssuer(key)
+	expired.AccessTTL = -time.Minute
+	expiredPair, _ := expired.Issue(employee)
+
+	otherPair, _ := NewIssuer(newKey(t)).Issue(employee)
+
+	for name, token := range map[string]string{
+		"expired":   expiredPair.AccessToken,
+		"other key": otherPair.AccessToken,
+		"garbage":   "not.a.token",
+		"empty":     "",
+	} {
+		if _, err := verifier.Verify(token, AccessToken); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: Verify error = %v, want ErrInvalidToken", name, err)
+		}
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	key := newKey(t)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwt_key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	privateKey, err := LoadPrivateKey(path)
+	if err != nil {
+		t.Fatalf("LoadPrivateKey: %v", err)
+	}
+	if !privateKey.Equal(key) {
+		t.Error("LoadPrivateKey returned a different key")
+	}
+	publicKey, err := LoadPublicKey(path)
+	if err != nil {
+		t.Fatalf("LoadPublicKey: %v", err)
+	}
+	if !publicKey.Equal(key.Public()) {
+		t.Error("LoadPublicKey returned a different key")
+	}
+}
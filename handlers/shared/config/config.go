@@ -0,0 +1,195 @@
+// Package config loads the Google Cloud settings shared by the functions,
+// the gateway and emsctl, so the same build runs against any project, with
+// any credentials, or against the Firestore emulator.
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Config holds the settings for reaching Google Cloud.
+type Config struct {
+	// ProjectID is the Google Cloud project that holds the Firestore
+	// database. When empty it is detected from the credentials.
+	ProjectID string `json:"projectId"`
+
+	// CredentialsFile is a service account key file. When empty,
+	// Application Default Credentials are used.
+	CredentialsFile string `json:"credentialsFile"`
+
+	// EmulatorHost is the host:port of a Firestore emulator. When set,
+	// credentials are not used and ProjectID is required.
+	EmulatorHost string `json:"emulatorHost"`
+
+	// LogName is the Cloud Logging log the functions write to.
+	LogName string `json:"logName"`
+}
+
+// defaultLogName is the log written to when LogName is not set.
+const defaultLogName = "my-log"
+
+// overrides lists the environment variables that override the config file, in
+// order of precedence for each field.
+var overrides = []struct {
+	names []string
+	field func(*Config) *string
+}{
+	{[]string{"GOOGLE_CLOUD_PROJECT", "GCP_PROJECT"}, func(c *Config) *string { return &c.ProjectID }},
+	{[]string{"GOOGLE_APPLICATION_CREDENTIALS"}, func(c *Config) *string { return &c.CredentialsFile }},
+	{[]string{"FIRESTORE_EMULATOR_HOST"}, func(c *Config) *string { return &c.EmulatorHost }},
+	{[]string{"LOG_NAME"}, func(c *Config) *string { return &c.LogName }},
+}
+
+// Load reads the JSON file named by CONFIG_FILE, if set, applies the
+// environment overrides and validates the result.
+func Load() (*Config, error) {
+	var cfg Config
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if cfg, err = Parse(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	cfg.applyEnv(os.LookupEnv)
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Parse decodes a config file. Unknown fields are rejected so that typos do
+// not silently fall back to the defaults.
+func Parse(data []byte) (Config, error) {
+	var cfg Config
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// applyEnv replaces fields with the non-empty environment variables that
+// override them.
+func (c *Config) applyEnv(lookupEnv func(string) (string, bool)) {
+	for _, override := range overrides {
+		for _, name := range override.names {
+			if value, ok := lookupEnv(name); ok && value != "" {
+				*override.field(c) = value
+				break
+			}
+		}
+	}
+}
+
+// Validate checks the config and fills in its defaults.
+func (c *Config) Validate() error {
+	if c.LogName == "" {
+		c.LogName = defaultLogName
+	}
+	if c.EmulatorHost != "" {
+		if _, _, err := net.SplitHostPort(c.EmulatorHost); err != nil {
+			return fmt.Errorf("emulator host %q must be host:port", c.EmulatorHost)
+		}
+		if c.ProjectID == "" {
+			return errors.New("a project ID is required with the Firestore emulator")
+		}
+		// The emulator does not check credentials
+		return nil
+	}
+	if c.CredentialsFile != "" {
+		if _, err := os.Stat(c.CredentialsFile); err != nil {
+			return fmt.Errorf("credentials file: %w", err)
+		}
+	}
+	return nil
+}
+
+// FirestoreProject returns the project ID to pass to firestore.NewClient.
+func (c *Config) FirestoreProject() string {
+	if c.ProjectID == "" {
+		return firestore.DetectProjectID
+	}
+	return c.ProjectID
+}
+
+// ClientOptions returns the options for Google Cloud clients: the emulator
+// endpoint, the credentials file, or nothing for Application Default
+// Credentials.
+func (c *Config) ClientOptions() []option.ClientOption {
+	if c.EmulatorHost != "" {
+		return []option.ClientOption{
+			option.WithEndpoint(c.EmulatorHost),
+			option.WithoutAuthentication(),
+			option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+			option.WithGRPCDialOption(grpc.WithPerRPCCredentials(emulatorCredentials{})),
+		}
+	}
+	if c.CredentialsFile != "" {
+		return []option.ClientOption{option.WithCredentialsFile(c.CredentialsFile)}
+	}
+	return nil
+}
+
+// NewFirestoreClient opens a Firestore client for the configured project.
+func (c *Config) NewFirestoreClient(ctx context.Context) (*firestore.Client, error) {
+	return firestore.NewClient(ctx, c.FirestoreProject(), c.ClientOptions()...)
+}
+
+// emulatorCredentials authenticate to the Firestore emulator as its owner,
+// which bypasses security rules, as the Firestore client does when
+// FIRESTORE_EMULATOR_HOST is set.
+type emulatorCredentials struct{}
+
+func (emulatorCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer owner"}, nil
+}
+
+func (emulatorCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+var (
+	current    *Config
+	currentErr error
+	loadOnce   sync.Once
+)
+
+// Current returns the process configuration, loading it on first use.
+func Current() (*Config, error) {
+	loadOnce.Do(func() {
+		current, currentErr = Load()
+	})
+	return current, currentErr
+}
+
+// MustLoadInFunction loads the process configuration and exits if it is
+// invalid, so a misconfigured function fails at once rather than on its first
+// request. Functions call it from init. It does nothing outside a function
+// instance, where the functions framework has not set FUNCTION_TARGET: the
+// gateway links every function package, and loads the configuration only
+// when the handlers use it.
+func MustLoadInFunction() {
+	if os.Getenv("FUNCTION_TARGET") == "" {
+		return
+	}
+	if _, err := Current(); err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+)
+
+func lookup(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func TestParseRejectsUnknownFields(t *testing.T) {
+	if _, err := Parse([]byte(`{"projectID": "p", "emulator": "localhost:8081"}`)); err == nil {
+		t.Error("Parse accepted unknown fields")
+	}
+	cfg, err := Parse([]byte(`{"projectId": "p", "emulatorHost": "localhost:8081"}`))
+	if err != nil || cfg.ProjectID != "p" || cfg.EmulatorHost != "localhost:8081" {
+		t.Errorf("Parse = %+v, %v", cfg, err)
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	cfg := Config{ProjectID: "from-file", LogName: "file-log"}
+	cfg.applyEnv(lookup(map[string]string{
+		"GCP_PROJECT":             "legacy",
+		"GOOGLE_CLOUD_PROJECT":    "from-env",
+		"FIRESTORE_EMULATOR_HOST": "localhost:8081",
+		"LOG_NAME":                "",
+	}))
+	want := Config{ProjectID: "from-env", EmulatorHost: "localhost:8081", LogName: "file-log"}
+	if cfg != want {
+		t.Errorf("config = %+v, want %+v", cfg, want)
+	}
+
+	cfg = Config{}
+	cfg.applyEnv(lookup(map[string]string{"GCP_PROJECT": "legacy"}))
+	if cfg.ProjectID != "legacy" {
+		t.Errorf("ProjectID = %q, want GCP_PROJECT when GOOGLE_CLOUD_PROJECT is unset", cfg.ProjectID)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key.json")
+	if err := os.WriteFile(keyFile, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{"defaults", Config{}, ""},
+		{"credentials file", Config{CredentialsFile: keyFile}, ""},
+		{"missing credentials file", Config{CredentialsFile: keyFile + ".missing"}, "credentials file"},
+		{"emulator", Config{ProjectID: "p", EmulatorHost: "localhost:8081"}, ""},
+		{"emulator ignores credentials", Config{ProjectID: "p", EmulatorHost: "localhost:8081", CredentialsFile: "missing"}, ""},
+		{"emulator without project", Config{EmulatorHost: "localhost:8081"}, "project ID"},
+		{"emulator without port", Config{ProjectID: "p", EmulatorHost: "localhost"}, "host:port"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Validate: err = %v, want one mentioning %q", err, tt.wantErr)
+			}
+			if err == nil && tt.cfg.LogName != defaultLogName {
+				t.Errorf("LogName = %q, want the default %q", tt.cfg.LogName, defaultLogName)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"projectId": "file-project", "emulatorHost": "localhost:8081"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "")
+	t.Setenv("GCP_PROJECT", "")
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:9090")
+	t.Setenv("LOG_NAME", "")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ProjectID != "file-project" || cfg.EmulatorHost != "localhost:9090" {
+		t.Errorf("config = %+v, want the file's project and the environment's emulator", cfg)
+	}
+	if len(cfg.ClientOptions()) == 0 {
+		t.Error("no client options for the emulator")
+	}
+
+	t.Setenv("CONFIG_FILE", path+".missing")
+	if _, err := Load(); err == nil {
+		t.Error("Load with a missing config file: err = nil")
+	}
+}
+
+func TestMustLoadInFunctionOutsideFunction(t *testing.T) {
+	t.Setenv("FUNCTION_TARGET", "")
+	t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.json"))
+
+	// The gateway links the function packages; their init must not exit
+	MustLoadInFunction()
+	if current != nil || currentErr != nil {
+		t.Error("MustLoadInFunction loaded the configuration outside a function")
+	}
+}
+
+func TestFirestoreProject(t *testing.T) {
+	if got := (&Config{}).FirestoreProject(); got != firestore.DetectProjectID {
+		t.Errorf("FirestoreProject without a project = %q, want DetectProjectID", got)
+	}
+	if got := (&Config{ProjectID: "p"}).FirestoreProject(); got != "p" {
+		t.Errorf("FirestoreProject = %q, want p", got)
+	}
+}
@@ -0,0 +1,158 @@
+// Package domain defines the error kinds shared by the repository, the
+// access policy and the handlers, and the HTTP status each maps to.
+package domain
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Kind classifies an error by what the caller can do about it.
+type Kind string
+
+const (
+	// Internal errors are failures the caller cannot fix. Their details are
+	// not shown to clients.
+	Internal Kind = "internal"
+	// NotFound means the requested resource does not exist.
+	NotFound Kind = "not_found"
+	// Conflict means the request clashes with the resource's current state.
+	Conflict Kind = "conflict"
+	// Validation means the request itself is invalid.
+	Validation Kind = "validation"
+	// Unavailable means a backend is temporarily unreachable; the request
+	// can be retried.
+	Unavailable Kind = "unavailable"
+	// Unauthenticated means the request carries no valid caller identity.
+	Unauthenticated Kind = "unauthenticated"
+	// PermissionDenied means the caller may not perform the request.
+	PermissionDenied Kind = "permission_denied"
+	// PreconditionFailed means the resource changed since the version the
+	// caller based its request on.
+	PreconditionFailed Kind = "precondition_failed"
+)
+
+// HTTPStatus returns the response status for errors of kind k.
+func (k Kind) HTTPStatus() int {
+	switch k {
+	case NotFound:
+		return http.StatusNotFound
+	case Conflict:
+		return http.StatusConflict
+	case Validation:
+		return http.StatusBadRequest
+	case Unavailable:
+		return http.StatusServiceUnavailable
+	case Unauthenticated:
+		return http.StatusUnauthorized
+	case PermissionDenied:
+		return http.StatusForbidden
+	case PreconditionFailed:
+		return http.StatusPreconditionFailed
+	}
+	return http.StatusInternalServerError
+}
+
+// KindForStatus returns the kind of error an HTTP status reports.
+func KindForStatus(status int) Kind {
+	switch status {
+	case http.StatusNotFound:
+		return NotFound
+	case http.StatusConflict:
+		return Conflict
+	case http.StatusPreconditionFailed:
+		return PreconditionFailed
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return Validation
+	case http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return Unavailable
+	case http.StatusUnauthorized:
+		return Unauthenticated
+	case http.StatusForbidden:
+		return PermissionDenied
+	}
+	return Internal
+}
+
+// Error is an error of a known kind.
+type Error struct {
+	Kind    Kind
+	Message string
+
+	// Fields lists the invalid fields of a Validation error.
+	Fields []FieldError
+
+	// Err is the underlying error, if any.
+	Err error
+}
+
+// FieldError describes one invalid field of a request body.
+type FieldError struct {
+	// Field is the JSON name of the field.
+	Field string `json:"field"`
+	// Code is the check that failed, such as required or email.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// New returns an error of the given kind. Sentinel errors such as
+// repository.ErrNotFound are created with New, so errors.Is keeps working.
+func New(kind Kind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// Wrap returns err classified as kind, keeping its message.
+func Wrap(kind Kind, err error) *Error {
+	return &Error{Kind: kind, Message: err.Error(), Err: err}
+}
+
+// Invalid returns a Validation error for the given invalid fields.
+func Invalid(message string, fields []FieldError) *Error {
+	return &Error{Kind: Validation, Message: message, Fields: fields}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// KindOf classifies err. Errors created by this package report their own
+// kind; Firestore and gRPC errors are classified by their status code, and
+// iterator.Done, returned by a query without results, counts as NotFound.
+// Backend FailedPrecondition, Unauthenticated and PermissionDenied errors,
+// such as a missing index or rejected service account credentials, are
+// Internal: the client can do nothing about them. Everything else,
+// including nil, is Internal too.
+func KindOf(err error) Kind {
+	var e *Error
+	switch {
+	case err == nil:
+		return Internal
+	case errors.As(err, &e):
+		return e.Kind
+	case errors.Is(err, iterator.Done):
+		return NotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		return Unavailable
+	}
+
+	switch status.Code(err) {
+	case codes.NotFound:
+		return NotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return Conflict
+	case codes.InvalidArgument, codes.OutOfRange:
+		return Validation
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return Unavailable
+	}
+	return Internal
+}
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestKindOf(t *testing.T) {
+	errMissing := New(NotFound, "employee not found")
+
+	tests := []struct {
+		name string
+		err  error
+		want Kind
+	}{
+		{"sentinel", errMissing, NotFound},
+		{"wrapped sentinel", fmt.Errorf("get 7: %w", errMissing), NotFound},
+		{"wrapped plain error", Wrap(Validation, errors.New("email is required")), Validation},
+		{"iterator.Done", iterator.Done, NotFound},
+		{"deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), Unavailable},
+		{"grpc not found", status.Error(codes.NotFound, "no document"), NotFound},
+		{"grpc already exists", status.Error(codes.AlreadyExists, "exists"), Conflict},
+		{"grpc aborted", status.Error(codes.Aborted, "contention"), Conflict},
+		{"grpc invalid argument", status.Error(codes.InvalidArgument, "bad"), Validation},
+		{"grpc unavailable", status.Error(codes.Unavailable, "down"), Unavailable},
+		{"grpc failed precondition", status.Error(codes.FailedPrecondition, "the query requires an index"), Internal},
+		{"grpc unauthenticated", status.Error(codes.Unauthenticated, "bad credentials"), Internal},
+		{"grpc permission denied", status.Error(codes.PermissionDenied, "no"), Internal},
+		{"permission denied sentinel", New(PermissionDenied, "forbidden"), PermissionDenied},
+		{"grpc internal", status.Error(codes.Internal, "boom"), Internal},
+		{"plain error", errors.New("boom"), Internal},
+		{"nil", nil, Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KindOf(tt.err); got != tt.want {
+				t.Errorf("KindOf(%v) = %s, want %s", tt.err, got, tt.want)
+			}
+		})
+	}
+
+	if !errors.Is(fmt.Errorf("get 7: %w", errMissing), errMissing) {
+		t.Error("errors.Is does not match a wrapped sentinel")
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	for kind, want := range map[Kind]int{
+		NotFound:           http.StatusNotFound,
+		Conflict:           http.StatusConflict,
+		Validation:         http.StatusBadRequest,
+		Unavailable:        http.StatusServiceUnavailable,
+		Unauthenticated:    http.StatusUnauthorized,
+		PermissionDenied:   http.StatusForbidden,
+		PreconditionFailed: http.StatusPreconditionFailed,
+		Internal:           http.StatusInternalServerError,
+		Kind("unknown"):    http.StatusInternalServerError,
+	} {
+		if got := kind.HTTPStatus(); got != want {
+			t.Errorf("%s.HTTPStatus() = %d, want %d", kind, got, want)
+		}
+	}
+}
+
+func TestKindForStatus(t *testing.T) {
+	for _, kind := range []Kind{NotFound, Conflict, Validation, Unavailable, Unauthenticated, PermissionDenied, PreconditionFailed, Internal} {
+		if got := KindForStatus(kind.HTTPStatus()); got != kind {
+			t.Errorf("KindForStatus(%d) = %s, want %s", kind.HTTPStatus(), got, kind)
+		}
+	}
+	if got := KindForStatus(http.StatusBadGateway); got != Unavailable {
+		t.Errorf("KindForStatus(502) = %s, want %s", got, Unavailable)
+	}
+}
@@ -0,0 +1,365 @@
+// Package importer loads employees from CSV files, such as exports of the
+// legacy HR system, into an employee repository. It is used by the import
+// endpoint and by emsctl import.
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"example.com/task3gcp/shared/domain"
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/repository"
+)
+
+// DefaultBatchSize is the number of employees written at once when
+// Options.BatchSize is zero.
+const DefaultBatchSize = 500
+
+// ErrInvalidFile is returned for files that cannot be imported at all, such
+// as malformed CSV or a header without a mapped column.
+var ErrInvalidFile = domain.New(domain.Validation, "invalid import file")
+
+// Fields are the JSON names of the employee fields read from a file. Every
+// one needs a column, except the optional ones; the others, such as the ID,
+// are set by the repository.
+var Fields = []string{"firstName", "lastName", "email", "password", "role", "departmentId"}
+
+// optional lists the fields that are left empty when the file has no column
+// for them and the mapping names none.
+var optional = map[string]bool{"departmentId": true}
+
+// Mapping names the CSV column that holds each employee field, keyed by the
+// field's JSON name. Fields without an entry are read from the column named
+// like the field. Column names are matched ignoring case.
+type Mapping map[string]string
+
+// ParseMapping parses a mapping written as field=Column pairs separated by
+// commas, e.g. "firstName=First Name,email=Mail".
+func ParseMapping(s string) (Mapping, error) {
+	mapping := Mapping{}
+	if strings.TrimSpace(s) == "" {
+		return mapping, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		field, column, ok := strings.Cut(pair, "=")
+		field, column = strings.TrimSpace(field), strings.TrimSpace(column)
+		if !ok || field == "" || column == "" {
+			return nil, fmt.Errorf("%w: mapping %q is not field=Column", ErrInvalidFile, pair)
+		}
+		mapping[field] = column
+	}
+	return mapping, mapping.validate()
+}
+
+func (m Mapping) validate() error {
+	for field := range m {
+		if !isField(field) {
+			return fmt.Errorf("%w: cannot map unknown field %q", ErrInvalidFile, field)
+		}
+	}
+	return nil
+}
+
+// column returns the name of the column that holds field.
+func (m Mapping) column(field string) string {
+	if column, ok := m[field]; ok {
+		return column
+	}
+	return field
+}
+
+func isField(name string) bool {
+	for _, field := range Fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Options controls an import.
+type Options struct {
+	Mapping Mapping
+
+	// DryRun validates every row without writing anything. Emails are only
+	// checked against the other rows of the file, not against stored
+	// employees, and departments are not checked.
+	DryRun bool
+
+	// BatchSize is the number of employees passed to CreateBatch at once;
+	// zero means DefaultBatchSize.
+	BatchSize int
+
+	// Check, if set, rejects the rows it returns an error for, such as
+	// employees with a role the caller may not grant.
+	Check func(models.Employee) error
+}
+
+// RowError describes a row that was not imported.
+type RowError struct {
+	// Line is the line of the file the row starts on; the header is line 1.
+	Line    int                 `json:"line"`
+	Message string              `json:"message"`
+	Errors  []domain.FieldError `json:"errors,omitempty"`
+
+	// Record holds the row as read, for the error report.
+	Record []string `json:"-"`
+}
+
+// Result summarizes an import.
+type Result struct {
+	// Rows is the number of rows read, not counting the header.
+	Rows int `json:"rows"`
+	// Imported is the number of employees stored, or that would have been
+	// stored by a dry run.
+	Imported int        `json:"imported"`
+	Rejected []RowError `json:"rejected"`
+	DryRun   bool       `json:"dryRun"`
+
+	header   []string
+	password int
+}
+
+// Import reads employees from the CSV file in r and creates them in repo,
+// BatchSize at a time, so files of any size can be imported. Every row is
+// validated like a created employee; rows that fail validation, repeat an
+// email of an earlier row, name a department that does not exist or cannot
+// be stored are collected in
+// Result.Rejected and the others are imported. The error is for failures
+// that stop the import, after which only the batches written so far are
+// stored.
+func Import(ctx context.Context, repo repository.EmployeeRepository, r io.Reader, opts Options) (*Result, error) {
+	if err := opts.Mapping.validate(); err != nil {
+		return nil, err
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	columns, err := findColumns(header, opts.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Rejected: []RowError{}, DryRun: opts.DryRun, header: header, password: columns["password"]}
+	seen := make(map[string]bool)
+	var batch []models.Employee
+	var rows []RowError
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		defer func() { batch, rows = batch[:0], rows[:0] }()
+		if opts.DryRun {
+			result.Imported += len(batch)
+			return nil
+		}
+		results, err := repo.CreateBatch(ctx, batch)
+		if err != nil {
+			return err
+		}
+		for i, created := range results {
+			if created.Err != nil {
+				result.reject(rows[i], created.Err)
+				continue
+			}
+			result.Imported++
+		}
+		return nil
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		line, _ := reader.FieldPos(0)
+		result.Rows++
+
+		employee, unparsed := employeeFrom(record, columns)
+		row := RowError{Line: line, Record: record}
+		if err := withFields(employee.Validate(), unparsed); err != nil {
+			result.reject(row, err)
+			continue
+		}
+		if opts.Check != nil {
+			if err := opts.Check(employee); err != nil {
+				result.reject(row, err)
+				continue
+			}
+		}
+		email := models.NormalizeEmail(employee.Email)
+		if seen[email] {
+			result.reject(row, repository.ErrEmailTaken)
+			continue
+		}
+		seen[email] = true
+
+		batch = append(batch, employee)
+		rows = append(rows, row)
+		if len(batch) == opts.BatchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return result, err
+	}
+	sort.Slice(result.Rejected, func(i, j int) bool { return result.Rejected[i].Line < result.Rejected[j].Line })
+	return result, nil
+}
+
+// findColumns returns the index of the column of every field.
+func findColumns(header []string, mapping Mapping) (map[string]int, error) {
+	columns := make(map[string]int, len(Fields))
+	for _, field := range Fields {
+		name := mapping.column(field)
+		for i, column := range header {
+			if strings.EqualFold(strings.TrimSpace(column), name) {
+				columns[field] = i
+				break
+			}
+		}
+		_, mapped := mapping[field]
+		if _, ok := columns[field]; !ok && (!optional[field] || mapped) {
+			return nil, fmt.Errorf("%w: no column %q for %s", ErrInvalidFile, name, field)
+		}
+	}
+	return columns, nil
+}
+
+// employeeFrom reads an employee from record and returns the fields whose
+// cells are not numbers where one is needed. Cells missing from a short row
+// or an optional column count as empty. Surrounding spaces are trimmed,
+// except from passwords.
+func employeeFrom(record []string, columns map[string]int) (models.Employee, []domain.FieldError) {
+	cell := func(field string) string {
+		i, ok := columns[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		if field == "password" {
+			return record[i]
+		}
+		return strings.TrimSpace(record[i])
+	}
+	employee := models.Employee{
+		FirstName: cell("firstName"),
+		LastName:  cell("lastName"),
+		Email:     cell("email"),
+		Password:  cell("password"),
+		Role:      cell("role"),
+	}
+
+	var unparsed []domain.FieldError
+	if department := cell("departmentId"); department != "" {
+		id, err := strconv.Atoi(department)
+		if err != nil {
+			unparsed = append(unparsed, domain.FieldError{Field: "departmentId", Code: "number", Message: "departmentId must be a number"})
+		}
+		employee.DepartmentID = id
+	}
+	return employee, unparsed
+}
+
+// withFields adds the invalid fields in unparsed to err, the result of
+// validating the employee they belong to.
+func withFields(err error, unparsed []domain.FieldError) error {
+	if len(unparsed) == 0 {
+		return err
+	}
+	var fields []domain.FieldError
+	var invalid *domain.Error
+	if errors.As(err, &invalid) {
+		fields = append(fields, invalid.Fields...)
+	} else if err != nil {
+		return err
+	}
+	return domain.Invalid("employee is invalid", append(fields, unparsed...))
+}
+
+// reject records that row was not imported because of err. Like the
+// problem details of a request, the message only repeats the text of
+// domain errors that wrap no other error; the rejected rows are returned
+// to the caller and the report may be shared.
+func (r *Result) reject(row RowError, err error) {
+	row.Message = "employee could not be stored"
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		kind := domain.KindOf(err)
+		if domainErr.Err == nil && kind != domain.Internal && kind != domain.Unavailable {
+			row.Message = err.Error()
+		}
+		row.Errors = domainErr.Fields
+	}
+	if row.Message != err.Error() {
+		log.Printf("Import line %d not stored: %v", row.Line, err)
+	}
+	if errors.Is(err, repository.ErrEmailTaken) {
+		row.Errors = []domain.FieldError{{Field: "email", Code: "unique", Message: err.Error()}}
+	}
+	r.Rejected = append(r.Rejected, row)
+}
+
+// WriteReport writes the rejected rows as CSV: the header and rows of the
+// imported file with two columns added, the line each row started on and
+// why it was rejected. Passwords are left out, so the report can be shared;
+// fill them in again before reimporting the fixed rows.
+func (r *Result) WriteReport(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(append(append([]string{}, r.header...), "line", "error")); err != nil {
+		return err
+	}
+	for _, row := range r.Rejected {
+		record := make([]string, len(r.header))
+		copy(record, row.Record)
+		if r.password < len(record) {
+			record[r.password] = ""
+		}
+		if err := writer.Write(append(record, fmt.Sprint(row.Line), row.reason())); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.reason())
+}
+
+// reason describes why the row was rejected, listing every invalid field.
+func (e RowError) reason() string {
+	if len(e.Errors) == 0 {
+		return e.Message
+	}
+	messages := make([]string, len(e.Errors))
+	for i, field := range e.Errors {
+		messages[i] = field.Message
+	}
+	return strings.Join(messages, "; ")
+}
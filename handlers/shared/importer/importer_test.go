@@ -0,0 +1,235 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/policy"
+	"example.com/task3gcp/shared/repository"
+)
+
+const legacyFile = "\ufeffGiven Name,Surname,Mail,Password,Role,Office\n" +
+	"Alan,Turing,alan@example.com,secret1,employee,Manchester\n" +
+	"Grace,,grace@example.com,secret1,employee,Arlington\n" +
+	"Barbara,Liskov,ada@example.com,secret1,manager,Cambridge\n" +
+	"Edsger,Dijkstra,not-an-email,short,employee,Austin\n" +
+	"Alan,Kay,ALAN@example.com,secret1,employee,Glendale\n" +
+	"\"Donald\nErvin\",Knuth,don@example.com,secret1,employee,Stanford\n"
+
+var legacyMapping = Mapping{"firstName": "given name", "lastName": "Surname", "email": "Mail"}
+
+func TestImport(t *testing.T) {
+	repo := repository.NewMemoryRepository(models.Employee{ID: 1, Email: "ada@example.com"})
+	ctx := context.Background()
+
+	result, err := Import(ctx, repo, strings.NewReader(legacyFile), Options{Mapping: legacyMapping, BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Rows != 6 || result.Imported != 2 {
+		t.Errorf("rows = %d, imported = %d, want 6 rows and 2 imported", result.Rows, result.Imported)
+	}
+
+	var lines []int
+	for _, row := range result.Rejected {
+		lines = append(lines, row.Line)
+	}
+	if len(lines) != 4 || lines[0] != 3 || lines[1] != 4 || lines[2] != 5 || lines[3] != 6 {
+		t.Fatalf("rejected lines = %v, want 3, 4, 5 and 6", lines)
+	}
+	if fields := result.Rejected[2].Errors; len(fields) != 2 || fields[0].Field != "email" || fields[1].Field != "password" {
+		t.Errorf("errors of line 5 = %+v, want email and password", fields)
+	}
+	for _, i := range []int{1, 3} {
+		if fields := result.Rejected[i].Errors; len(fields) != 1 || fields[0].Code != "unique" {
+			t.Errorf("errors of line %d = %+v, want a taken email", result.Rejected[i].Line, fields)
+		}
+	}
+
+	page, err := repo.List(ctx, repository.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Employees) != 3 {
+		t.Fatalf("stored %d employees, want the 2 imported ones added", len(page.Employees))
+	}
+	knuth := page.Employees[2]
+	if knuth.FirstName != "Donald\nErvin" || knuth.Role != "employee" || !knuth.CheckPassword("secret1") {
+		t.Errorf("imported %+v, want Donald Ervin Knuth with a hashed password", knuth)
+	}
+}
+
+func TestImportDepartments(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	ctx := context.Background()
+	engineering, err := repo.CreateDepartment(ctx, models.Department{Name: "Engineering", Code: "ENG"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := "firstName,lastName,email,password,role,Dept\n" +
+		"Alan,Turing,alan@example.com,secret1,employee," + strconv.Itoa(engineering.ID) + "\n" +
+		"Grace,Hopper,grace@example.com,secret1,employee,99\n" +
+		"Barbara,Liskov,barbara@example.com,secret1,employee,Sales\n" +
+		"Edsger,Dijkstra,edsger@example.com,secret1,employee,\n"
+
+	result, err := Import(ctx, repo, strings.NewReader(file), Options{Mapping: Mapping{"departmentId": "dept"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 2 || len(result.Rejected) != 2 {
+		t.Fatalf("result = %+v, want lines 3 and 4 rejected", result)
+	}
+	for i, code := range []string{"exists", "number"} {
+		if fields := result.Rejected[i].Errors; len(fields) != 1 || fields[0].Field != "departmentId" || fields[0].Code != code {
+			t.Errorf("errors of line %d = %+v, want departmentId %s", result.Rejected[i].Line, fields, code)
+		}
+	}
+
+	page, err := repo.List(ctx, repository.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Employees) != 2 || page.Employees[0].DepartmentID != engineering.ID || page.Employees[1].DepartmentID != 0 {
+		t.Errorf("stored %+v, want Turing in engineering and Dijkstra without a department", page.Employees)
+	}
+}
+
+func TestImportCheck(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	file := "firstName,lastName,email,password,role\n" +
+		"Alan,Turing,alan@example.com,secret1,employee\n" +
+		"Grace,Hopper,grace@example.com,secret1,admin\n"
+	noAdmins := func(employee models.Employee) error {
+		if employee.Role == "admin" {
+			return policy.ErrForbidden
+		}
+		return nil
+	}
+
+	result, err := Import(context.Background(), repo, strings.NewReader(file), Options{Check: noAdmins})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 1 || len(result.Rejected) != 1 || result.Rejected[0].Line != 3 {
+		t.Errorf("result = %+v, want line 3 rejected and the other row imported", result)
+	}
+}
+
+// failingRepository fails every write of a batch with a backend error.
+type failingRepository struct {
+	*repository.MemoryRepository
+}
+
+func (failingRepository) CreateBatch(_ context.Context, employees []models.Employee) ([]repository.BatchResult, error) {
+	results := make([]repository.BatchResult, len(employees))
+	for i := range results {
+		results[i].Err = errors.New("rpc error: code = Internal desc = projects/p/databases/(default) is busy")
+	}
+	return results, nil
+}
+
+func TestImportHidesBackendErrors(t *testing.T) {
+	repo := failingRepository{repository.NewMemoryRepository()}
+	file := "firstName,lastName,email,password,role\n" +
+		"Alan,Turing,alan@example.com,secret1,employee\n"
+
+	result, err := Import(context.Background(), repo, strings.NewReader(file), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Rejected) != 1 || result.Rejected[0].Message != "employee could not be stored" {
+		t.Fatalf("rejected = %+v, want the row with a generic message", result.Rejected)
+	}
+
+	var report bytes.Buffer
+	if err := result.WriteReport(&report); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(report.String(), "projects/p") {
+		t.Errorf("report repeats the backend error:\n%s", report.String())
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+
+	result, err := Import(context.Background(), repo, strings.NewReader(legacyFile), Options{Mapping: legacyMapping, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without stored employees only the file's own duplicate is a conflict
+	if !result.DryRun || result.Imported != 3 || len(result.Rejected) != 3 {
+		t.Errorf("dry run = %+v, want 3 importable and 3 rejected rows", result)
+	}
+	if page, _ := repo.List(context.Background(), repository.ListOptions{}); len(page.Employees) != 0 {
+		t.Errorf("dry run stored %d employees", len(page.Employees))
+	}
+}
+
+func TestImportInvalidFile(t *testing.T) {
+	for name, file := range map[string]string{
+		"empty":          "",
+		"missing column": "firstName,lastName,email,password\n",
+		"mapped column":  "firstName,lastName,email,password,role\n",
+		"bad quotes":     "firstName,lastName,email,password,role\n\"Ada,Lovelace\n",
+	} {
+		// departmentId may have no column unless the mapping names one
+		opts := Options{Mapping: Mapping{"departmentId": "Department"}}
+		_, err := Import(context.Background(), repository.NewMemoryRepository(), strings.NewReader(file), opts)
+		if !errors.Is(err, ErrInvalidFile) {
+			t.Errorf("%s: err = %v, want ErrInvalidFile", name, err)
+		}
+	}
+}
+
+func TestParseMapping(t *testing.T) {
+	mapping, err := ParseMapping(" firstName = Given Name ,email=Mail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mapping) != 2 || mapping["firstName"] != "Given Name" || mapping["email"] != "Mail" {
+		t.Errorf("mapping = %v", mapping)
+	}
+
+	for _, s := range []string{"firstName", "=Mail", "id=ID", "password=Secret,salary=Pay"} {
+		if _, err := ParseMapping(s); !errors.Is(err, ErrInvalidFile) {
+			t.Errorf("ParseMapping(%q): err = %v, want ErrInvalidFile", s, err)
+		}
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	result, err := Import(context.Background(), repository.NewMemoryRepository(), strings.NewReader(legacyFile), Options{Mapping: legacyMapping, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := result.WriteReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"Given Name", "Surname", "Mail", "Password", "Role", "Office", "line", "error"},
+		{"Grace", "", "grace@example.com", "", "employee", "Arlington", "3", "lastName is required"},
+		{"Edsger", "Dijkstra", "not-an-email", "", "employee", "Austin", "5", "email must be a valid email address; password must be at least 6 characters"},
+		{"Alan", "Kay", "ALAN@example.com", "", "employee", "Glendale", "6", "email is already in use"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("report = %q, want %q", records, want)
+	}
+	for i := range want {
+		if strings.Join(records[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("report line %d = %q, want %q", i+1, records[i], want[i])
+		}
+	}
+}
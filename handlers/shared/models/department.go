@@ -0,0 +1,31 @@
+package models
+
+import "strings"
+
+// Department is a unit of the organisation that employees belong to.
+type Department struct {
+	ID   int    `json:"id"`
+	Name string `json:"name" validate:"required"`
+
+	// Code is the department's short unique name, such as ENG. Codes are
+	// compared ignoring case (see NormalizeCode).
+	Code       string `json:"code" validate:"required,max=20"`
+	CostCenter string `json:"costCenter"`
+
+	// HeadEmployeeID is the ID of the employee who heads the department, or
+	// zero for none.
+	HeadEmployeeID int `json:"headEmployeeId,omitempty" validate:"min=0"`
+}
+
+// Validate checks the department against its validate tags, like
+// Employee.Validate.
+func (d Department) Validate() error {
+	return validateStruct(d, "department is invalid")
+}
+
+// NormalizeCode returns the form of a department code used to compare
+// codes: two departments may not share a code that differs only in case or
+// surrounding spaces.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Employee struct with validations using the validator package
+type Employee struct {
+	ID        int        `json:"id"`
+	FirstName string     `json:"firstName" validate:"required"`
+	LastName  string     `json:"lastName" validate:"required"`
+	Email     string     `json:"email" validate:"required,email"`
+	Password  string     `json:"password" validate:"required,min=6,max=72"`
+	Role      string     `json:"role" validate:"required"`
+	Deleted   bool       `json:"deleted"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+
+	// DepartmentID is the ID of the employee's department, or zero for none.
+	// The repository only stores IDs of existing departments.
+	DepartmentID int `json:"departmentId,omitempty" validate:"min=0"`
+
+	// Version counts the writes to the employee; the repository increments
+	// it on every change. It is the employee's ETag.
+	Version int `json:"version"`
+
+	// Lowercase copies of the searchable fields, kept in sync by
+	// SetSearchFields so Firestore can answer case-insensitive prefix queries.
+	FirstNameLower string `json:"-"`
+	LastNameLower  string `json:"-"`
+	EmailLower     string `json:"-"`
+	RoleLower      string `json:"-"`
+}
+
+// SetSearchFields refreshes the lowercase search fields from the current
+// values. It must be called before the employee is written.
+func (e *Employee) SetSearchFields() {
+	e.FirstNameLower = strings.ToLower(e.FirstName)
+	e.LastNameLower = strings.ToLower(e.LastName)
+	e.EmailLower = strings.ToLower(e.Email)
+	e.RoleLower = strings.ToLower(e.Role)
+}
+
+// NormalizeEmail returns the form of email used to compare addresses: two
+// employees may not share an email that differs only in case or
+// surrounding spaces.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ChangedFields returns the JSON names of the fields whose values differ
+// between before and after, in declaration order.
+func ChangedFields(before, after Employee) []string {
+	var fields []string
+	add := func(name string, changed bool) {
+		if changed {
+			fields = append(fields, name)
+		}
+	}
+	add("id", before.ID != after.ID)
+	add("firstName", before.FirstName != after.FirstName)
+	add("lastName", before.LastName != after.LastName)
+	add("email", before.Email != after.Email)
+	add("password", before.Password != after.Password)
+	add("role", before.Role != after.Role)
+	add("departmentId", before.DepartmentID != after.DepartmentID)
+	add("deleted", before.Deleted != after.Deleted)
+	add("deletedAt", !sameTime(before.DeletedAt, after.DeletedAt))
+	add("version", before.Version != after.Version)
+	return fields
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
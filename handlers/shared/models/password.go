@@ -0,0 +1,30 @@
+package models
+
+import "golang.org/x/crypto/bcrypt"
+
+// IsPasswordHash reports whether password is already a bcrypt hash rather
+// than a plaintext password.
+func IsPasswordHash(password string) bool {
+	_, err := bcrypt.Cost([]byte(password))
+	return err == nil
+}
+
+// HashPassword replaces a plaintext password with its bcrypt hash. Empty
+// passwords and passwords that are already hashed are left unchanged, so it
+// is safe to call on every write.
+func (e *Employee) HashPassword() error {
+	if e.Password == "" || IsPasswordHash(e.Password) {
+		return nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(e.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	e.Password = string(hash)
+	return nil
+}
+
+// CheckPassword reports whether password matches the stored hash.
+func (e Employee) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(e.Password), []byte(password)) == nil
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// EmployeeResponse is the representation of an employee returned by the API.
+// It deliberately has no password field.
+type EmployeeResponse struct {
+	ID           int        `json:"id"`
+	FirstName    string     `json:"firstName"`
+	LastName     string     `json:"lastName"`
+	Email        string     `json:"email"`
+	Role         string     `json:"role"`
+	DepartmentID int        `json:"departmentId,omitempty"`
+	Deleted      bool       `json:"deleted"`
+	DeletedAt    *time.Time `json:"deletedAt,omitempty"`
+	Version      int        `json:"version"`
+}
+
+// Response returns the API representation of e.
+func (e Employee) Response() EmployeeResponse {
+	return EmployeeResponse{
+		ID:           e.ID,
+		FirstName:    e.FirstName,
+		LastName:     e.LastName,
+		Email:        e.Email,
+		Role:         e.Role,
+		DepartmentID: e.DepartmentID,
+		Deleted:      e.Deleted,
+		DeletedAt:    e.DeletedAt,
+		Version:      e.Version,
+	}
+}
+
+// NewEmployeeResponses returns the API representation of every employee.
+func NewEmployeeResponses(employees []Employee) []EmployeeResponse {
+	responses := make([]EmployeeResponse, len(employees))
+	for i, employee := range employees {
+		responses[i] = employee.Response()
+	}
+	return responses
+}
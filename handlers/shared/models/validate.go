@@ -0,0 +1,70 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"example.com/task3gcp/shared/domain"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// validate reports invalid fields by their JSON names.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
+// Validate checks the employee against its validate tags. An invalid
+// employee yields a domain.Validation error that lists every invalid field.
+func (e Employee) Validate() error {
+	return validateStruct(e, "employee is invalid")
+}
+
+// validateStruct checks s against its validate tags and reports the invalid
+// fields in a domain.Validation error with the given message.
+func validateStruct(s interface{}, message string) error {
+	err := validate.Struct(s)
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return err
+	}
+	fields := make([]domain.FieldError, len(invalid))
+	for i, field := range invalid {
+		fields[i] = domain.FieldError{Field: field.Field(), Code: field.Tag(), Message: fieldMessage(field)}
+	}
+	return domain.Invalid(message, fields)
+}
+
+// fieldMessage describes a failed check without validator's Go-centric
+// wording.
+func fieldMessage(field validator.FieldError) string {
+	switch field.Tag() {
+	case "required":
+		return field.Field() + " is required"
+	case "email":
+		return field.Field() + " must be a valid email address"
+	case "min":
+		if field.Kind() != reflect.String {
+			return fmt.Sprintf("%s must be at least %s", field.Field(), field.Param())
+		}
+		return fmt.Sprintf("%s must be at least %s characters", field.Field(), field.Param())
+	case "max":
+		if field.Kind() != reflect.String {
+			return fmt.Sprintf("%s must be at most %s", field.Field(), field.Param())
+		}
+		return fmt.Sprintf("%s must be at most %s characters", field.Field(), field.Param())
+	}
+	return fmt.Sprintf("%s failed the %s check", field.Field(), field.Tag())
+}
@@ -0,0 +1,217 @@
+// Package policy decides which employee operations a caller may perform,
+// based on the caller's role. The rules are declared in a JSON file; the
+// policy.json next to this file is embedded as the default.
+package policy
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/domain"
+	"example.com/task3gcp/shared/models"
+)
+
+// Operation names an action on employees or departments. The gateway and
+// the handlers use the same names, so one policy file governs both.
+type Operation string
+
+const (
+	List     Operation = "employees.list"
+	Get      Operation = "employees.get"
+	Search   Operation = "employees.search"
+	Create   Operation = "employees.create"
+	Update   Operation = "employees.update"
+	Delete   Operation = "employees.delete"
+	Restore  Operation = "employees.restore"
+	Purge    Operation = "employees.purge"
+	Import   Operation = "employees.import"
+	History  Operation = "employees.history"
+	Rollback Operation = "employees.rollback"
+
+	ListDepartments  Operation = "departments.list"
+	GetDepartment    Operation = "departments.get"
+	CreateDepartment Operation = "departments.create"
+	UpdateDepartment Operation = "departments.update"
+	DeleteDepartment Operation = "departments.delete"
+)
+
+// Scope limits which records an operation applies to.
+type Scope string
+
+const (
+	// ScopeAll allows the operation on every employee.
+	ScopeAll Scope = "all"
+	// ScopeSelf allows the operation only on the caller's own record.
+	ScopeSelf Scope = "self"
+)
+
+var (
+	// ErrUnauthenticated is returned for requests without a caller identity.
+	ErrUnauthenticated = domain.New(domain.Unauthenticated, "authentication required")
+	// ErrForbidden is returned when the policy does not allow the request.
+	ErrForbidden = domain.New(domain.PermissionDenied, "forbidden")
+)
+
+// Rule grants one operation to a role.
+type Rule struct {
+	Scope Scope `json:"scope"`
+
+	// ReadOnlyFields lists the JSON names of fields an update may not change.
+	ReadOnlyFields []string `json:"readOnlyFields,omitempty"`
+
+	// AllowedRoles lists the roles that create and import may give new
+	// employees; empty allows any role.
+	AllowedRoles []string `json:"allowedRoles,omitempty"`
+}
+
+// Policy maps each role to the operations it may perform. Operations that
+// are not listed for a role are denied.
+type Policy struct {
+	Roles map[string]map[Operation]Rule `json:"roles"`
+}
+
+//go:embed policy.json
+var defaultPolicy []byte
+
+// Default returns the embedded default policy.
+func Default() *Policy {
+	p, err := Parse(defaultPolicy)
+	if err != nil {
+		panic("policy: invalid embedded policy: " + err.Error())
+	}
+	return p
+}
+
+// FromEnv loads the policy file named by POLICY_FILE, or returns the default
+// policy when it is not set.
+func FromEnv() (*Policy, error) {
+	path := os.Getenv("POLICY_FILE")
+	if path == "" {
+		return Default(), nil
+	}
+	return Load(path)
+}
+
+// Load reads a policy file.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Parse decodes and validates a policy.
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return &p, p.validate()
+}
+
+func (p *Policy) validate() error {
+	for role, rules := range p.Roles {
+		for op, rule := range rules {
+			switch op {
+			case List, Search, Create, Purge, Import, Rollback,
+				ListDepartments, GetDepartment, CreateDepartment, UpdateDepartment, DeleteDepartment:
+				if rule.Scope != ScopeAll {
+					return fmt.Errorf("role %s: %s only supports scope %q", role, op, ScopeAll)
+				}
+			case Get, Update, Delete, Restore, History:
+				if rule.Scope != ScopeAll && rule.Scope != ScopeSelf {
+					return fmt.Errorf("role %s: %s has unknown scope %q", role, op, rule.Scope)
+				}
+			default:
+				return fmt.Errorf("role %s: unknown operation %q", role, op)
+			}
+			for _, field := range rule.ReadOnlyFields {
+				if _, ok := fieldValue(models.Employee{}, field); !ok {
+					return fmt.Errorf("role %s: %s: unknown read-only field %q", role, op, field)
+				}
+			}
+			if len(rule.AllowedRoles) > 0 && op != Create && op != Import {
+				return fmt.Errorf("role %s: %s does not support allowedRoles", role, op)
+			}
+		}
+	}
+	return nil
+}
+
+// Authorize checks that identity may perform op. targetID is the employee the
+// operation applies to, or 0 for operations that are not about one record.
+func (p *Policy) Authorize(identity auth.Identity, op Operation, targetID int) (Rule, error) {
+	rule, ok := p.Roles[identity.Role][op]
+	if !ok {
+		return Rule{}, ErrForbidden
+	}
+	if rule.Scope == ScopeSelf && (targetID == 0 || targetID != identity.EmployeeID) {
+		return Rule{}, ErrForbidden
+	}
+	return rule, nil
+}
+
+// AuthorizeRequest authorizes the caller identified by the gateway headers of
+// r. It returns ErrUnauthenticated when r carries no identity.
+func (p *Policy) AuthorizeRequest(r *http.Request, op Operation, targetID int) (Rule, error) {
+	identity, ok := auth.IdentityFromHeaders(r.Header)
+	if !ok {
+		return Rule{}, ErrUnauthenticated
+	}
+	return p.Authorize(identity, op, targetID)
+}
+
+// CheckUpdate returns ErrForbidden if updated changes a field the rule marks
+// read-only.
+func (r Rule) CheckUpdate(existing, updated models.Employee) error {
+	for _, field := range r.ReadOnlyFields {
+		before, _ := fieldValue(existing, field)
+		after, _ := fieldValue(updated, field)
+		if before != after {
+			return fmt.Errorf("%w: %s cannot be changed", ErrForbidden, field)
+		}
+	}
+	return nil
+}
+
+// CheckCreate returns ErrForbidden if employee has a role the rule does not
+// allow new employees to have.
+func (r Rule) CheckCreate(employee models.Employee) error {
+	if len(r.AllowedRoles) == 0 {
+		return nil
+	}
+	for _, role := range r.AllowedRoles {
+		if employee.Role == role {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: role %s cannot be granted", ErrForbidden, employee.Role)
+}
+
+// fieldValue returns the value of the employee field with the given JSON
+// name.
+func fieldValue(employee models.Employee, field string) (string, bool) {
+	switch field {
+	case "firstName":
+		return employee.FirstName, true
+	case "lastName":
+		return employee.LastName, true
+	case "email":
+		return employee.Email, true
+	case "role":
+		return employee.Role, true
+	case "departmentId":
+		return strconv.Itoa(employee.DepartmentID), true
+	}
+	return "", false
+}
@@ -0,0 +1,159 @@
+package policy
+
+import (
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/models"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	p := Default()
+	admin := auth.Identity{EmployeeID: 1, Role: "admin"}
+	hr := auth.Identity{EmployeeID: 2, Role: "hr"}
+	manager := auth.Identity{EmployeeID: 3, Role: "manager"}
+	employee := auth.Identity{EmployeeID: 4, Role: "employee"}
+	unknown := auth.Identity{EmployeeID: 5, Role: "contractor"}
+
+	tests := []struct {
+		identity auth.Identity
+		op       Operation
+		target   int
+		allowed  bool
+	}{
+		{admin, Purge, 0, true},
+		{hr, Purge, 0, false},
+		{hr, Delete, 9, true},
+		{hr, Import, 0, true},
+		{manager, Import, 0, false},
+		{hr, History, 9, true},
+		{employee, History, 4, false},
+		{hr, Rollback, 9, true},
+		{manager, Rollback, 9, false},
+		{manager, List, 0, true},
+		{manager, Create, 0, false},
+		{manager, Update, 3, true},
+		{manager, Update, 4, false},
+		{employee, Get, 4, true},
+		{employee, Get, 3, false},
+		{employee, Update, 4, true},
+		{employee, Delete, 4, false},
+		{employee, List, 0, false},
+		{unknown, Get, 5, false},
+	}
+	for _, tt := range tests {
+		_, err := p.Authorize(tt.identity, tt.op, tt.target)
+		if allowed := err == nil; allowed != tt.allowed {
+			t.Errorf("%s %s on %d: allowed = %v, want %v", tt.identity.Role, tt.op, tt.target, allowed, tt.allowed)
+		}
+		if err != nil && !errors.Is(err, ErrForbidden) {
+			t.Errorf("%s %s: error = %v, want ErrForbidden", tt.identity.Role, tt.op, err)
+		}
+	}
+}
+
+func TestCheckUpdate(t *testing.T) {
+	rule, err := Default().Authorize(auth.Identity{EmployeeID: 4, Role: "employee"}, Update, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing := models.Employee{ID: 4, LastName: "Lovelace", Role: "employee"}
+
+	renamed := existing
+	renamed.LastName = "King"
+	if err := rule.CheckUpdate(existing, renamed); err != nil {
+		t.Errorf("changing lastName: %v", err)
+	}
+
+	promoted := existing
+	promoted.Role = "admin"
+	if err := rule.CheckUpdate(existing, promoted); !errors.Is(err, ErrForbidden) {
+		t.Errorf("changing role: error = %v, want ErrForbidden", err)
+	}
+
+	transferred := existing
+	transferred.DepartmentID = 3
+	if err := rule.CheckUpdate(existing, transferred); !errors.Is(err, ErrForbidden) {
+		t.Errorf("changing departmentId: error = %v, want ErrForbidden", err)
+	}
+}
+
+func TestCheckUpdateHR(t *testing.T) {
+	hr := auth.Identity{EmployeeID: 2, Role: "hr"}
+	for _, target := range []int{2, 4} {
+		rule, err := Default().Authorize(hr, Update, target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		existing := models.Employee{ID: target, LastName: "Lovelace", Role: "employee"}
+
+		transferred := existing
+		transferred.DepartmentID = 3
+		if err := rule.CheckUpdate(existing, transferred); err != nil {
+			t.Errorf("changing departmentId of %d: %v", target, err)
+		}
+
+		promoted := existing
+		promoted.Role = "admin"
+		if err := rule.CheckUpdate(existing, promoted); !errors.Is(err, ErrForbidden) {
+			t.Errorf("granting admin to %d: error = %v, want ErrForbidden", target, err)
+		}
+	}
+}
+
+func TestCheckCreate(t *testing.T) {
+	hr, err := Default().Authorize(auth.Identity{EmployeeID: 2, Role: "hr"}, Create, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hr.CheckCreate(models.Employee{Role: "manager"}); err != nil {
+		t.Errorf("hr creating a manager: %v", err)
+	}
+	if err := hr.CheckCreate(models.Employee{Role: "admin"}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("hr creating an admin: error = %v, want ErrForbidden", err)
+	}
+
+	admin, err := Default().Authorize(auth.Identity{EmployeeID: 1, Role: "admin"}, Import, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := admin.CheckCreate(models.Employee{Role: "admin"}); err != nil {
+		t.Errorf("admin importing an admin: %v", err)
+	}
+}
+
+func TestAuthorizeRequest(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	if _, err := Default().AuthorizeRequest(req, List, 0); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("without identity: error = %v, want ErrUnauthenticated", err)
+	}
+
+	req.Header.Set(auth.HeaderEmployeeID, "1")
+	req.Header.Set(auth.HeaderEmployeeRole, "admin")
+	if _, err := Default().AuthorizeRequest(req, List, 0); err != nil {
+		t.Errorf("admin: %v", err)
+	}
+}
+
+func TestLoadRejectsInvalidPolicies(t *testing.T) {
+	for name, data := range map[string]string{
+		"malformed":               `{"roles":`,
+		"unknown op":              `{"roles":{"admin":{"employees.fire":{"scope":"all"}}}}`,
+		"self on list":            `{"roles":{"admin":{"employees.list":{"scope":"self"}}}}`,
+		"unknown scope":           `{"roles":{"admin":{"employees.get":{"scope":"team"}}}}`,
+		"unknown field":           `{"roles":{"admin":{"employees.update":{"scope":"all","readOnlyFields":["salary"]}}}}`,
+		"allowed roles on update": `{"roles":{"admin":{"employees.update":{"scope":"all","allowedRoles":["employee"]}}}}`,
+	} {
+		path := filepath.Join(t.TempDir(), "policy.json")
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("%s: Load succeeded, want an error", name)
+		}
+	}
+}
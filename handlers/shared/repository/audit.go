@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/models"
+)
+
+// auditCollection is the subcollection of every employee document that holds
+// its audit log. Entries are only ever created, never changed or deleted.
+const auditCollection = "employee_audit"
+
+// Operations recorded in the audit log.
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+)
+
+// Redacted stands in for secret values, such as passwords, in audit entries.
+const Redacted = "[REDACTED]"
+
+// AuditEntry records one change to an employee.
+type AuditEntry struct {
+	EmployeeID int       `json:"employeeId"`
+	Operation  string    `json:"operation"`
+	Actor      Actor     `json:"actor"`
+	Timestamp  time.Time `json:"timestamp"`
+
+	// Version is the employee's version after the change.
+	Version int `json:"version"`
+
+	// Changes lists the fields the operation changed, in the order of
+	// models.ChangedFields.
+	Changes []FieldChange `json:"changes"`
+}
+
+// Actor is the caller that made a change. It is empty for changes made
+// outside a request, such as by emsctl.
+type Actor struct {
+	EmployeeID int    `json:"employeeId,omitempty"`
+	Email      string `json:"email,omitempty"`
+	Role       string `json:"role,omitempty"`
+}
+
+// FieldChange is the value of one field before and after a change. Values
+// are nil for empty passwords and deletedAt times, and Redacted for
+// passwords.
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// newAuditEntry describes the change from before to after, made by the
+// caller identified in ctx (see auth.ContextWithIdentity).
+func newAuditEntry(ctx context.Context, operation string, before, after models.Employee) AuditEntry {
+	entry := AuditEntry{
+		EmployeeID: after.ID,
+		Operation:  operation,
+		Timestamp:  time.Now().UTC(),
+		Version:    after.Version,
+		Changes:    []FieldChange{},
+	}
+	if identity, ok := auth.IdentityFromContext(ctx); ok {
+		entry.Actor = Actor{EmployeeID: identity.EmployeeID, Email: identity.Email, Role: identity.Role}
+	}
+	for _, field := range models.ChangedFields(before, after) {
+		// The ID and version are recorded once, outside the changes
+		if field == "id" || field == "version" {
+			continue
+		}
+		entry.Changes = append(entry.Changes, FieldChange{
+			Field:  field,
+			Before: auditValue(before, field),
+			After:  auditValue(after, field),
+		})
+	}
+	return entry
+}
+
+// auditValue returns the value of field as recorded in the audit log.
+func auditValue(employee models.Employee, field string) interface{} {
+	switch field {
+	case "password":
+		if employee.Password == "" {
+			return nil
+		}
+		return Redacted
+	case "deleted":
+		return employee.Deleted
+	case "deletedAt":
+		if employee.DeletedAt == nil {
+			return nil
+		}
+		return *employee.DeletedAt
+	}
+	return fieldValue(employee, field)
+}
+
+// auditRef returns a new entry in the audit log of the employee document.
+func auditRef(employeeRef *firestore.DocumentRef) *firestore.DocumentRef {
+	return employeeRef.Collection(auditCollection).NewDoc()
+}
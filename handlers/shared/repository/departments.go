@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+
+	"example.com/task3gcp/shared/domain"
+	"example.com/task3gcp/shared/models"
+)
+
+const departmentsCollection = "departments"
+
+var (
+	// ErrDepartmentNotFound is returned when no department matches the
+	// requested ID.
+	ErrDepartmentNotFound = domain.New(domain.NotFound, "department not found")
+
+	// ErrDepartmentCodeTaken is returned when a department would get the
+	// code of another one, ignoring case (see models.NormalizeCode).
+	ErrDepartmentCodeTaken = domain.New(domain.Conflict, "department code is already in use")
+
+	// ErrDepartmentInUse is returned when deleting a department that
+	// employees still belong to, including soft-deleted ones.
+	ErrDepartmentInUse = domain.New(domain.Conflict, "department still has employees")
+
+	// ErrUnknownDepartment is returned when an employee would be assigned to
+	// a department that does not exist.
+	ErrUnknownDepartment = domain.Invalid("employee is invalid", []domain.FieldError{
+		{Field: "departmentId", Code: "exists", Message: "departmentId must be the ID of an existing department"},
+	})
+
+	// ErrUnknownHead is returned when a department would be headed by an
+	// employee that does not exist or is deleted.
+	ErrUnknownHead = domain.Invalid("department is invalid", []domain.FieldError{
+		{Field: "headEmployeeId", Code: "exists", Message: "headEmployeeId must be the ID of an existing employee"},
+	})
+)
+
+// DepartmentRepository is the storage used by the department handlers. The
+// repositories that implement it also implement EmployeeRepository, whose
+// writes only accept the IDs of existing departments, so that departments
+// and employees stay consistent. Codes are stored normalized and are unique.
+type DepartmentRepository interface {
+	// ListDepartments returns every department, ordered by ID.
+	ListDepartments(ctx context.Context) ([]models.Department, error)
+
+	// GetDepartment returns the department with the given ID.
+	GetDepartment(ctx context.Context, id int) (models.Department, error)
+
+	// CreateDepartment stores a new department and returns it with its ID
+	// set. IDs are allocated like employee IDs. A non-zero HeadEmployeeID
+	// must name an employee that is not deleted, or ErrUnknownHead is
+	// returned.
+	CreateDepartment(ctx context.Context, department models.Department) (models.Department, error)
+
+	// UpdateDepartment replaces the department that has the same ID,
+	// checking its code and head like CreateDepartment, and returns it as
+	// stored.
+	UpdateDepartment(ctx context.Context, department models.Department) (models.Department, error)
+
+	// DeleteDepartment removes the department with the given ID. It fails
+	// with ErrDepartmentInUse while employees belong to it; reassign them,
+	// or purge deleted ones, first.
+	DeleteDepartment(ctx context.Context, id int) error
+
+	// Close releases any resources held by the repository.
+	Close() error
+}
+
+// normalizeDepartment prepares department to be stored.
+func normalizeDepartment(department *models.Department) {
+	department.Code = models.NormalizeCode(department.Code)
+}
+
+// departmentKey returns the ID of the Firestore document of the department.
+func departmentKey(id int) string {
+	return strconv.Itoa(id)
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"net/url"
+	"sort"
+
+	"example.com/task3gcp/shared/models"
+)
+
+// emailIndex is a document in "employee_emails". Its ID is the emailKey of
+// an email and it names the employee that uses the email, so that
+// transactions can claim an email by creating the document.
+type emailIndex struct {
+	EmployeeID int
+}
+
+// emailKey returns the ID of the email index document for email, or "" for
+// an empty email. Escaping keeps a "/" in the address from being read as a
+// path separator.
+func emailKey(email string) string {
+	return url.PathEscape(models.NormalizeEmail(email))
+}
+
+// duplicateEmails groups the IDs of employees by normalized email and
+// returns the groups with more than one employee, each sorted by ID.
+func duplicateEmails(employees []models.Employee) map[string][]int {
+	byEmail := make(map[string][]int)
+	for _, employee := range employees {
+		if key := models.NormalizeEmail(employee.Email); key != "" {
+			byEmail[key] = append(byEmail[key], employee.ID)
+		}
+	}
+	duplicates := make(map[string][]int)
+	for email, ids := range byEmail {
+		if len(ids) > 1 {
+			sort.Ints(ids)
+			duplicates[email] = ids
+		}
+	}
+	return duplicates
+}
@@ -0,0 +1,1191 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"example.com/task3gcp/shared/models"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	employeesCollection = "employees"
+	countersCollection  = "counters"
+	emailsCollection    = "employee_emails"
+)
+
+// employeeCounter is a document in "counters" that holds the last ID
+// allocated in the collection it is named after, employees or departments.
+type employeeCounter struct {
+	LastID int
+}
+
+// FirestoreRepository stores employees in the Firestore "employees" collection.
+type FirestoreRepository struct {
+	client *firestore.Client
+}
+
+// NewFirestoreRepository returns a repository backed by the given client.
+// The repository takes ownership of the client and closes it in Close.
+func NewFirestoreRepository(client *firestore.Client) *FirestoreRepository {
+	return &FirestoreRepository{client: client}
+}
+
+func (r *FirestoreRepository) List(ctx context.Context, opts ListOptions) (Page, error) {
+	if err := opts.validate(); err != nil {
+		return Page{}, err
+	}
+	fields := opts.sortFields()
+
+	query := r.client.Collection(employeesCollection).Query
+	if !opts.IncludeDeleted {
+		query = query.Where("Deleted", "==", false)
+	}
+	for _, filter := range opts.Filters {
+		query = query.Where(filterFields[filter.Field], "==", filter.Value)
+	}
+	for _, field := range fields {
+		direction := firestore.Asc
+		if field.Desc {
+			direction = firestore.Desc
+		}
+		query = query.OrderBy(employeeFields[field.Field], direction)
+	}
+	if opts.PageToken != "" {
+		cursor, err := decodePageToken(opts.PageToken, fields)
+		if err != nil {
+			return Page{}, err
+		}
+		query = query.StartAfter(cursor...)
+	}
+	if opts.Limit > 0 {
+		// Fetch one extra document to learn whether another page exists
+		query = query.Limit(opts.Limit + 1)
+	}
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	employees := []models.Employee{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return Page{}, err
+		}
+		var employee models.Employee
+		if err := doc.DataTo(&employee); err != nil {
+			return Page{}, err
+		}
+		employees = append(employees, employee)
+	}
+
+	page := Page{Employees: employees}
+	if opts.Limit > 0 && len(employees) > opts.Limit {
+		page.Employees = employees[:opts.Limit]
+		page.NextPageToken = encodePageToken(fields, page.Employees[opts.Limit-1])
+	}
+	return page, nil
+}
+
+func (r *FirestoreRepository) Get(ctx context.Context, id int) (models.Employee, error) {
+	doc, err := r.find(ctx, id)
+	if err != nil {
+		return models.Employee{}, err
+	}
+	var employee models.Employee
+	if err := doc.DataTo(&employee); err != nil {
+		return models.Employee{}, err
+	}
+	return employee, nil
+}
+
+// GetByEmail looks the email up in the email index, which is keyed by the
+// normalized email, rather than querying the Email field.
+func (r *FirestoreRepository) GetByEmail(ctx context.Context, email string) (models.Employee, error) {
+	key := emailKey(email)
+	if key == "" {
+		return models.Employee{}, ErrNotFound
+	}
+	snap, err := r.client.Collection(emailsCollection).Doc(key).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return models.Employee{}, ErrNotFound
+	}
+	if err != nil {
+		return models.Employee{}, err
+	}
+	var index emailIndex
+	if err := snap.DataTo(&index); err != nil {
+		return models.Employee{}, err
+	}
+	employee, err := r.Get(ctx, index.EmployeeID)
+	if err != nil {
+		return models.Employee{}, err
+	}
+	if employee.Deleted || emailKey(employee.Email) != key {
+		return models.Employee{}, ErrNotFound
+	}
+	return employee, nil
+}
+
+// Search runs exact criteria as equality filters and the first prefix
+// criterion as a range over its lowercase field. Any further prefix criteria
+// are applied to the query results, since Firestore allows only one range
+// field per query. Results are sorted by ID before Limit is applied.
+func (r *FirestoreRepository) Search(ctx context.Context, opts SearchOptions) ([]models.Employee, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	query := r.client.Collection(employeesCollection).Query
+	if !opts.IncludeDeleted {
+		query = query.Where("Deleted", "==", false)
+	}
+	var remaining []SearchCriterion
+	ranged := false
+	for _, criterion := range opts.Criteria {
+		fields := searchFields[criterion.Field]
+		switch {
+		case !criterion.Prefix:
+			query = query.Where(fields.exact, "==", criterion.Value)
+		case !ranged:
+			prefix := strings.ToLower(criterion.Value)
+			query = query.Where(fields.lower, ">=", prefix).Where(fields.lower, "<=", prefix+"\uf8ff")
+			ranged = true
+		default:
+			remaining = append(remaining, criterion)
+		}
+	}
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	employees := []models.Employee{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var employee models.Employee
+		if err := doc.DataTo(&employee); err != nil {
+			return nil, err
+		}
+		if matchesCriteria(employee, remaining) {
+			employees = append(employees, employee)
+		}
+	}
+	sort.Slice(employees, func(i, j int) bool { return employees[i].ID < employees[j].ID })
+	if opts.Limit > 0 && len(employees) > opts.Limit {
+		employees = employees[:opts.Limit]
+	}
+	return employees, nil
+}
+
+// Create allocates the next employee ID from the counter document and stores
+// the employee in the same transaction, so concurrent creates never share an
+// ID. The counter is seeded from the highest existing ID the first time it is
+// used.
+func (r *FirestoreRepository) Create(ctx context.Context, employee models.Employee) (models.Employee, error) {
+	employee.Deleted = false
+	employee.DeletedAt = nil
+	employee.SetSearchFields()
+	if err := employee.HashPassword(); err != nil {
+		return models.Employee{}, err
+	}
+
+	counterRef := r.client.Collection(countersCollection).Doc(employeesCollection)
+	employeeRef := r.client.Collection(employeesCollection).NewDoc()
+
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		lastID, err := r.lastEmployeeID(tx, counterRef)
+		if err != nil {
+			return err
+		}
+		employee.ID = lastID + 1
+		employee.Version = 1
+		if err := r.checkDepartment(tx, models.Employee{}, employee); err != nil {
+			return err
+		}
+		writeEmail, err := r.reserveEmail(tx, employee.ID, employee.Email, "")
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Set(counterRef, employeeCounter{LastID: employee.ID}); err != nil {
+			return err
+		}
+		if err := writeEmail(); err != nil {
+			return err
+		}
+		if err := tx.Create(employeeRef, employee); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, employeeRef, AuditCreate, models.Employee{}, employee)
+	})
+	if err != nil {
+		return models.Employee{}, err
+	}
+	return employee, nil
+}
+
+// CreateBatch allocates IDs and claims emails in one transaction per chunk
+// of employees, so the claims are checked like in Create, and then writes
+// the employee documents with a BulkWriter, followed by the audit entries
+// and first version snapshots of the stored ones. An employee whose write
+// fails releases its email again. IDs of employees that were not stored are
+// not reused.
+func (r *FirestoreRepository) CreateBatch(ctx context.Context, employees []models.Employee) ([]BatchResult, error) {
+	results := make([]BatchResult, len(employees))
+	for i := range employees {
+		employee := employees[i]
+		employee.Deleted = false
+		employee.DeletedAt = nil
+		employee.Version = 1
+		employee.SetSearchFields()
+		if err := employee.HashPassword(); err != nil {
+			return nil, err
+		}
+		results[i].Employee = employee
+	}
+
+	for start := 0; start < len(results); start += batchChunkSize {
+		end := start + batchChunkSize
+		if end > len(results) {
+			end = len(results)
+		}
+		if err := r.claimBatch(ctx, results[start:end]); err != nil {
+			return nil, err
+		}
+	}
+
+	writer := r.client.BulkWriter(ctx)
+	refs := make([]*firestore.DocumentRef, len(results))
+	jobs := make([]*firestore.BulkWriterJob, len(results))
+	for i, result := range results {
+		if result.Err != nil {
+			continue
+		}
+		refs[i] = r.client.Collection(employeesCollection).NewDoc()
+		job, err := writer.Create(refs[i], result.Employee)
+		if err != nil {
+			writer.End()
+			return nil, err
+		}
+		jobs[i] = job
+	}
+	writer.Flush()
+
+	// Record only the employees that were stored
+	var records []*firestore.BulkWriterJob
+	for i, job := range jobs {
+		if job == nil {
+			continue
+		}
+		if _, err := job.Results(); err != nil {
+			results[i].Err = err
+			if key := emailKey(results[i].Employee.Email); key != "" {
+				if _, err := r.client.Collection(emailsCollection).Doc(key).Delete(ctx); err != nil {
+					writer.End()
+					return nil, err
+				}
+			}
+			continue
+		}
+		audit, err := writer.Create(auditRef(refs[i]), newAuditEntry(ctx, AuditCreate, models.Employee{}, results[i].Employee))
+		if err != nil {
+			writer.End()
+			return nil, err
+		}
+		version, err := writer.Set(versionRef(refs[i], 1), snapshot(results[i].Employee))
+		if err != nil {
+			writer.End()
+			return nil, err
+		}
+		records = append(records, audit, version)
+	}
+	writer.End()
+
+	for _, record := range records {
+		if _, err := record.Results(); err != nil {
+			return nil, err
+		}
+	}
+	for i := range results {
+		if results[i].Err != nil {
+			results[i].Employee = models.Employee{}
+		}
+	}
+	return results, nil
+}
+
+// batchChunkSize keeps the transactions of CreateBatch, which write one
+// email index entry per employee plus the counter, below Firestore's limit
+// of 500 writes.
+const batchChunkSize = 400
+
+// claimBatch allocates the IDs of results and claims their emails in one
+// transaction. Results whose email is taken get ErrEmailTaken, and results
+// assigned to a department that does not exist ErrUnknownDepartment.
+func (r *FirestoreRepository) claimBatch(ctx context.Context, results []BatchResult) error {
+	counterRef := r.client.Collection(countersCollection).Doc(employeesCollection)
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		lastID, err := r.lastEmployeeID(tx, counterRef)
+		if err != nil {
+			return err
+		}
+
+		departments, err := r.existingDepartments(tx, results)
+		if err != nil {
+			return err
+		}
+
+		refs := make([]*firestore.DocumentRef, 0, len(results))
+		for _, result := range results {
+			if key := emailKey(result.Employee.Email); key != "" {
+				refs = append(refs, r.client.Collection(emailsCollection).Doc(key))
+			}
+		}
+		taken := make(map[string]bool, len(refs))
+		if len(refs) > 0 {
+			snaps, err := tx.GetAll(refs)
+			if err != nil {
+				return err
+			}
+			for _, snap := range snaps {
+				if snap.Exists() {
+					taken[snap.Ref.ID] = true
+				}
+			}
+		}
+
+		for i := range results {
+			// The transaction may be retried, so start over every time.
+			results[i].Employee.ID = lastID + 1 + i
+			results[i].Err = nil
+			if id := results[i].Employee.DepartmentID; id != 0 && !departments[id] {
+				results[i].Err = ErrUnknownDepartment
+				continue
+			}
+			key := emailKey(results[i].Employee.Email)
+			if key == "" {
+				continue
+			}
+			if taken[key] {
+				results[i].Err = ErrEmailTaken
+				continue
+			}
+			taken[key] = true
+			ref := r.client.Collection(emailsCollection).Doc(key)
+			if err := tx.Create(ref, emailIndex{EmployeeID: results[i].Employee.ID}); err != nil {
+				return err
+			}
+		}
+		return tx.Set(counterRef, employeeCounter{LastID: lastID + len(results)})
+	})
+}
+
+func (r *FirestoreRepository) Update(ctx context.Context, employee models.Employee) (models.Employee, error) {
+	plaintext := employee.Password
+	if err := employee.HashPassword(); err != nil {
+		return models.Employee{}, err
+	}
+	doc, err := r.find(ctx, employee.ID)
+	if err != nil {
+		return models.Employee{}, err
+	}
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		existing, err := getEmployee(tx, doc.Ref)
+		if err != nil {
+			return err
+		}
+		if existing.Deleted {
+			return ErrNotFound
+		}
+		if err := checkVersion(existing, employee.Version); err != nil {
+			return err
+		}
+		if err := r.checkDepartment(tx, existing, employee); err != nil {
+			return err
+		}
+		writeEmail, err := r.reserveEmail(tx, employee.ID, employee.Email, existing.Email)
+		if err != nil {
+			return err
+		}
+		keepPasswordHash(&employee, existing, plaintext)
+		employee.Deleted = false
+		employee.DeletedAt = nil
+		employee.Version = existing.Version + 1
+		employee.SetSearchFields()
+		if err := writeEmail(); err != nil {
+			return err
+		}
+		if err := tx.Set(doc.Ref, employee); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, doc.Ref, AuditUpdate, existing, employee)
+	})
+	if err != nil {
+		return models.Employee{}, err
+	}
+	return employee, nil
+}
+
+// Patch updates the named fields, and the lowercase copies of the
+// searchable ones, without rewriting the rest of the document.
+func (r *FirestoreRepository) Patch(ctx context.Context, employee models.Employee, fields []string) (models.Employee, error) {
+	if err := checkPatchFields(fields); err != nil {
+		return models.Employee{}, err
+	}
+	if err := employee.HashPassword(); err != nil {
+		return models.Employee{}, err
+	}
+	doc, err := r.find(ctx, employee.ID)
+	if err != nil {
+		return models.Employee{}, err
+	}
+	updates := make([]firestore.Update, 0, 2*len(fields))
+	for _, field := range fields {
+		value := patchValue(employee, field)
+		updates = append(updates, firestore.Update{Path: patchPaths[field], Value: value})
+		if search, ok := searchFields[field]; ok {
+			updates = append(updates, firestore.Update{Path: search.lower, Value: strings.ToLower(value.(string))})
+		}
+	}
+	if len(updates) == 0 {
+		return r.Get(ctx, employee.ID)
+	}
+	var patched models.Employee
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		existing, err := getEmployee(tx, doc.Ref)
+		if err != nil {
+			return err
+		}
+		if existing.Deleted {
+			return ErrNotFound
+		}
+		if err := checkVersion(existing, employee.Version); err != nil {
+			return err
+		}
+		patched = existing
+		applyPatch(&patched, employee, fields)
+		if err := r.checkDepartment(tx, existing, patched); err != nil {
+			return err
+		}
+		writeEmail, err := r.reserveEmail(tx, existing.ID, patched.Email, existing.Email)
+		if err != nil {
+			return err
+		}
+		if err := writeEmail(); err != nil {
+			return err
+		}
+		if err := tx.Update(doc.Ref, append(updates, firestore.Update{Path: "Version", Value: existing.Version + 1})); err != nil {
+			return err
+		}
+		patched.Version++
+		patched.SetSearchFields()
+		return recordChange(ctx, tx, doc.Ref, AuditUpdate, existing, patched)
+	})
+	if err != nil {
+		return models.Employee{}, err
+	}
+	return patched, nil
+}
+
+func (r *FirestoreRepository) Delete(ctx context.Context, id, version int) error {
+	doc, err := r.find(ctx, id)
+	if err != nil {
+		return err
+	}
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		existing, err := getEmployee(tx, doc.Ref)
+		if err != nil {
+			return err
+		}
+		if existing.Deleted {
+			return ErrNotFound
+		}
+		if err := checkVersion(existing, version); err != nil {
+			return err
+		}
+		deleted := existing
+		deletedAt := time.Now().UTC()
+		deleted.Deleted = true
+		deleted.DeletedAt = &deletedAt
+		deleted.Version++
+		err = tx.Update(doc.Ref, []firestore.Update{
+			{Path: "Deleted", Value: true},
+			{Path: "DeletedAt", Value: deletedAt},
+			{Path: "Version", Value: deleted.Version},
+		})
+		if err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, doc.Ref, AuditDelete, existing, deleted)
+	})
+}
+
+func (r *FirestoreRepository) Restore(ctx context.Context, id int) (models.Employee, error) {
+	doc, err := r.find(ctx, id)
+	if err != nil {
+		return models.Employee{}, err
+	}
+	var employee models.Employee
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		existing, err := getEmployee(tx, doc.Ref)
+		if err != nil {
+			return err
+		}
+		if !existing.Deleted {
+			return ErrNotDeleted
+		}
+		employee = existing
+		employee.Deleted = false
+		employee.DeletedAt = nil
+		employee.Version++
+		err = tx.Update(doc.Ref, []firestore.Update{
+			{Path: "Deleted", Value: false},
+			{Path: "DeletedAt", Value: nil},
+			{Path: "Version", Value: employee.Version},
+		})
+		if err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, doc.Ref, AuditRestore, existing, employee)
+	})
+	if err != nil {
+		return models.Employee{}, err
+	}
+	return employee, nil
+}
+
+// Purge only matches documents with a DeletedAt timestamp; restored
+// employees store null there and are skipped by the range filter. Each
+// document is deleted only if it is unchanged since the query read it, so an
+// employee restored in the meantime is kept. Only then are its email
+// released and its version snapshots deleted.
+func (r *FirestoreRepository) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	iter := r.client.Collection(employeesCollection).Where("DeletedAt", "<", deletedBefore).Documents(ctx)
+	defer iter.Stop()
+
+	writer := r.client.BulkWriter(ctx)
+	var jobs []*firestore.BulkWriterJob
+	var docs []*firestore.DocumentSnapshot
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			writer.End()
+			return 0, err
+		}
+		job, err := writer.Delete(doc.Ref, firestore.LastUpdateTime(doc.UpdateTime))
+		if err != nil {
+			writer.End()
+			return 0, err
+		}
+		jobs = append(jobs, job)
+		docs = append(docs, doc)
+	}
+	writer.End()
+
+	cleanup := r.client.BulkWriter(ctx)
+	defer cleanup.End()
+	purged := 0
+	for i, job := range jobs {
+		_, err := job.Results()
+		switch status.Code(err) {
+		case codes.OK:
+		case codes.FailedPrecondition, codes.NotFound:
+			// Changed, e.g. restored, or purged by someone else since the query
+			continue
+		default:
+			return purged, err
+		}
+		purged++
+
+		var employee models.Employee
+		if err := docs[i].DataTo(&employee); err != nil {
+			return purged, err
+		}
+		if err := r.releaseEmail(ctx, cleanup, employee); err != nil {
+			return purged, err
+		}
+		if err := deleteVersions(ctx, cleanup, docs[i].Ref); err != nil {
+			return purged, err
+		}
+		// The audit log outlives the employee document
+		if _, err := cleanup.Create(auditRef(docs[i].Ref), newAuditEntry(ctx, AuditPurge, employee, employee)); err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
+
+// RehashPasswords hashes every password that is still stored in plaintext and
+// returns how many employees were changed. Each document is re-read in a
+// transaction, so it is safe to run while the functions are serving traffic
+// and to run again after a partial failure.
+func (r *FirestoreRepository) RehashPasswords(ctx context.Context) (int, error) {
+	iter := r.client.Collection(employeesCollection).Documents(ctx)
+	defer iter.Stop()
+
+	rehashed := 0
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return rehashed, err
+		}
+
+		changed := false
+		err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+			changed = false
+			employee, err := getEmployee(tx, doc.Ref)
+			if err != nil {
+				return err
+			}
+			if employee.Password == "" || models.IsPasswordHash(employee.Password) {
+				return nil
+			}
+			if err := employee.HashPassword(); err != nil {
+				return err
+			}
+			changed = true
+			return tx.Update(doc.Ref, []firestore.Update{{Path: "Password", Value: employee.Password}})
+		})
+		if err != nil {
+			return rehashed, err
+		}
+		if changed {
+			rehashed++
+		}
+	}
+	return rehashed, nil
+}
+
+// DuplicateEmails returns the IDs of the employees that share each email,
+// ignoring case. Only emails used more than once are included. Such
+// duplicates predate the email index and must be resolved by hand.
+func (r *FirestoreRepository) DuplicateEmails(ctx context.Context) (map[string][]int, error) {
+	docs, err := r.client.Collection(employeesCollection).Select("ID", "Email").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	employees := make([]models.Employee, len(docs))
+	for i, doc := range docs {
+		if err := doc.DataTo(&employees[i]); err != nil {
+			return nil, err
+		}
+	}
+	return duplicateEmails(employees), nil
+}
+
+// IndexEmails adds the email index entries of employees written before the
+// index existed and returns how many were added. Emails already claimed by
+// another employee are skipped; DuplicateEmails lists them. Like
+// RehashPasswords it can be rerun safely.
+func (r *FirestoreRepository) IndexEmails(ctx context.Context) (int, error) {
+	iter := r.client.Collection(employeesCollection).Documents(ctx)
+	defer iter.Stop()
+
+	indexed := 0
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return indexed, err
+		}
+
+		var employee models.Employee
+		if err := doc.DataTo(&employee); err != nil {
+			return indexed, err
+		}
+		if emailKey(employee.Email) == "" {
+			continue
+		}
+		ref := r.client.Collection(emailsCollection).Doc(emailKey(employee.Email))
+		_, err = ref.Create(ctx, emailIndex{EmployeeID: employee.ID})
+		switch status.Code(err) {
+		case codes.OK:
+			indexed++
+		case codes.AlreadyExists:
+		default:
+			return indexed, err
+		}
+	}
+	return indexed, nil
+}
+
+// History reads the employee's audit subcollection. Entries of purged
+// employees stay in Firestore but can no longer be looked up by ID.
+func (r *FirestoreRepository) History(ctx context.Context, id int) ([]AuditEntry, error) {
+	doc, err := r.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := doc.Ref.Collection(auditCollection).OrderBy("Timestamp", firestore.Asc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]AuditEntry, len(docs))
+	for i, doc := range docs {
+		if err := doc.DataTo(&entries[i]); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+func (r *FirestoreRepository) Version(ctx context.Context, id, version int) (models.Employee, error) {
+	doc, err := r.find(ctx, id)
+	if err != nil {
+		return models.Employee{}, err
+	}
+	var current models.Employee
+	if err := doc.DataTo(&current); err != nil {
+		return models.Employee{}, err
+	}
+	snap, err := versionRef(doc.Ref, version).Get(ctx)
+	return decodeVersion(snap, err, current, version)
+}
+
+// Rollback reads the snapshot and rewrites the employee in one transaction,
+// claiming the restored email like Update.
+func (r *FirestoreRepository) Rollback(ctx context.Context, id, to, version int) (models.Employee, error) {
+	doc, err := r.find(ctx, id)
+	if err != nil {
+		return models.Employee{}, err
+	}
+	var employee models.Employee
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		existing, err := getEmployee(tx, doc.Ref)
+		if err != nil {
+			return err
+		}
+		if existing.Deleted {
+			return ErrNotFound
+		}
+		if err := checkVersion(existing, version); err != nil {
+			return err
+		}
+		snap, err := tx.Get(versionRef(doc.Ref, to))
+		target, err := decodeVersion(snap, err, existing, to)
+		if err != nil {
+			return err
+		}
+		employee = existing
+		applyPatch(&employee, target, rollbackFields)
+		employee.Version++
+		employee.SetSearchFields()
+		if err := r.checkDepartment(tx, existing, employee); err != nil {
+			return err
+		}
+		writeEmail, err := r.reserveEmail(tx, id, employee.Email, existing.Email)
+		if err != nil {
+			return err
+		}
+		if err := writeEmail(); err != nil {
+			return err
+		}
+		if err := tx.Set(doc.Ref, employee); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, doc.Ref, AuditRollback, existing, employee)
+	})
+	if err != nil {
+		return models.Employee{}, err
+	}
+	return employee, nil
+}
+
+func (r *FirestoreRepository) ListDepartments(ctx context.Context) ([]models.Department, error) {
+	docs, err := r.client.Collection(departmentsCollection).OrderBy("ID", firestore.Asc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	departments := make([]models.Department, len(docs))
+	for i, doc := range docs {
+		if err := doc.DataTo(&departments[i]); err != nil {
+			return nil, err
+		}
+	}
+	return departments, nil
+}
+
+func (r *FirestoreRepository) GetDepartment(ctx context.Context, id int) (models.Department, error) {
+	snap, err := r.departmentRef(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return models.Department{}, ErrDepartmentNotFound
+	}
+	if err != nil {
+		return models.Department{}, err
+	}
+	var department models.Department
+	if err := snap.DataTo(&department); err != nil {
+		return models.Department{}, err
+	}
+	return department, nil
+}
+
+// CreateDepartment allocates the next department ID from its counter
+// document and checks the code and head in the same transaction.
+func (r *FirestoreRepository) CreateDepartment(ctx context.Context, department models.Department) (models.Department, error) {
+	normalizeDepartment(&department)
+	counterRef := r.client.Collection(countersCollection).Doc(departmentsCollection)
+
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		var counter employeeCounter
+		snap, err := tx.Get(counterRef)
+		if err == nil {
+			err = snap.DataTo(&counter)
+		}
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		department.ID = counter.LastID + 1
+		if err := r.checkDepartmentFields(tx, department); err != nil {
+			return err
+		}
+
+		if err := tx.Set(counterRef, employeeCounter{LastID: department.ID}); err != nil {
+			return err
+		}
+		return tx.Create(r.departmentRef(department.ID), department)
+	})
+	if err != nil {
+		return models.Department{}, err
+	}
+	return department, nil
+}
+
+func (r *FirestoreRepository) UpdateDepartment(ctx context.Context, department models.Department) (models.Department, error) {
+	normalizeDepartment(&department)
+	ref := r.departmentRef(department.ID)
+
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if _, err := tx.Get(ref); err != nil {
+			if status.Code(err) == codes.NotFound {
+				return ErrDepartmentNotFound
+			}
+			return err
+		}
+		if err := r.checkDepartmentFields(tx, department); err != nil {
+			return err
+		}
+		return tx.Set(ref, department)
+	})
+	if err != nil {
+		return models.Department{}, err
+	}
+	return department, nil
+}
+
+// DeleteDepartment looks for employees of the department inside the
+// transaction, so an employee assigned concurrently makes one of the two
+// transactions retry.
+func (r *FirestoreRepository) DeleteDepartment(ctx context.Context, id int) error {
+	ref := r.departmentRef(id)
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if _, err := tx.Get(ref); err != nil {
+			if status.Code(err) == codes.NotFound {
+				return ErrDepartmentNotFound
+			}
+			return err
+		}
+		query := r.client.Collection(employeesCollection).Where("DepartmentID", "==", id).Limit(1)
+		members, err := tx.Documents(query).GetAll()
+		if err != nil {
+			return err
+		}
+		if len(members) > 0 {
+			return ErrDepartmentInUse
+		}
+		return tx.Delete(ref)
+	})
+}
+
+func (r *FirestoreRepository) Close() error {
+	return r.client.Close()
+}
+
+// lastEmployeeID reads the last allocated ID inside tx. Collections created
+// before the counter existed fall back to the highest stored ID.
+func (r *FirestoreRepository) lastEmployeeID(tx *firestore.Transaction, counterRef *firestore.DocumentRef) (int, error) {
+	snap, err := tx.Get(counterRef)
+	if err == nil {
+		var counter employeeCounter
+		if err := snap.DataTo(&counter); err != nil {
+			return 0, err
+		}
+		return counter.LastID, nil
+	}
+	if status.Code(err) != codes.NotFound {
+		return 0, err
+	}
+
+	query := r.client.Collection(employeesCollection).OrderBy("ID", firestore.Desc).Limit(1)
+	iter := tx.Documents(query)
+	defer iter.Stop()
+
+	doc, err := iter.Next()
+	if err == iterator.Done {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var highest models.Employee
+	if err := doc.DataTo(&highest); err != nil {
+		return 0, err
+	}
+	return highest.ID, nil
+}
+
+// reserveEmail checks inside tx that email is free for employee id, which
+// currently uses previous, and returns ErrEmailTaken if another employee
+// has it. Firestore transactions must read before they write, so the
+// returned function, which claims email and releases previous, has to be
+// called after the transaction's other reads.
+func (r *FirestoreRepository) reserveEmail(tx *firestore.Transaction, id int, email, previous string) (func() error, error) {
+	key, previousKey := emailKey(email), emailKey(previous)
+	if key == previousKey {
+		return func() error { return nil }, nil
+	}
+
+	var claim, release *firestore.DocumentRef
+	if key != "" {
+		claim = r.client.Collection(emailsCollection).Doc(key)
+		owner, err := emailOwner(tx, claim)
+		if err != nil {
+			return nil, err
+		}
+		if owner != 0 && owner != id {
+			return nil, ErrEmailTaken
+		}
+	}
+	if previousKey != "" {
+		ref := r.client.Collection(emailsCollection).Doc(previousKey)
+		owner, err := emailOwner(tx, ref)
+		if err != nil {
+			return nil, err
+		}
+		// Never release an entry another employee claimed
+		if owner == id {
+			release = ref
+		}
+	}
+
+	return func() error {
+		if claim != nil {
+			if err := tx.Set(claim, emailIndex{EmployeeID: id}); err != nil {
+				return err
+			}
+		}
+		if release != nil {
+			return tx.Delete(release)
+		}
+		return nil
+	}, nil
+}
+
+// emailOwner returns the ID of the employee an email index entry names, or
+// zero if there is no entry.
+func emailOwner(tx *firestore.Transaction, ref *firestore.DocumentRef) (int, error) {
+	snap, err := tx.Get(ref)
+	if status.Code(err) == codes.NotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var index emailIndex
+	if err := snap.DataTo(&index); err != nil {
+		return 0, err
+	}
+	return index.EmployeeID, nil
+}
+
+// releaseEmail queues the deletion of the email index entry of a purged
+// employee, unless another employee holds it.
+func (r *FirestoreRepository) releaseEmail(ctx context.Context, writer *firestore.BulkWriter, employee models.Employee) error {
+	key := emailKey(employee.Email)
+	if key == "" {
+		return nil
+	}
+	ref := r.client.Collection(emailsCollection).Doc(key)
+	snap, err := ref.Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var index emailIndex
+	if err := snap.DataTo(&index); err != nil {
+		return err
+	}
+	if index.EmployeeID != employee.ID {
+		return nil
+	}
+	_, err = writer.Delete(ref, firestore.LastUpdateTime(snap.UpdateTime))
+	return err
+}
+
+// departmentRef returns the document of the department with the given ID.
+func (r *FirestoreRepository) departmentRef(id int) *firestore.DocumentRef {
+	return r.client.Collection(departmentsCollection).Doc(departmentKey(id))
+}
+
+// checkDepartment reads inside tx whether the department after is assigned
+// to exists, and returns ErrUnknownDepartment if it does not. Only a changed
+// assignment is checked. Reading the department document makes a
+// concurrent DeleteDepartment conflict with the write.
+func (r *FirestoreRepository) checkDepartment(tx *firestore.Transaction, before, after models.Employee) error {
+	if after.DepartmentID == 0 || after.DepartmentID == before.DepartmentID {
+		return nil
+	}
+	_, err := tx.Get(r.departmentRef(after.DepartmentID))
+	if status.Code(err) == codes.NotFound {
+		return ErrUnknownDepartment
+	}
+	return err
+}
+
+// existingDepartments reads inside tx the departments results are assigned
+// to and returns the IDs of those that exist.
+func (r *FirestoreRepository) existingDepartments(tx *firestore.Transaction, results []BatchResult) (map[int]bool, error) {
+	var refs []*firestore.DocumentRef
+	seen := make(map[int]bool)
+	for _, result := range results {
+		if id := result.Employee.DepartmentID; id != 0 && !seen[id] {
+			seen[id] = true
+			refs = append(refs, r.departmentRef(id))
+		}
+	}
+	existing := make(map[int]bool, len(refs))
+	if len(refs) == 0 {
+		return existing, nil
+	}
+	snaps, err := tx.GetAll(refs)
+	if err != nil {
+		return nil, err
+	}
+	for _, snap := range snaps {
+		if snap.Exists() {
+			var department models.Department
+			if err := snap.DataTo(&department); err != nil {
+				return nil, err
+			}
+			existing[department.ID] = true
+		}
+	}
+	return existing, nil
+}
+
+// checkDepartmentFields checks inside tx that the code of department is
+// free and that its head is an employee who is not deleted.
+func (r *FirestoreRepository) checkDepartmentFields(tx *firestore.Transaction, department models.Department) error {
+	query := r.client.Collection(departmentsCollection).Where("Code", "==", department.Code)
+	docs, err := tx.Documents(query).GetAll()
+	if err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		if doc.Ref.ID != departmentKey(department.ID) {
+			return ErrDepartmentCodeTaken
+		}
+	}
+
+	if department.HeadEmployeeID == 0 {
+		return nil
+	}
+	query = r.client.Collection(employeesCollection).Where("ID", "==", department.HeadEmployeeID).Limit(1)
+	heads, err := tx.Documents(query).GetAll()
+	if err != nil {
+		return err
+	}
+	if len(heads) == 0 {
+		return ErrUnknownHead
+	}
+	var head models.Employee
+	if err := heads[0].DataTo(&head); err != nil {
+		return err
+	}
+	if head.Deleted {
+		return ErrUnknownHead
+	}
+	return nil
+}
+
+// recordChange writes the audit entry of a change to the employee document
+// at ref, and the snapshot of the version the change wrote, inside tx.
+func recordChange(ctx context.Context, tx *firestore.Transaction, ref *firestore.DocumentRef, operation string, before, after models.Employee) error {
+	if err := tx.Create(auditRef(ref), newAuditEntry(ctx, operation, before, after)); err != nil {
+		return err
+	}
+	return tx.Set(versionRef(ref, after.Version), snapshot(after))
+}
+
+// decodeVersion decodes the snapshot of the given version of current, read
+// with error err. The current version is known even without a snapshot.
+func decodeVersion(snap *firestore.DocumentSnapshot, err error, current models.Employee, version int) (models.Employee, error) {
+	if status.Code(err) == codes.NotFound {
+		if version == current.Version {
+			return snapshot(current), nil
+		}
+		return models.Employee{}, ErrVersionNotFound
+	}
+	if err != nil {
+		return models.Employee{}, err
+	}
+	var employee models.Employee
+	if err := snap.DataTo(&employee); err != nil {
+		return models.Employee{}, err
+	}
+	return employee, nil
+}
+
+// getEmployee reads and decodes the employee document inside tx.
+func getEmployee(tx *firestore.Transaction, ref *firestore.DocumentRef) (models.Employee, error) {
+	snap, err := tx.Get(ref)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return models.Employee{}, ErrNotFound
+		}
+		return models.Employee{}, err
+	}
+	var employee models.Employee
+	if err := snap.DataTo(&employee); err != nil {
+		return models.Employee{}, err
+	}
+	return employee, nil
+}
+
+// find returns the document whose "ID" field matches id.
+func (r *FirestoreRepository) find(ctx context.Context, id int) (*firestore.DocumentSnapshot, error) {
+	iter := r.client.Collection(employeesCollection).Where("ID", "==", id).Limit(1).Documents(ctx)
+	defer iter.Stop()
+
+	doc, err := iter.Next()
+	if err == iterator.Done {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
@@ -0,0 +1,296 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"example.com/task3gcp/shared/domain"
+	"example.com/task3gcp/shared/models"
+)
+
+// MaxPageSize is the largest limit a single List call accepts.
+const MaxPageSize = 1000
+
+// ErrInvalidQuery is returned for list options that cannot be applied, such
+// as an unknown field or a malformed page token.
+var ErrInvalidQuery = domain.New(domain.Validation, "invalid query")
+
+// ListOptions controls which employees List returns.
+type ListOptions struct {
+	// IncludeDeleted also returns soft-deleted employees.
+	IncludeDeleted bool
+
+	// Limit caps the number of employees in one page; zero returns every
+	// matching employee.
+	Limit int
+
+	// PageToken resumes a listing after the last employee of a previous page.
+	// It must come from Page.NextPageToken of a List call with the same
+	// OrderBy.
+	PageToken string
+
+	// OrderBy sorts the results. Employees are always ordered by ID last,
+	// which keeps pages stable when other fields are equal.
+	OrderBy []SortField
+
+	// Filters keeps only employees whose fields equal the given values.
+	Filters []Filter
+}
+
+// SortField orders a listing by one employee field.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Filter is an equality condition on one employee field.
+type Filter struct {
+	Field string
+	Value interface{}
+}
+
+// Page is one page of a listing.
+type Page struct {
+	Employees     []models.Employee `json:"employees"`
+	NextPageToken string            `json:"nextPageToken,omitempty"`
+}
+
+// employeeFields maps the JSON names accepted in queries to the field names
+// stored in Firestore.
+var employeeFields = map[string]string{
+	"id":        "ID",
+	"firstName": "FirstName",
+	"lastName":  "LastName",
+	"email":     "Email",
+	"role":      "Role",
+}
+
+// filterFields maps the JSON names List filters on to their Firestore
+// fields: every field of employeeFields and departmentId. Employees stored
+// before departments existed have no DepartmentID in Firestore, and
+// ordering by a missing field would leave them out, so it only filters.
+var filterFields = map[string]string{
+	"id":           "ID",
+	"firstName":    "FirstName",
+	"lastName":     "LastName",
+	"email":        "Email",
+	"role":         "Role",
+	"departmentId": "DepartmentID",
+}
+
+// ListOptionsFromQuery parses the list query parameters: limit, pageToken,
+// includeDeleted, orderBy (comma separated, "-" prefix for descending) and
+// equality filters named after employee fields, e.g. role=manager or
+// departmentId=3. Other parameters are ignored.
+func ListOptionsFromQuery(values url.Values) (ListOptions, error) {
+	var opts ListOptions
+
+	if value := values.Get("includeDeleted"); value != "" {
+		includeDeleted, err := strconv.ParseBool(value)
+		if err != nil {
+			return ListOptions{}, fmt.Errorf("%w: includeDeleted must be true or false", ErrInvalidQuery)
+		}
+		opts.IncludeDeleted = includeDeleted
+	}
+
+	if value := values.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > MaxPageSize {
+			return ListOptions{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxPageSize)
+		}
+		opts.Limit = limit
+	}
+
+	opts.PageToken = values.Get("pageToken")
+
+	if value := values.Get("orderBy"); value != "" {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			desc := strings.HasPrefix(name, "-")
+			name = strings.TrimPrefix(name, "-")
+			if _, ok := employeeFields[name]; !ok {
+				return ListOptions{}, fmt.Errorf("%w: cannot order by %q", ErrInvalidQuery, name)
+			}
+			opts.OrderBy = append(opts.OrderBy, SortField{Field: name, Desc: desc})
+		}
+	}
+
+	names := make([]string, 0, len(filterFields))
+	for name := range filterFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+		filter := Filter{Field: name, Value: value[0]}
+		if name == "id" || name == "departmentId" {
+			id, err := strconv.Atoi(value[0])
+			if err != nil {
+				return ListOptions{}, fmt.Errorf("%w: %s must be a number", ErrInvalidQuery, name)
+			}
+			filter.Value = id
+		}
+		opts.Filters = append(opts.Filters, filter)
+	}
+	return opts, nil
+}
+
+// sortFields returns the effective ordering with the ID tiebreaker appended.
+func (opts ListOptions) sortFields() []SortField {
+	fields := make([]SortField, 0, len(opts.OrderBy)+1)
+	for _, field := range opts.OrderBy {
+		fields = append(fields, field)
+		if field.Field == "id" {
+			return fields
+		}
+	}
+	return append(fields, SortField{Field: "id"})
+}
+
+// validate checks that every field referenced by the options is known.
+func (opts ListOptions) validate() error {
+	for _, field := range opts.OrderBy {
+		if _, ok := employeeFields[field.Field]; !ok {
+			return fmt.Errorf("%w: cannot order by %q", ErrInvalidQuery, field.Field)
+		}
+	}
+	for _, filter := range opts.Filters {
+		if _, ok := filterFields[filter.Field]; !ok {
+			return fmt.Errorf("%w: cannot filter by %q", ErrInvalidQuery, filter.Field)
+		}
+	}
+	if opts.Limit < 0 || opts.Limit > MaxPageSize {
+		return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxPageSize)
+	}
+	return nil
+}
+
+// pageCursor is the decoded form of a page token: the sort key of the last
+// employee on the previous page and the ordering it belongs to.
+type pageCursor struct {
+	OrderBy string        `json:"o"`
+	Values  []interface{} `json:"v"`
+}
+
+// orderKey describes an ordering so tokens cannot be replayed against a
+// different one.
+func orderKey(fields []SortField) string {
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i] = field.Field
+		if field.Desc {
+			parts[i] = "-" + field.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// encodePageToken returns the token that resumes the listing after employee.
+func encodePageToken(fields []SortField, employee models.Employee) string {
+	cursor := pageCursor{OrderBy: orderKey(fields)}
+	for _, field := range fields {
+		cursor.Values = append(cursor.Values, fieldValue(employee, field.Field))
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodePageToken returns the cursor values of token for the given ordering.
+func decodePageToken(token string, fields []SortField) ([]interface{}, error) {
+	invalid := fmt.Errorf("%w: invalid page token", ErrInvalidQuery)
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, invalid
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var cursor pageCursor
+	if err := decoder.Decode(&cursor); err != nil {
+		return nil, invalid
+	}
+	if cursor.OrderBy != orderKey(fields) || len(cursor.Values) != len(fields) {
+		return nil, invalid
+	}
+
+	for i, field := range fields {
+		switch value := cursor.Values[i].(type) {
+		case json.Number:
+			if field.Field != "id" {
+				return nil, invalid
+			}
+			id, err := value.Int64()
+			if err != nil {
+				return nil, invalid
+			}
+			cursor.Values[i] = int(id)
+		case string:
+			if field.Field == "id" {
+				return nil, invalid
+			}
+		default:
+			return nil, invalid
+		}
+	}
+	return cursor.Values, nil
+}
+
+// fieldValue returns the value of the named query field of employee.
+func fieldValue(employee models.Employee, field string) interface{} {
+	switch field {
+	case "id":
+		return employee.ID
+	case "firstName":
+		return employee.FirstName
+	case "lastName":
+		return employee.LastName
+	case "email":
+		return employee.Email
+	case "role":
+		return employee.Role
+	case "departmentId":
+		return employee.DepartmentID
+	}
+	return nil
+}
+
+// compareValues orders two values of the same query field.
+func compareValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case int:
+		b := b.(int)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(a, b.(string))
+	}
+	return 0
+}
+
+// compareKeys orders employee against a cursor under the given ordering.
+func compareKeys(fields []SortField, employee models.Employee, cursor []interface{}) int {
+	for i, field := range fields {
+		c := compareValues(fieldValue(employee, field.Field), cursor[i])
+		if field.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
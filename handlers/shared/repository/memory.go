@@ -0,0 +1,538 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"example.com/task3gcp/shared/models"
+)
+
+// MemoryRepository keeps employees in memory. It needs no GCP credentials and
+// is meant for tests and local development.
+type MemoryRepository struct {
+	mu        sync.RWMutex
+	employees map[int]models.Employee
+	lastID    int
+	audit     []AuditEntry
+	versions  map[int]map[int]models.Employee
+
+	departments      map[int]models.Department
+	lastDepartmentID int
+}
+
+// NewMemoryRepository returns a repository seeded with the given employees.
+func NewMemoryRepository(employees ...models.Employee) *MemoryRepository {
+	r := &MemoryRepository{
+		employees:   make(map[int]models.Employee),
+		versions:    make(map[int]map[int]models.Employee),
+		departments: make(map[int]models.Department),
+	}
+	for _, employee := range employees {
+		employee.SetSearchFields()
+		r.employees[employee.ID] = employee
+		if employee.ID > r.lastID {
+			r.lastID = employee.ID
+		}
+	}
+	return r
+}
+
+func (r *MemoryRepository) List(ctx context.Context, opts ListOptions) (Page, error) {
+	if err := opts.validate(); err != nil {
+		return Page{}, err
+	}
+	fields := opts.sortFields()
+	var cursor []interface{}
+	if opts.PageToken != "" {
+		var err error
+		if cursor, err = decodePageToken(opts.PageToken, fields); err != nil {
+			return Page{}, err
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	employees := make([]models.Employee, 0, len(r.employees))
+	for _, employee := range r.employees {
+		if employee.Deleted && !opts.IncludeDeleted {
+			continue
+		}
+		if !matchesFilters(employee, opts.Filters) {
+			continue
+		}
+		if cursor != nil && compareKeys(fields, employee, cursor) <= 0 {
+			continue
+		}
+		employees = append(employees, employee)
+	}
+	sort.Slice(employees, func(i, j int) bool {
+		return compareKeys(fields, employees[i], sortKey(fields, employees[j])) < 0
+	})
+
+	page := Page{Employees: employees}
+	if opts.Limit > 0 && len(employees) > opts.Limit {
+		page.Employees = employees[:opts.Limit]
+		page.NextPageToken = encodePageToken(fields, page.Employees[opts.Limit-1])
+	}
+	return page, nil
+}
+
+func (r *MemoryRepository) Get(ctx context.Context, id int) (models.Employee, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	employee, ok := r.employees[id]
+	if !ok {
+		return models.Employee{}, ErrNotFound
+	}
+	return employee, nil
+}
+
+func (r *MemoryRepository) GetByEmail(ctx context.Context, email string) (models.Employee, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key := models.NormalizeEmail(email)
+	for _, employee := range r.employees {
+		if key != "" && !employee.Deleted && models.NormalizeEmail(employee.Email) == key {
+			return employee, nil
+		}
+	}
+	return models.Employee{}, ErrNotFound
+}
+
+func (r *MemoryRepository) Search(ctx context.Context, opts SearchOptions) ([]models.Employee, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	employees := []models.Employee{}
+	for _, employee := range r.employees {
+		if employee.Deleted && !opts.IncludeDeleted {
+			continue
+		}
+		if matchesCriteria(employee, opts.Criteria) {
+			employees = append(employees, employee)
+		}
+	}
+	sort.Slice(employees, func(i, j int) bool { return employees[i].ID < employees[j].ID })
+	if opts.Limit > 0 && len(employees) > opts.Limit {
+		employees = employees[:opts.Limit]
+	}
+	return employees, nil
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, employee models.Employee) (models.Employee, error) {
+	if err := employee.HashPassword(); err != nil {
+		return models.Employee{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.emailTaken(employee.Email, 0) {
+		return models.Employee{}, ErrEmailTaken
+	}
+	if err := r.checkDepartment(models.Employee{}, employee); err != nil {
+		return models.Employee{}, err
+	}
+	r.lastID++
+	employee.ID = r.lastID
+	employee.Deleted = false
+	employee.DeletedAt = nil
+	employee.Version = 1
+	employee.SetSearchFields()
+	r.employees[employee.ID] = employee
+	r.record(ctx, AuditCreate, models.Employee{}, employee)
+	return employee, nil
+}
+
+func (r *MemoryRepository) CreateBatch(ctx context.Context, employees []models.Employee) ([]BatchResult, error) {
+	results := make([]BatchResult, len(employees))
+	for i, employee := range employees {
+		if err := ctx.Err(); err != nil {
+			return results[:i], err
+		}
+		results[i].Employee, results[i].Err = r.Create(ctx, employee)
+	}
+	return results, nil
+}
+
+func (r *MemoryRepository) Update(ctx context.Context, employee models.Employee) (models.Employee, error) {
+	plaintext := employee.Password
+	if err := employee.HashPassword(); err != nil {
+		return models.Employee{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.employees[employee.ID]
+	if !ok || existing.Deleted {
+		return models.Employee{}, ErrNotFound
+	}
+	if err := checkVersion(existing, employee.Version); err != nil {
+		return models.Employee{}, err
+	}
+	if r.emailTaken(employee.Email, employee.ID) {
+		return models.Employee{}, ErrEmailTaken
+	}
+	if err := r.checkDepartment(existing, employee); err != nil {
+		return models.Employee{}, err
+	}
+	keepPasswordHash(&employee, existing, plaintext)
+	employee.Deleted = false
+	employee.DeletedAt = nil
+	employee.Version = existing.Version + 1
+	employee.SetSearchFields()
+	r.employees[employee.ID] = employee
+	r.record(ctx, AuditUpdate, existing, employee)
+	return employee, nil
+}
+
+func (r *MemoryRepository) Patch(ctx context.Context, employee models.Employee, fields []string) (models.Employee, error) {
+	if err := checkPatchFields(fields); err != nil {
+		return models.Employee{}, err
+	}
+	if err := employee.HashPassword(); err != nil {
+		return models.Employee{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.employees[employee.ID]
+	if !ok || existing.Deleted {
+		return models.Employee{}, ErrNotFound
+	}
+	if err := checkVersion(existing, employee.Version); err != nil {
+		return models.Employee{}, err
+	}
+	patched := existing
+	applyPatch(&patched, employee, fields)
+	if r.emailTaken(patched.Email, patched.ID) {
+		return models.Employee{}, ErrEmailTaken
+	}
+	if err := r.checkDepartment(existing, patched); err != nil {
+		return models.Employee{}, err
+	}
+	patched.Version++
+	patched.SetSearchFields()
+	r.employees[employee.ID] = patched
+	r.record(ctx, AuditUpdate, existing, patched)
+	return patched, nil
+}
+
+func (r *MemoryRepository) Delete(ctx context.Context, id, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.employees[id]
+	if !ok || existing.Deleted {
+		return ErrNotFound
+	}
+	if err := checkVersion(existing, version); err != nil {
+		return err
+	}
+	deletedAt := time.Now().UTC()
+	employee := existing
+	employee.Deleted = true
+	employee.DeletedAt = &deletedAt
+	employee.Version++
+	r.employees[id] = employee
+	r.record(ctx, AuditDelete, existing, employee)
+	return nil
+}
+
+func (r *MemoryRepository) Restore(ctx context.Context, id int) (models.Employee, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.employees[id]
+	if !ok {
+		return models.Employee{}, ErrNotFound
+	}
+	if !existing.Deleted {
+		return models.Employee{}, ErrNotDeleted
+	}
+	employee := existing
+	employee.Deleted = false
+	employee.DeletedAt = nil
+	employee.Version++
+	r.employees[id] = employee
+	r.record(ctx, AuditRestore, existing, employee)
+	return employee, nil
+}
+
+func (r *MemoryRepository) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := 0
+	for id, employee := range r.employees {
+		if employee.Deleted && employee.DeletedAt != nil && employee.DeletedAt.Before(deletedBefore) {
+			delete(r.employees, id)
+			delete(r.versions, id)
+			r.audit = append(r.audit, newAuditEntry(ctx, AuditPurge, employee, employee))
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// RehashPasswords hashes every password that is still stored in plaintext and
+// returns how many employees were changed.
+func (r *MemoryRepository) RehashPasswords(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rehashed := 0
+	for id, employee := range r.employees {
+		if employee.Password == "" || models.IsPasswordHash(employee.Password) {
+			continue
+		}
+		if err := employee.HashPassword(); err != nil {
+			return rehashed, err
+		}
+		r.employees[id] = employee
+		rehashed++
+	}
+	return rehashed, nil
+}
+
+func (r *MemoryRepository) History(ctx context.Context, id int) ([]AuditEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.employees[id]; !ok {
+		return nil, ErrNotFound
+	}
+	entries := []AuditEntry{}
+	for _, entry := range r.audit {
+		if entry.EmployeeID == id {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (r *MemoryRepository) Version(ctx context.Context, id, version int) (models.Employee, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	existing, ok := r.employees[id]
+	if !ok {
+		return models.Employee{}, ErrNotFound
+	}
+	return r.version(existing, version)
+}
+
+func (r *MemoryRepository) Rollback(ctx context.Context, id, to, version int) (models.Employee, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.employees[id]
+	if !ok || existing.Deleted {
+		return models.Employee{}, ErrNotFound
+	}
+	if err := checkVersion(existing, version); err != nil {
+		return models.Employee{}, err
+	}
+	target, err := r.version(existing, to)
+	if err != nil {
+		return models.Employee{}, err
+	}
+	employee := existing
+	applyPatch(&employee, target, rollbackFields)
+	if r.emailTaken(employee.Email, id) {
+		return models.Employee{}, ErrEmailTaken
+	}
+	if err := r.checkDepartment(existing, employee); err != nil {
+		return models.Employee{}, err
+	}
+	employee.Version++
+	employee.SetSearchFields()
+	r.employees[id] = employee
+	r.record(ctx, AuditRollback, existing, employee)
+	return employee, nil
+}
+
+func (r *MemoryRepository) ListDepartments(ctx context.Context) ([]models.Department, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	departments := make([]models.Department, 0, len(r.departments))
+	for _, department := range r.departments {
+		departments = append(departments, department)
+	}
+	sort.Slice(departments, func(i, j int) bool { return departments[i].ID < departments[j].ID })
+	return departments, nil
+}
+
+func (r *MemoryRepository) GetDepartment(ctx context.Context, id int) (models.Department, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	department, ok := r.departments[id]
+	if !ok {
+		return models.Department{}, ErrDepartmentNotFound
+	}
+	return department, nil
+}
+
+func (r *MemoryRepository) CreateDepartment(ctx context.Context, department models.Department) (models.Department, error) {
+	normalizeDepartment(&department)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkDepartmentFields(department); err != nil {
+		return models.Department{}, err
+	}
+	r.lastDepartmentID++
+	department.ID = r.lastDepartmentID
+	r.departments[department.ID] = department
+	return department, nil
+}
+
+func (r *MemoryRepository) UpdateDepartment(ctx context.Context, department models.Department) (models.Department, error) {
+	normalizeDepartment(&department)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.departments[department.ID]; !ok {
+		return models.Department{}, ErrDepartmentNotFound
+	}
+	if err := r.checkDepartmentFields(department); err != nil {
+		return models.Department{}, err
+	}
+	r.departments[department.ID] = department
+	return department, nil
+}
+
+func (r *MemoryRepository) DeleteDepartment(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.departments[id]; !ok {
+		return ErrDepartmentNotFound
+	}
+	for _, employee := range r.employees {
+		if employee.DepartmentID == id {
+			return ErrDepartmentInUse
+		}
+	}
+	delete(r.departments, id)
+	return nil
+}
+
+func (r *MemoryRepository) Close() error {
+	return nil
+}
+
+// record appends the audit entry of a write and keeps a snapshot of the
+// version it wrote. The caller must hold r.mu.
+func (r *MemoryRepository) record(ctx context.Context, operation string, before, after models.Employee) {
+	r.audit = append(r.audit, newAuditEntry(ctx, operation, before, after))
+	if r.versions[after.ID] == nil {
+		r.versions[after.ID] = make(map[int]models.Employee)
+	}
+	r.versions[after.ID][after.Version] = snapshot(after)
+}
+
+// checkDepartment returns ErrUnknownDepartment when after is assigned to a
+// department that does not exist. Only a changed assignment is checked.
+// The caller must hold r.mu.
+func (r *MemoryRepository) checkDepartment(before, after models.Employee) error {
+	if after.DepartmentID == 0 || after.DepartmentID == before.DepartmentID {
+		return nil
+	}
+	if _, ok := r.departments[after.DepartmentID]; !ok {
+		return ErrUnknownDepartment
+	}
+	return nil
+}
+
+// checkDepartmentFields checks that the code of department is free and that
+// its head exists. The caller must hold r.mu.
+func (r *MemoryRepository) checkDepartmentFields(department models.Department) error {
+	for _, other := range r.departments {
+		if other.ID != department.ID && other.Code == department.Code {
+			return ErrDepartmentCodeTaken
+		}
+	}
+	if department.HeadEmployeeID != 0 {
+		head, ok := r.employees[department.HeadEmployeeID]
+		if !ok || head.Deleted {
+			return ErrUnknownHead
+		}
+	}
+	return nil
+}
+
+// version returns the snapshot of the given version of current. The caller
+// must hold r.mu.
+func (r *MemoryRepository) version(current models.Employee, version int) (models.Employee, error) {
+	if employee, ok := r.versions[current.ID][version]; ok {
+		return employee, nil
+	}
+	// The current version is known even without a snapshot
+	if version == current.Version {
+		return snapshot(current), nil
+	}
+	return models.Employee{}, ErrVersionNotFound
+}
+
+// matchesFilters reports whether employee satisfies every filter.
+func matchesFilters(employee models.Employee, filters []Filter) bool {
+	for _, filter := range filters {
+		if fieldValue(employee, filter.Field) != filter.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// sortKey returns the values of employee for the given ordering.
+func sortKey(fields []SortField, employee models.Employee) []interface{} {
+	key := make([]interface{}, len(fields))
+	for i, field := range fields {
+		key[i] = fieldValue(employee, field.Field)
+	}
+	return key
+}
+
+// DuplicateEmails returns the IDs of the employees that share each email,
+// ignoring case. Only emails used more than once are included.
+func (r *MemoryRepository) DuplicateEmails(ctx context.Context) (map[string][]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	employees := make([]models.Employee, 0, len(r.employees))
+	for _, employee := range r.employees {
+		employees = append(employees, employee)
+	}
+	return duplicateEmails(employees), nil
+}
+
+// emailTaken reports whether an employee other than id uses email. The
+// caller must hold r.mu.
+func (r *MemoryRepository) emailTaken(email string, id int) bool {
+	key := models.NormalizeEmail(email)
+	if key == "" {
+		return false
+	}
+	for _, employee := range r.employees {
+		if employee.ID != id && models.NormalizeEmail(employee.Email) == key {
+			return true
+		}
+	}
+	return false
+}
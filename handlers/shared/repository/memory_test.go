@@ -0,0 +1,608 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/models"
+)
+
+func TestMemoryRepositoryCreateConcurrent(t *testing.T) {
+	repo := NewMemoryRepository(models.Employee{ID: 10})
+	ctx := context.Background()
+
+	const creates = 100
+	ids := make(chan int, creates)
+	var wg sync.WaitGroup
+	for i := 0; i < creates; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			employee, err := repo.Create(ctx, models.Employee{FirstName: "Ada"})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			ids <- employee.ID
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	var got []int
+	for id := range ids {
+		got = append(got, id)
+	}
+	sort.Ints(got)
+	if len(got) != creates {
+		t.Fatalf("created %d employees, want %d", len(got), creates)
+	}
+	for i, id := range got {
+		if want := 11 + i; id != want {
+			t.Fatalf("IDs = %v, want 11..%d without gaps or duplicates", got, 10+creates)
+		}
+	}
+}
+
+func TestMemoryRepositoryCreateDoesNotReuseIDs(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	first, _ := repo.Create(ctx, models.Employee{})
+	if err := repo.Delete(ctx, first.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	second, _ := repo.Create(ctx, models.Employee{})
+	if second.ID <= first.ID {
+		t.Errorf("ID after delete = %d, want greater than %d", second.ID, first.ID)
+	}
+}
+
+func TestMemoryRepositorySoftDelete(t *testing.T) {
+	repo := NewMemoryRepository(models.Employee{ID: 1}, models.Employee{ID: 2})
+	ctx := context.Background()
+
+	if err := repo.Delete(ctx, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(ctx, 1, 0); err != ErrNotFound {
+		t.Errorf("second Delete: err = %v, want ErrNotFound", err)
+	}
+
+	visible, _ := repo.List(ctx, ListOptions{})
+	all, _ := repo.List(ctx, ListOptions{IncludeDeleted: true})
+	if len(visible.Employees) != 1 || len(all.Employees) != 2 {
+		t.Errorf("List returned %d visible and %d total employees, want 1 and 2", len(visible.Employees), len(all.Employees))
+	}
+
+	deleted, err := repo.Get(ctx, 1)
+	if err != nil || !deleted.Deleted || deleted.DeletedAt == nil {
+		t.Fatalf("Get(1) = %+v, %v; want a soft-deleted employee", deleted, err)
+	}
+
+	restored, err := repo.Restore(ctx, 1)
+	if err != nil || restored.Deleted || restored.DeletedAt != nil {
+		t.Fatalf("Restore(1) = %+v, %v", restored, err)
+	}
+	if _, err := repo.Restore(ctx, 1); err != ErrNotDeleted {
+		t.Errorf("second Restore: err = %v, want ErrNotDeleted", err)
+	}
+}
+
+func TestMemoryRepositoryPurge(t *testing.T) {
+	repo := NewMemoryRepository(models.Employee{ID: 1}, models.Employee{ID: 2})
+	ctx := context.Background()
+	if err := repo.Delete(ctx, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, _ := repo.Purge(ctx, time.Now().Add(-time.Hour)); n != 0 {
+		t.Errorf("Purge before the retention period removed %d employees", n)
+	}
+	if n, _ := repo.Purge(ctx, time.Now().Add(time.Hour)); n != 1 {
+		t.Errorf("Purge removed %d employees, want 1", n)
+	}
+	if _, err := repo.Get(ctx, 1); err != ErrNotFound {
+		t.Errorf("Get(1) after purge: err = %v, want ErrNotFound", err)
+	}
+	if versions := repo.versions[1]; len(versions) != 0 {
+		t.Errorf("purge kept %d versions of employee 1", len(versions))
+	}
+	if _, err := repo.Get(ctx, 2); err != nil {
+		t.Errorf("Get(2) after purge: %v", err)
+	}
+}
+
+func TestMemoryRepositoryGetByEmail(t *testing.T) {
+	repo := NewMemoryRepository(
+		models.Employee{ID: 1, FirstName: "Ada", Email: "ada@example.com"},
+		models.Employee{ID: 2, FirstName: "Alan", Email: "alan@example.com", Deleted: true},
+	)
+	ctx := context.Background()
+
+	for _, email := range []string{"ada@example.com", "Ada@Example.COM", " ada@example.com "} {
+		if employee, err := repo.GetByEmail(ctx, email); err != nil || employee.ID != 1 {
+			t.Errorf("GetByEmail(%q) = %d, %v, want employee 1", email, employee.ID, err)
+		}
+	}
+	for _, email := range []string{"alan@example.com", "grace@example.com", ""} {
+		if _, err := repo.GetByEmail(ctx, email); err != ErrNotFound {
+			t.Errorf("GetByEmail(%q): err = %v, want ErrNotFound", email, err)
+		}
+	}
+}
+
+func TestMemoryRepositorySearch(t *testing.T) {
+	repo := NewMemoryRepository(
+		models.Employee{ID: 1, FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Role: "manager"},
+		models.Employee{ID: 2, FirstName: "Adam", LastName: "Smith", Email: "adam@example.com", Role: "employee"},
+		models.Employee{ID: 3, FirstName: "Alan", LastName: "Turing", Email: "alan@example.com", Role: "manager"},
+		models.Employee{ID: 4, FirstName: "Adele", LastName: "Goldberg", Email: "adele@example.com", Role: "manager", Deleted: true},
+	)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		criteria []SearchCriterion
+		want     []int
+	}{
+		{"exact", []SearchCriterion{{Field: "firstName", Value: "Ada"}}, []int{1}},
+		{"exact is case-sensitive", []SearchCriterion{{Field: "firstName", Value: "ada"}}, nil},
+		{"prefix ignores case", []SearchCriterion{{Field: "firstName", Value: "AD", Prefix: true}}, []int{1, 2}},
+		{"combined", []SearchCriterion{
+			{Field: "firstName", Value: "a", Prefix: true},
+			{Field: "role", Value: "manager"},
+		}, []int{1, 3}},
+		{"two prefixes", []SearchCriterion{
+			{Field: "firstName", Value: "a", Prefix: true},
+			{Field: "lastName", Value: "tu", Prefix: true},
+		}, []int{3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			employees, err := repo.Search(ctx, SearchOptions{Criteria: tt.criteria})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []int
+			for _, employee := range employees {
+				got = append(got, employee.ID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("IDs = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("IDs = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	if _, err := repo.Search(ctx, SearchOptions{}); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Search without criteria: err = %v, want ErrInvalidQuery", err)
+	}
+}
+
+func TestMemoryRepositoryHashesPasswords(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	created, err := repo.Create(ctx, models.Employee{Password: "secret1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := repo.Get(ctx, created.ID)
+	if stored.Password == "secret1" || !stored.CheckPassword("secret1") {
+		t.Fatalf("created password stored as %q, want a bcrypt hash of secret1", stored.Password)
+	}
+
+	stored.Password = "secret2"
+	if _, err := repo.Update(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+	updated, _ := repo.Get(ctx, created.ID)
+	if !models.IsPasswordHash(updated.Password) || !updated.CheckPassword("secret2") {
+		t.Fatalf("updated password stored as %q, want a bcrypt hash of secret2", updated.Password)
+	}
+
+	// Updating with the stored hash must not hash it a second time.
+	if _, err := repo.Update(ctx, updated); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := repo.Get(ctx, created.ID); again.Password != updated.Password {
+		t.Errorf("password rehashed on update with an existing hash")
+	}
+}
+
+func TestMemoryRepositoryRehashPasswords(t *testing.T) {
+	repo := NewMemoryRepository(
+		models.Employee{ID: 1, Password: "plaintext"},
+		models.Employee{ID: 2},
+		models.Employee{ID: 3, Password: "other", Deleted: true},
+	)
+	ctx := context.Background()
+
+	rehashed, err := repo.RehashPasswords(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rehashed != 2 {
+		t.Errorf("rehashed %d passwords, want 2", rehashed)
+	}
+	for id, password := range map[int]string{1: "plaintext", 3: "other"} {
+		employee, _ := repo.Get(ctx, id)
+		if !employee.CheckPassword(password) {
+			t.Errorf("employee %d password = %q, want a bcrypt hash of %q", id, employee.Password, password)
+		}
+	}
+
+	if rehashed, _ := repo.RehashPasswords(ctx); rehashed != 0 {
+		t.Errorf("second run rehashed %d passwords, want 0", rehashed)
+	}
+}
+
+func TestMemoryRepositoryPatch(t *testing.T) {
+	repo := NewMemoryRepository(models.Employee{ID: 1, FirstName: "Ada", LastName: "Lovelace", Role: "employee"})
+	ctx := context.Background()
+
+	// Only the named fields are written, whatever else the argument holds.
+	patched, err := repo.Patch(ctx, models.Employee{ID: 1, FirstName: "Augusta", Role: "manager"}, []string{"role"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	employee, _ := repo.Get(ctx, 1)
+	if employee.Role != "manager" || employee.RoleLower != "manager" || employee.FirstName != "Ada" || employee.LastName != "Lovelace" {
+		t.Errorf("patched employee = %+v, want only the role changed", employee)
+	}
+	if patched != employee {
+		t.Errorf("Patch returned %+v, want the stored %+v", patched, employee)
+	}
+
+	if _, err := repo.Patch(ctx, models.Employee{ID: 1, Deleted: true}, []string{"deleted"}); !errors.Is(err, ErrReadOnlyField) {
+		t.Errorf("Patch(deleted): err = %v, want ErrReadOnlyField", err)
+	}
+	if _, err := repo.Patch(ctx, models.Employee{ID: 2}, []string{"role"}); err != ErrNotFound {
+		t.Errorf("Patch(2): err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryRepositoryVersions(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	created, _ := repo.Create(ctx, models.Employee{FirstName: "Ada"})
+	if created.Version != 1 {
+		t.Fatalf("created version = %d, want 1", created.Version)
+	}
+
+	stale := created
+	created.LastName = "Lovelace"
+	updated, err := repo.Update(ctx, created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Version != 2 || updated.LastName != "Lovelace" {
+		t.Errorf("updated employee = %+v, want version 2 with the new last name", updated)
+	}
+	if _, err := repo.Update(ctx, stale); err != ErrVersionMismatch {
+		t.Errorf("Update at a stale version: err = %v, want ErrVersionMismatch", err)
+	}
+	if _, err := repo.Patch(ctx, stale, []string{"firstName"}); err != ErrVersionMismatch {
+		t.Errorf("Patch at a stale version: err = %v, want ErrVersionMismatch", err)
+	}
+	if err := repo.Delete(ctx, created.ID, 1); err != ErrVersionMismatch {
+		t.Errorf("Delete at a stale version: err = %v, want ErrVersionMismatch", err)
+	}
+
+	if err := repo.Delete(ctx, created.ID, 2); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := repo.Restore(ctx, created.ID)
+	if err != nil || restored.Version != 4 || restored.LastName != "Lovelace" {
+		t.Errorf("Restore = %+v, %v; want version 4 after create, update, delete and restore", restored, err)
+	}
+}
+
+func TestMemoryRepositoryUniqueEmails(t *testing.T) {
+	repo := NewMemoryRepository(
+		models.Employee{ID: 1, Email: "ada@example.com"},
+		models.Employee{ID: 2, Email: "alan@example.com"},
+	)
+	ctx := context.Background()
+
+	if _, err := repo.Create(ctx, models.Employee{Email: "Ada@Example.com"}); err != ErrEmailTaken {
+		t.Errorf("Create with a taken email: err = %v, want ErrEmailTaken", err)
+	}
+	if _, err := repo.Update(ctx, models.Employee{ID: 2, Email: "ADA@example.com"}); err != ErrEmailTaken {
+		t.Errorf("Update to a taken email: err = %v, want ErrEmailTaken", err)
+	}
+	if _, err := repo.Patch(ctx, models.Employee{ID: 2, Email: "ada@example.com"}, []string{"email"}); err != ErrEmailTaken {
+		t.Errorf("Patch to a taken email: err = %v, want ErrEmailTaken", err)
+	}
+	// Changing the case of one's own email is not a conflict
+	if _, err := repo.Patch(ctx, models.Employee{ID: 2, Email: "Alan@example.com"}, []string{"email"}); err != nil {
+		t.Errorf("Patch of the own email: %v", err)
+	}
+
+	// Soft-deleted employees keep their email
+	if err := repo.Delete(ctx, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Create(ctx, models.Employee{Email: "ada@example.com"}); err != ErrEmailTaken {
+		t.Errorf("Create with the email of a deleted employee: err = %v, want ErrEmailTaken", err)
+	}
+}
+
+func TestMemoryRepositoryDuplicateEmails(t *testing.T) {
+	repo := NewMemoryRepository(
+		models.Employee{ID: 3, Email: "ada@example.com"},
+		models.Employee{ID: 1, Email: "Ada@Example.com "},
+		models.Employee{ID: 2, Email: "alan@example.com"},
+		models.Employee{ID: 4},
+		models.Employee{ID: 5},
+	)
+
+	duplicates, err := repo.DuplicateEmails(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := duplicates["ada@example.com"]; len(duplicates) != 1 || len(ids) != 2 || ids[0] != 1 || ids[1] != 3 {
+		t.Errorf("DuplicateEmails = %v, want only ada@example.com used by 1 and 3", duplicates)
+	}
+}
+
+func TestMemoryRepositoryCreateBatch(t *testing.T) {
+	repo := NewMemoryRepository(models.Employee{ID: 1, Email: "ada@example.com"})
+	ctx := context.Background()
+
+	results, err := repo.CreateBatch(ctx, []models.Employee{
+		{Email: "alan@example.com"},
+		{Email: "ADA@example.com"},
+		{Email: "grace@example.com"},
+		{Email: "Alan@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want one per employee", len(results))
+	}
+	for i, want := range []error{nil, ErrEmailTaken, nil, ErrEmailTaken} {
+		if results[i].Err != want {
+			t.Errorf("result %d: err = %v, want %v", i, results[i].Err, want)
+		}
+	}
+	if results[0].Employee.ID != 2 || results[2].Employee.ID != 3 || results[2].Employee.Version != 1 {
+		t.Errorf("created %+v and %+v, want IDs 2 and 3 at version 1", results[0].Employee, results[2].Employee)
+	}
+}
+
+func TestMemoryRepositoryAudit(t *testing.T) {
+	repo := NewMemoryRepository()
+	admin := auth.Identity{EmployeeID: 7, Email: "admin@example.com", Role: "admin"}
+	ctx := auth.ContextWithIdentity(context.Background(), admin)
+
+	created, err := repo.Create(ctx, models.Employee{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Password: "secret1", Role: "employee"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sending the current password again is not a password change
+	update := created
+	update.LastName = "King"
+	update.Password = "secret1"
+	if _, err := repo.Update(ctx, update); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Patch(ctx, models.Employee{ID: created.ID, Password: "secret2"}, []string{"password"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(context.Background(), created.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Restore(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := repo.History(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		operation string
+		version   int
+		fields    string
+	}{
+		{AuditCreate, 1, "firstName,lastName,email,password,role"},
+		{AuditUpdate, 2, "lastName"},
+		{AuditUpdate, 3, "password"},
+		{AuditDelete, 4, "deleted,deletedAt"},
+		{AuditRestore, 5, "deleted,deletedAt"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("history = %+v, want %d entries", entries, len(want))
+	}
+	for i, w := range want {
+		entry := entries[i]
+		var fields []string
+		for _, change := range entry.Changes {
+			fields = append(fields, change.Field)
+		}
+		if entry.Operation != w.operation || entry.Version != w.version || strings.Join(fields, ",") != w.fields {
+			t.Errorf("entry %d = %s v%d of %v, want %s v%d of %s", i, entry.Operation, entry.Version, fields, w.operation, w.version, w.fields)
+		}
+	}
+	if entries[0].Actor.EmployeeID != 7 || entries[0].Actor.Role != "admin" || entries[3].Actor != (Actor{}) {
+		t.Errorf("actors = %+v and %+v, want the admin and none", entries[0].Actor, entries[3].Actor)
+	}
+	if change := entries[1].Changes[0]; change.Before != "Lovelace" || change.After != "King" {
+		t.Errorf("lastName change = %+v", change)
+	}
+	if change := entries[2].Changes[0]; change.Before != Redacted || change.After != Redacted {
+		t.Errorf("password change = %+v, want redacted values", change)
+	}
+
+	if _, err := repo.History(ctx, 99); err != ErrNotFound {
+		t.Errorf("History of an unknown employee: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryRepositoryRollback(t *testing.T) {
+	repo := NewMemoryRepository(models.Employee{ID: 9, FirstName: "Grace", LastName: "Hopper", Email: "grace@example.com", Password: "secret1", Role: "employee", Version: 1})
+	ctx := context.Background()
+
+	created, err := repo.Create(ctx, models.Employee{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Password: "secret1", Role: "employee"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Patch(ctx, models.Employee{ID: created.ID, LastName: "King", Email: "countess@example.com", Password: "secret2", Role: "manager"}, []string{"lastName", "email", "password", "role"}); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := repo.Version(ctx, created.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.LastName != "Lovelace" || first.Version != 1 || first.Password != "" {
+		t.Errorf("version 1 = %+v, want the created employee without its password", first)
+	}
+	// Seeded employees have no snapshots, but their current version is known
+	if seeded, err := repo.Version(ctx, 9, 1); err != nil || seeded.FirstName != "Grace" || seeded.Password != "" {
+		t.Errorf("current version of a seeded employee = %+v, %v", seeded, err)
+	}
+	for _, version := range []int{0, 3} {
+		if _, err := repo.Version(ctx, created.ID, version); err != ErrVersionNotFound {
+			t.Errorf("Version(%d): err = %v, want ErrVersionNotFound", version, err)
+		}
+	}
+
+	if _, err := repo.Rollback(ctx, created.ID, 1, 1); err != ErrVersionMismatch {
+		t.Errorf("Rollback at a stale version: err = %v, want ErrVersionMismatch", err)
+	}
+	rolledBack, err := repo.Rollback(ctx, created.ID, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rolledBack.LastName != "Lovelace" || rolledBack.Email != "ada@example.com" || rolledBack.Role != "employee" || rolledBack.Version != 3 {
+		t.Errorf("rolled back employee = %+v, want version 1 again as version 3", rolledBack)
+	}
+	if !rolledBack.CheckPassword("secret2") {
+		t.Error("Rollback restored the old password")
+	}
+	if stored, _ := repo.Get(ctx, created.ID); stored.LastNameLower != "lovelace" {
+		t.Errorf("stored employee = %+v, want the search fields updated", stored)
+	}
+	entries, _ := repo.History(ctx, created.ID)
+	if last := entries[len(entries)-1]; last.Operation != AuditRollback || last.Version != 3 {
+		t.Errorf("last audit entry = %+v, want the rollback", last)
+	}
+
+	// The old email may belong to someone else by now
+	if _, err := repo.Patch(ctx, models.Employee{ID: created.ID, Email: "ada.king@example.com"}, []string{"email"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Patch(ctx, models.Employee{ID: 9, Email: "ada@example.com"}, []string{"email"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Rollback(ctx, created.ID, 1, 0); err != ErrEmailTaken {
+		t.Errorf("Rollback to a taken email: err = %v, want ErrEmailTaken", err)
+	}
+
+	if err := repo.Delete(ctx, created.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Rollback(ctx, created.ID, 1, 0); err != ErrNotFound {
+		t.Errorf("Rollback of a deleted employee: err = %v, want ErrNotFound", err)
+	}
+	if _, err := repo.Version(ctx, 99, 1); err != ErrNotFound {
+		t.Errorf("Version of an unknown employee: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryRepositoryDepartments(t *testing.T) {
+	repo := NewMemoryRepository(
+		models.Employee{ID: 1, FirstName: "Ada", Email: "ada@example.com", Role: "manager"},
+		models.Employee{ID: 2, FirstName: "Alan", Email: "alan@example.com", Role: "employee"},
+	)
+	ctx := context.Background()
+
+	engineering, err := repo.CreateDepartment(ctx, models.Department{Name: "Engineering", Code: " eng ", CostCenter: "CC-100", HeadEmployeeID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if engineering.ID != 1 || engineering.Code != "ENG" {
+		t.Errorf("created department = %+v, want ID 1 and code ENG", engineering)
+	}
+	if _, err := repo.CreateDepartment(ctx, models.Department{Name: "Engines", Code: "Eng"}); err != ErrDepartmentCodeTaken {
+		t.Errorf("create with a taken code: err = %v, want ErrDepartmentCodeTaken", err)
+	}
+	if _, err := repo.CreateDepartment(ctx, models.Department{Name: "Sales", Code: "SAL", HeadEmployeeID: 9}); err != ErrUnknownHead {
+		t.Errorf("create with an unknown head: err = %v, want ErrUnknownHead", err)
+	}
+	sales, err := repo.CreateDepartment(ctx, models.Department{Name: "Sales", Code: "SAL"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sales.HeadEmployeeID = 2
+	if sales, err = repo.UpdateDepartment(ctx, sales); err != nil || sales.HeadEmployeeID != 2 {
+		t.Errorf("UpdateDepartment = %+v, %v", sales, err)
+	}
+	if _, err := repo.UpdateDepartment(ctx, models.Department{ID: 9, Name: "HR", Code: "HR"}); err != ErrDepartmentNotFound {
+		t.Errorf("update of an unknown department: err = %v, want ErrDepartmentNotFound", err)
+	}
+
+	// Employees may only be assigned to existing departments
+	if _, err := repo.Create(ctx, models.Employee{FirstName: "Grace", Email: "grace@example.com", DepartmentID: 9}); err != ErrUnknownDepartment {
+		t.Errorf("create in an unknown department: err = %v, want ErrUnknownDepartment", err)
+	}
+	if _, err := repo.Patch(ctx, models.Employee{ID: 2, DepartmentID: 9}, []string{"departmentId"}); err != ErrUnknownDepartment {
+		t.Errorf("patch to an unknown department: err = %v, want ErrUnknownDepartment", err)
+	}
+	for _, id := range []int{1, 2} {
+		if _, err := repo.Patch(ctx, models.Employee{ID: id, DepartmentID: engineering.ID}, []string{"departmentId"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, err := repo.List(ctx, ListOptions{Filters: []Filter{{Field: "departmentId", Value: engineering.ID}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Employees) != 2 {
+		t.Errorf("employees of engineering = %+v, want 2", page.Employees)
+	}
+
+	// Departments with employees, even deleted ones, cannot be deleted
+	if err := repo.Delete(ctx, 2, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Patch(ctx, models.Employee{ID: 1, DepartmentID: sales.ID}, []string{"departmentId"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteDepartment(ctx, engineering.ID); err != ErrDepartmentInUse {
+		t.Errorf("delete with a deleted member: err = %v, want ErrDepartmentInUse", err)
+	}
+	if _, err := repo.Purge(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteDepartment(ctx, engineering.ID); err != nil {
+		t.Errorf("delete without members: %v", err)
+	}
+	if _, err := repo.GetDepartment(ctx, engineering.ID); err != ErrDepartmentNotFound {
+		t.Errorf("GetDepartment after delete: err = %v, want ErrDepartmentNotFound", err)
+	}
+
+	departments, err := repo.ListDepartments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(departments) != 1 || departments[0].Code != "SAL" {
+		t.Errorf("departments = %+v, want only sales", departments)
+	}
+}
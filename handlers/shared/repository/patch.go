@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"fmt"
+
+	"example.com/task3gcp/shared/domain"
+	"example.com/task3gcp/shared/models"
+)
+
+// ErrReadOnlyField is returned when a patch changes a field that Patch
+// cannot write, such as id or deleted.
+var ErrReadOnlyField = domain.New(domain.Validation, "field cannot be patched")
+
+// patchPaths maps the JSON names of the fields Patch writes to their
+// Firestore fields.
+var patchPaths = map[string]string{
+	"firstName":    "FirstName",
+	"lastName":     "LastName",
+	"email":        "Email",
+	"password":     "Password",
+	"role":         "Role",
+	"departmentId": "DepartmentID",
+}
+
+// checkPatchFields returns ErrReadOnlyField for the first field Patch
+// cannot write.
+func checkPatchFields(fields []string) error {
+	for _, field := range fields {
+		if _, ok := patchPaths[field]; !ok {
+			return fmt.Errorf("%w: %s", ErrReadOnlyField, field)
+		}
+	}
+	return nil
+}
+
+// patchValue returns the value of a patchable field of employee.
+func patchValue(employee models.Employee, field string) interface{} {
+	switch field {
+	case "firstName":
+		return employee.FirstName
+	case "lastName":
+		return employee.LastName
+	case "email":
+		return employee.Email
+	case "password":
+		return employee.Password
+	case "role":
+		return employee.Role
+	case "departmentId":
+		return employee.DepartmentID
+	}
+	return nil
+}
+
+// applyPatch copies the named fields of src to dst.
+func applyPatch(dst *models.Employee, src models.Employee, fields []string) {
+	for _, field := range fields {
+		switch field {
+		case "firstName":
+			dst.FirstName = src.FirstName
+		case "lastName":
+			dst.LastName = src.LastName
+		case "email":
+			dst.Email = src.Email
+		case "password":
+			dst.Password = src.Password
+		case "role":
+			dst.Role = src.Role
+		case "departmentId":
+			dst.DepartmentID = src.DepartmentID
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"example.com/task3gcp/shared/domain"
+	"example.com/task3gcp/shared/models"
+)
+
+var (
+	// ErrNotFound is returned when no employee matches the requested ID.
+	ErrNotFound = domain.New(domain.NotFound, "employee not found")
+
+	// ErrNotDeleted is returned when restoring an employee that is not deleted.
+	ErrNotDeleted = domain.New(domain.Conflict, "employee is not deleted")
+
+	// ErrEmailTaken is returned when an employee would get the email of
+	// another employee, ignoring case (see models.NormalizeEmail).
+	ErrEmailTaken = domain.New(domain.Conflict, "email is already in use")
+
+	// ErrVersionMismatch is returned by writes conditioned on a version the
+	// employee no longer has.
+	ErrVersionMismatch = domain.New(domain.PreconditionFailed, "employee was changed by another request")
+)
+
+// EmployeeRepository is the storage used by the employee handlers. Every
+// write increments the employee's Version, starting at 1 on Create. Emails
+// are unique: Create, Update and Patch return ErrEmailTaken rather than give
+// an employee the email of another one, including soft-deleted ones. They
+// return ErrUnknownDepartment rather than assign an employee to a department
+// that does not exist. Writes given a non-zero version fail with
+// ErrVersionMismatch unless it is still the stored one; zero writes
+// unconditionally. Every write also appends an AuditEntry to the employee's
+// audit log, naming the caller from the context's auth.Identity, and keeps a
+// snapshot of the new version.
+type EmployeeRepository interface {
+	// List returns one page of the employees matching opts, ordered by
+	// opts.OrderBy and then by ID.
+	List(ctx context.Context, opts ListOptions) (Page, error)
+
+	// Get returns the employee with the given ID, including soft-deleted
+	// employees; callers check Employee.Deleted.
+	Get(ctx context.Context, id int) (models.Employee, error)
+
+	// GetByEmail returns the employee, not soft-deleted, whose email equals
+	// email ignoring case and surrounding spaces (see models.NormalizeEmail).
+	GetByEmail(ctx context.Context, email string) (models.Employee, error)
+
+	// Search returns the employees matching every criterion in opts, ordered
+	// by ID.
+	Search(ctx context.Context, opts SearchOptions) ([]models.Employee, error)
+
+	// Create stores a new employee and returns it with its ID set. IDs are
+	// unique and increase monotonically, even under concurrent creates. A
+	// plaintext password is replaced by its bcrypt hash before it is stored.
+	Create(ctx context.Context, employee models.Employee) (models.Employee, error)
+
+	// CreateBatch creates many employees at once, for imports. Unlike
+	// Create it is not atomic: each result reports whether its employee was
+	// stored, in the order of employees, and an employee whose email is
+	// taken, also by an earlier one in the batch, fails with ErrEmailTaken
+	// without affecting the others. The error is for failures of the whole
+	// batch.
+	CreateBatch(ctx context.Context, employees []models.Employee) ([]BatchResult, error)
+
+	// Update replaces the employee that has the same ID, conditioned on
+	// employee.Version. Soft-deleted employees must be restored before they
+	// can be updated. Like Create, it stores only the bcrypt hash of the
+	// password. It returns the stored employee, with its new version.
+	Update(ctx context.Context, employee models.Employee) (models.Employee, error)
+
+	// Patch writes only the named fields of employee, given by their JSON
+	// names, to the employee with the same ID, conditioned on
+	// employee.Version. Only firstName, lastName, email, password, role
+	// and departmentId can be patched; other fields yield
+	// ErrReadOnlyField. Like Update, it stores only the bcrypt hash of the
+	// password and returns the stored employee.
+	Patch(ctx context.Context, employee models.Employee, fields []string) (models.Employee, error)
+
+	// Delete soft-deletes the employee with the given ID and version by
+	// setting Deleted and DeletedAt.
+	Delete(ctx context.Context, id, version int) error
+
+	// Restore undoes a soft delete and returns the restored employee.
+	Restore(ctx context.Context, id int) (models.Employee, error)
+
+	// Purge permanently removes employees that were soft-deleted before the
+	// given time, with their version snapshots, and returns how many were
+	// removed. Employees restored while it runs are kept. The audit log of a
+	// purged employee is kept and ends with the purge.
+	Purge(ctx context.Context, deletedBefore time.Time) (int, error)
+
+	// History returns the audit log of the employee with the given ID, oldest
+	// entry first. Soft-deleted employees keep theirs; purged employees are
+	// not found.
+	History(ctx context.Context, id int) ([]AuditEntry, error)
+
+	// Version returns the employee with the given ID as it was at the given
+	// version, without its password. Versions written before snapshots were
+	// kept, other than the current one, yield ErrVersionNotFound.
+	Version(ctx context.Context, id, version int) (models.Employee, error)
+
+	// Rollback makes the firstName, lastName, email, role and departmentId
+	// of version to current again, as a new version, and returns the
+	// employee. It is conditioned on version like Update, and like Update it
+	// fails with ErrNotFound for soft-deleted employees, ErrEmailTaken when
+	// the old email is now used by another employee and
+	// ErrUnknownDepartment when the old department no longer exists.
+	Rollback(ctx context.Context, id, to, version int) (models.Employee, error)
+
+	// Close releases any resources held by the repository.
+	Close() error
+}
+
+// BatchResult is the outcome of creating one employee in CreateBatch.
+type BatchResult struct {
+	// Employee is the stored employee, with its ID set, when Err is nil.
+	Employee models.Employee
+	Err      error
+}
+
+// checkVersion returns ErrVersionMismatch when a write conditioned on version
+// cannot be applied to stored.
+func checkVersion(stored models.Employee, version int) error {
+	if version != 0 && version != stored.Version {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+// keepPasswordHash keeps the stored hash when an update sends the password
+// existing already has, which a full replacement must include. Hashing it
+// again would produce a new hash and record a password change that did not
+// happen.
+func keepPasswordHash(employee *models.Employee, existing models.Employee, plaintext string) {
+	if plaintext != "" && !models.IsPasswordHash(plaintext) && existing.CheckPassword(plaintext) {
+		employee.Password = existing.Password
+	}
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"example.com/task3gcp/shared/models"
+)
+
+// SearchCriterion matches one employee field against a value.
+type SearchCriterion struct {
+	// Field is the JSON name of a searchable field: firstName, lastName,
+	// email or role.
+	Field string
+	Value string
+
+	// Prefix matches values that start with Value, ignoring case. Otherwise
+	// the field must equal Value exactly.
+	Prefix bool
+}
+
+// SearchOptions controls which employees Search returns.
+type SearchOptions struct {
+	// Criteria are combined with AND; at least one is required.
+	Criteria []SearchCriterion
+
+	// IncludeDeleted also returns soft-deleted employees.
+	IncludeDeleted bool
+
+	// Limit caps the number of results; zero returns every match.
+	Limit int
+}
+
+// searchFields maps the searchable JSON field names to the Firestore field
+// used for exact matches and the lowercase field used for prefix matches.
+var searchFields = map[string]struct{ exact, lower string }{
+	"firstName": {"FirstName", "FirstNameLower"},
+	"lastName":  {"LastName", "LastNameLower"},
+	"email":     {"Email", "EmailLower"},
+	"role":      {"Role", "RoleLower"},
+}
+
+// SearchOptionsFromQuery parses the search query parameters. Criteria come
+// either from a field/value pair (field names are case-insensitive, so both
+// FirstName and firstName work) or from parameters named after the fields,
+// e.g. firstName=ad&role=manager. match=prefix switches every criterion to
+// case-insensitive prefix matching; the default is match=exact.
+func SearchOptionsFromQuery(values url.Values) (SearchOptions, error) {
+	var opts SearchOptions
+
+	prefix := false
+	switch values.Get("match") {
+	case "", "exact":
+	case "prefix":
+		prefix = true
+	default:
+		return SearchOptions{}, fmt.Errorf("%w: match must be exact or prefix", ErrInvalidQuery)
+	}
+
+	if field := values.Get("field"); field != "" {
+		name, ok := searchFieldName(field)
+		if !ok {
+			return SearchOptions{}, fmt.Errorf("%w: cannot search by %q", ErrInvalidQuery, field)
+		}
+		opts.Criteria = append(opts.Criteria, SearchCriterion{Field: name, Value: values.Get("value"), Prefix: prefix})
+	}
+	for _, name := range []string{"firstName", "lastName", "email", "role"} {
+		if value, ok := values[name]; ok {
+			opts.Criteria = append(opts.Criteria, SearchCriterion{Field: name, Value: value[0], Prefix: prefix})
+		}
+	}
+
+	if value := values.Get("includeDeleted"); value != "" {
+		includeDeleted, err := strconv.ParseBool(value)
+		if err != nil {
+			return SearchOptions{}, fmt.Errorf("%w: includeDeleted must be true or false", ErrInvalidQuery)
+		}
+		opts.IncludeDeleted = includeDeleted
+	}
+
+	if value := values.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > MaxPageSize {
+			return SearchOptions{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxPageSize)
+		}
+		opts.Limit = limit
+	}
+
+	return opts, opts.validate()
+}
+
+// searchFieldName returns the JSON name of a searchable field, ignoring case.
+func searchFieldName(field string) (string, bool) {
+	for name := range searchFields {
+		if strings.EqualFold(name, field) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func (opts SearchOptions) validate() error {
+	if len(opts.Criteria) == 0 {
+		return fmt.Errorf("%w: at least one search field is required", ErrInvalidQuery)
+	}
+	for _, criterion := range opts.Criteria {
+		if _, ok := searchFields[criterion.Field]; !ok {
+			return fmt.Errorf("%w: cannot search by %q", ErrInvalidQuery, criterion.Field)
+		}
+		if criterion.Value == "" {
+			return fmt.Errorf("%w: value for %s must not be empty", ErrInvalidQuery, criterion.Field)
+		}
+	}
+	if opts.Limit < 0 || opts.Limit > MaxPageSize {
+		return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxPageSize)
+	}
+	return nil
+}
+
+// matchesCriteria reports whether employee satisfies every criterion.
+func matchesCriteria(employee models.Employee, criteria []SearchCriterion) bool {
+	for _, criterion := range criteria {
+		value, _ := fieldValue(employee, criterion.Field).(string)
+		if criterion.Prefix {
+			if !strings.HasPrefix(strings.ToLower(value), strings.ToLower(criterion.Value)) {
+				return false
+			}
+		} else if value != criterion.Value {
+			return false
+		}
+	}
+	return true
+}
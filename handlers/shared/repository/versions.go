@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+
+	"cloud.google.com/go/firestore"
+	"example.com/task3gcp/shared/domain"
+	"example.com/task3gcp/shared/models"
+)
+
+// versionsCollection is the subcollection of every employee document that
+// holds a snapshot of each of its versions, keyed by version number.
+const versionsCollection = "employee_versions"
+
+// AuditRollback is the operation recorded for Rollback.
+const AuditRollback = "rollback"
+
+// ErrVersionNotFound is returned for a version an employee never had, or
+// whose snapshot was not kept because it was written before snapshots were.
+var ErrVersionNotFound = domain.New(domain.NotFound, "employee version not found")
+
+// rollbackFields are the fields Rollback restores. The password is kept, so a
+// rollback never brings back a password that was changed, and the ID,
+// version and deletion state belong to the current record.
+var rollbackFields = []string{"firstName", "lastName", "email", "role", "departmentId"}
+
+// snapshot returns employee as kept in its version history: without the
+// password hash, which versions are never read back for.
+func snapshot(employee models.Employee) models.Employee {
+	employee.Password = ""
+	return employee
+}
+
+// versionRef returns the snapshot of the given version of the employee
+// document.
+func versionRef(employeeRef *firestore.DocumentRef, version int) *firestore.DocumentRef {
+	return employeeRef.Collection(versionsCollection).Doc(strconv.Itoa(version))
+}
+
+// deleteVersions queues the deletion of every version snapshot of the
+// employee document, as when the employee is purged.
+func deleteVersions(ctx context.Context, writer *firestore.BulkWriter, employeeRef *firestore.DocumentRef) error {
+	refs, err := employeeRef.Collection(versionsCollection).DocumentRefs(ctx).GetAll()
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		if _, err := writer.Delete(ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,393 @@
+package utils
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"example.com/task3gcp/shared/domain"
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Firestore is the process-wide client pool used by every handler. A Cloud
+// Function instance serves many requests, so the client and its gRPC
+// connection are kept between invocations.
+var Firestore = NewClientPool(CreateFirestoreClient)
+
+// OpenRepository returns a repository backed by the shared Firestore client.
+// Closing the repository returns the client to the pool instead of closing it.
+func OpenRepository() (repository.EmployeeRepository, error) {
+	return Firestore.Repository(context.Background())
+}
+
+// OpenDepartmentRepository returns a department repository backed by the
+// shared Firestore client, like OpenRepository.
+func OpenDepartmentRepository() (repository.DepartmentRepository, error) {
+	return Firestore.Departments(context.Background())
+}
+
+// ErrPoolClosed is returned by a ClientPool after Shutdown.
+var ErrPoolClosed = domain.New(domain.Unavailable, "firestore client pool is shut down")
+
+// DefaultHealthCheckInterval is how long a client is used without a health
+// check.
+const DefaultHealthCheckInterval = time.Minute
+
+// ClientPool lazily opens one Firestore client and hands it out to every
+// caller. The client is health-checked when it has not been checked for
+// HealthCheckInterval and is replaced after an error that leaves it unusable.
+// A replaced client is closed once the last repository using it is closed.
+type ClientPool struct {
+	// HealthCheckInterval is how often the client is checked before it is
+	// handed out; zero disables health checks.
+	HealthCheckInterval time.Duration
+
+	open func() (*firestore.Client, error)
+	ping func(ctx context.Context, client *firestore.Client) error
+
+	mu       sync.Mutex
+	current  *pooledClient
+	closed   bool
+	inUse    sync.WaitGroup
+	shutdown sync.Once
+}
+
+// pooledClient counts the repositories using a client so that a replaced
+// client is only closed when nobody uses it any more.
+type pooledClient struct {
+	client  *firestore.Client
+	checked time.Time
+	users   int
+	retired bool
+}
+
+// NewClientPool returns a pool that opens clients with open.
+func NewClientPool(open func() (*firestore.Client, error)) *ClientPool {
+	return &ClientPool{
+		HealthCheckInterval: DefaultHealthCheckInterval,
+		open:                open,
+		ping:                ping,
+	}
+}
+
+// Repository returns a repository backed by the pool's client, opening or
+// replacing the client first if needed. The caller must close the repository.
+func (p *ClientPool) Repository(ctx context.Context) (repository.EmployeeRepository, error) {
+	pc, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pooledRepository{
+		EmployeeRepository: repository.NewFirestoreRepository(pc.client),
+		pool:               p,
+		client:             pc,
+	}, nil
+}
+
+// Departments returns a department repository backed by the pool's client,
+// like Repository. The caller must close the repository.
+func (p *ClientPool) Departments(ctx context.Context) (repository.DepartmentRepository, error) {
+	pc, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pooledDepartments{
+		DepartmentRepository: repository.NewFirestoreRepository(pc.client),
+		pool:                 p,
+		client:               pc,
+	}, nil
+}
+
+// acquire returns the current client with its user count incremented. The
+// health check and opening a client call Firestore, so they run without p.mu
+// held and other callers keep using the current client meanwhile.
+func (p *ClientPool) acquire(ctx context.Context) (*pooledClient, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrPoolClosed
+	}
+	pc := p.current
+	if pc == nil {
+		p.mu.Unlock()
+		return p.openClient()
+	}
+	check := p.HealthCheckInterval > 0 && time.Since(pc.checked) >= p.HealthCheckInterval
+	if check {
+		// Only this caller checks the client; the others use it meanwhile
+		pc.checked = time.Now()
+	}
+	pc.users++
+	p.inUse.Add(1)
+	p.mu.Unlock()
+
+	if check {
+		if err := p.ping(ctx, pc.client); isFatal(err) {
+			p.report(pc, err)
+			p.release(pc)
+			return p.openClient()
+		}
+	}
+	return pc, nil
+}
+
+// openClient opens a client and hands it out as the current one. If another
+// caller opened one meanwhile, that one is handed out and the new one closed.
+func (p *ClientPool) openClient() (*pooledClient, error) {
+	client, err := p.open()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		client.Close()
+		return nil, ErrPoolClosed
+	}
+	if p.current == nil {
+		p.current = &pooledClient{client: client, checked: time.Now()}
+	} else {
+		client.Close()
+	}
+	p.current.users++
+	p.inUse.Add(1)
+	return p.current, nil
+}
+
+// release is called when a repository using pc is closed.
+func (p *ClientPool) release(pc *pooledClient) {
+	p.mu.Lock()
+	pc.users--
+	if pc.retired && pc.users == 0 {
+		pc.client.Close()
+	}
+	p.mu.Unlock()
+	p.inUse.Done()
+}
+
+// report replaces pc when err shows that it can no longer be used.
+func (p *ClientPool) report(pc *pooledClient, err error) {
+	if !isFatal(err) {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.retire(pc)
+}
+
+// retire stops handing out pc and closes it if it is unused. p.mu must be
+// held.
+func (p *ClientPool) retire(pc *pooledClient) {
+	if pc.retired {
+		return
+	}
+	pc.retired = true
+	if p.current == pc {
+		p.current = nil
+	}
+	if pc.users == 0 {
+		pc.client.Close()
+	}
+}
+
+// Shutdown stops handing out clients, waits until every repository from the
+// pool is closed or ctx is done, and then closes the client.
+func (p *ClientPool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		p.inUse.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	p.shutdown.Do(func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.current != nil {
+			p.current.client.Close()
+			p.current = nil
+		}
+	})
+	return err
+}
+
+// ping checks that client can reach Firestore by reading the ID counter.
+func ping(ctx context.Context, client *firestore.Client) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err := client.Collection("counters").Doc("employees").Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil
+	}
+	return err
+}
+
+// isFatal reports whether err means the client has to be recreated, rather
+// than that one request failed.
+func isFatal(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.Unauthenticated:
+		return true
+	}
+	return false
+}
+
+// pooledRepository reports the errors of a Firestore repository to its pool
+// and releases the client on Close.
+type pooledRepository struct {
+	repository.EmployeeRepository
+
+	pool    *ClientPool
+	client  *pooledClient
+	release sync.Once
+}
+
+func (r *pooledRepository) List(ctx context.Context, opts repository.ListOptions) (repository.Page, error) {
+	page, err := r.EmployeeRepository.List(ctx, opts)
+	r.pool.report(r.client, err)
+	return page, err
+}
+
+func (r *pooledRepository) Get(ctx context.Context, id int) (models.Employee, error) {
+	employee, err := r.EmployeeRepository.Get(ctx, id)
+	r.pool.report(r.client, err)
+	return employee, err
+}
+
+func (r *pooledRepository) GetByEmail(ctx context.Context, email string) (models.Employee, error) {
+	employee, err := r.EmployeeRepository.GetByEmail(ctx, email)
+	r.pool.report(r.client, err)
+	return employee, err
+}
+
+func (r *pooledRepository) Search(ctx context.Context, opts repository.SearchOptions) ([]models.Employee, error) {
+	employees, err := r.EmployeeRepository.Search(ctx, opts)
+	r.pool.report(r.client, err)
+	return employees, err
+}
+
+func (r *pooledRepository) Create(ctx context.Context, employee models.Employee) (models.Employee, error) {
+	created, err := r.EmployeeRepository.Create(ctx, employee)
+	r.pool.report(r.client, err)
+	return created, err
+}
+
+func (r *pooledRepository) CreateBatch(ctx context.Context, employees []models.Employee) ([]repository.BatchResult, error) {
+	results, err := r.EmployeeRepository.CreateBatch(ctx, employees)
+	r.pool.report(r.client, err)
+	return results, err
+}
+
+func (r *pooledRepository) Update(ctx context.Context, employee models.Employee) (models.Employee, error) {
+	updated, err := r.EmployeeRepository.Update(ctx, employee)
+	r.pool.report(r.client, err)
+	return updated, err
+}
+
+func (r *pooledRepository) Patch(ctx context.Context, employee models.Employee, fields []string) (models.Employee, error) {
+	patched, err := r.EmployeeRepository.Patch(ctx, employee, fields)
+	r.pool.report(r.client, err)
+	return patched, err
+}
+
+func (r *pooledRepository) Delete(ctx context.Context, id, version int) error {
+	err := r.EmployeeRepository.Delete(ctx, id, version)
+	r.pool.report(r.client, err)
+	return err
+}
+
+func (r *pooledRepository) Restore(ctx context.Context, id int) (models.Employee, error) {
+	employee, err := r.EmployeeRepository.Restore(ctx, id)
+	r.pool.report(r.client, err)
+	return employee, err
+}
+
+func (r *pooledRepository) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	n, err := r.EmployeeRepository.Purge(ctx, deletedBefore)
+	r.pool.report(r.client, err)
+	return n, err
+}
+
+func (r *pooledRepository) History(ctx context.Context, id int) ([]repository.AuditEntry, error) {
+	entries, err := r.EmployeeRepository.History(ctx, id)
+	r.pool.report(r.client, err)
+	return entries, err
+}
+
+func (r *pooledRepository) Version(ctx context.Context, id, version int) (models.Employee, error) {
+	employee, err := r.EmployeeRepository.Version(ctx, id, version)
+	r.pool.report(r.client, err)
+	return employee, err
+}
+
+func (r *pooledRepository) Rollback(ctx context.Context, id, to, version int) (models.Employee, error) {
+	employee, err := r.EmployeeRepository.Rollback(ctx, id, to, version)
+	r.pool.report(r.client, err)
+	return employee, err
+}
+
+// Close releases the client without closing it.
+func (r *pooledRepository) Close() error {
+	r.release.Do(func() { r.pool.release(r.client) })
+	return nil
+}
+
+// pooledDepartments is the pooledRepository of a department repository.
+type pooledDepartments struct {
+	repository.DepartmentRepository
+
+	pool    *ClientPool
+	client  *pooledClient
+	release sync.Once
+}
+
+func (r *pooledDepartments) ListDepartments(ctx context.Context) ([]models.Department, error) {
+	departments, err := r.DepartmentRepository.ListDepartments(ctx)
+	r.pool.report(r.client, err)
+	return departments, err
+}
+
+func (r *pooledDepartments) GetDepartment(ctx context.Context, id int) (models.Department, error) {
+	department, err := r.DepartmentRepository.GetDepartment(ctx, id)
+	r.pool.report(r.client, err)
+	return department, err
+}
+
+func (r *pooledDepartments) CreateDepartment(ctx context.Context, department models.Department) (models.Department, error) {
+	department, err := r.DepartmentRepository.CreateDepartment(ctx, department)
+	r.pool.report(r.client, err)
+	return department, err
+}
+
+func (r *pooledDepartments) UpdateDepartment(ctx context.Context, department models.Department) (models.Department, error) {
+	department, err := r.DepartmentRepository.UpdateDepartment(ctx, department)
+	r.pool.report(r.client, err)
+	return department, err
+}
+
+func (r *pooledDepartments) DeleteDepartment(ctx context.Context, id int) error {
+	err := r.DepartmentRepository.DeleteDepartment(ctx, id)
+	r.pool.report(r.client, err)
+	return err
+}
+
+// Close releases the client without closing it.
+func (r *pooledDepartments) Close() error {
+	r.release.Do(func() { r.pool.release(r.client) })
+	return nil
+}
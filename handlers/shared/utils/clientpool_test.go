@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newTestPool returns a pool whose clients point at an emulator address
+// nothing listens on; creating a client does not connect. It counts the
+// clients opened.
+func newTestPool(t *testing.T) (*ClientPool, *int) {
+	t.Helper()
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:1")
+	opened := 0
+	pool := NewClientPool(func() (*firestore.Client, error) {
+		opened++
+		return firestore.NewClient(context.Background(), "test")
+	})
+	pool.ping = func(context.Context, *firestore.Client) error { return nil }
+	t.Cleanup(func() { pool.Shutdown(context.Background()) })
+	return pool, &opened
+}
+
+func TestClientPoolReusesClient(t *testing.T) {
+	pool, opened := newTestPool(t)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		repo, err := pool.Repository(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repo.Close()
+		repo.Close() // a second Close must not release the client again
+	}
+	if *opened != 1 {
+		t.Errorf("opened %d clients, want 1", *opened)
+	}
+}
+
+func TestClientPoolReplacesClientAfterFatalError(t *testing.T) {
+	pool, opened := newTestPool(t)
+	ctx := context.Background()
+
+	repo, _ := pool.Repository(ctx)
+	first := repo.(*pooledRepository).client
+	pool.report(first, status.Error(codes.NotFound, "no such document"))
+	if pool.current != first {
+		t.Fatal("client replaced after a NotFound error")
+	}
+	pool.report(first, status.Error(codes.Unavailable, "connection refused"))
+	if pool.current == first || !first.retired {
+		t.Fatal("client kept after an Unavailable error")
+	}
+
+	next, _ := pool.Repository(ctx)
+	defer next.Close()
+	if *opened != 2 {
+		t.Errorf("opened %d clients, want 2", *opened)
+	}
+	if first.users != 1 {
+		t.Errorf("retired client has %d users, want 1 until it is released", first.users)
+	}
+	repo.Close()
+	if first.users != 0 {
+		t.Errorf("retired client has %d users after release", first.users)
+	}
+}
+
+func TestClientPoolHealthCheck(t *testing.T) {
+	pool, opened := newTestPool(t)
+	ctx := context.Background()
+	pool.HealthCheckInterval = time.Nanosecond
+
+	healthy := true
+	pool.ping = func(context.Context, *firestore.Client) error {
+		if healthy {
+			return nil
+		}
+		return status.Error(codes.Unavailable, "connection refused")
+	}
+
+	for _, h := range []bool{true, false, true} {
+		healthy = h
+		repo, err := pool.Repository(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repo.Close()
+	}
+	if *opened != 2 {
+		t.Errorf("opened %d clients, want 2 after one failed health check", *opened)
+	}
+}
+
+func TestClientPoolHealthCheckDoesNotBlock(t *testing.T) {
+	pool, _ := newTestPool(t)
+	ctx := context.Background()
+	repo, err := pool.Repository(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.Close()
+	pool.current.checked = time.Time{}
+
+	pinging, unblock := make(chan struct{}), make(chan struct{})
+	defer close(unblock)
+	pool.ping = func(context.Context, *firestore.Client) error {
+		close(pinging)
+		<-unblock
+		return nil
+	}
+	go func() {
+		if repo, err := pool.Repository(ctx); err == nil {
+			repo.Close()
+		}
+	}()
+	<-pinging
+
+	acquired := make(chan error, 1)
+	go func() {
+		repo, err := pool.Repository(ctx)
+		if err == nil {
+			repo.Close()
+		}
+		acquired <- err
+	}()
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Repository blocked on another caller's health check")
+	}
+}
+
+func TestClientPoolShutdown(t *testing.T) {
+	pool, _ := newTestPool(t)
+	repo, _ := pool.Repository(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := pool.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown with an open repository: err = %v, want DeadlineExceeded", err)
+	}
+	repo.Close()
+
+	if _, err := pool.Repository(context.Background()); err != ErrPoolClosed {
+		t.Errorf("Repository after Shutdown: err = %v, want ErrPoolClosed", err)
+	}
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Errorf("second Shutdown: %v", err)
+	}
+}
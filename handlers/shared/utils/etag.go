@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/repository"
+)
+
+// ETag returns the entity tag of employee, its quoted version.
+func ETag(employee models.Employee) string {
+	return strconv.Quote(strconv.Itoa(employee.Version))
+}
+
+// SetETag sets the ETag header of the response to employee's.
+func SetETag(w http.ResponseWriter, employee models.Employee) {
+	w.Header().Set("ETag", ETag(employee))
+}
+
+// CheckIfMatch evaluates the If-Match header of the request against the
+// current employee. It returns the version to condition the write on, the
+// employee's version, also when the header is absent: handlers check the
+// request against current, so a write must fail with ErrVersionMismatch if
+// the employee changed since it was read. When the header does not match it
+// writes 412 and returns false.
+func CheckIfMatch(w http.ResponseWriter, r *http.Request, current models.Employee) (int, bool) {
+	header := r.Header.Get("If-Match")
+	// If-Match uses the strong comparison, so weak tags never match
+	if header != "" && !matchETag(header, ETag(current), false) {
+		RespondWithDomainError(w, r, repository.ErrVersionMismatch, "")
+		return 0, false
+	}
+	return current.Version, true
+}
+
+// NotModified answers 304 Not Modified when the If-None-Match header of the
+// request matches the employee's ETag, and reports whether it did.
+// Otherwise it only sets the ETag header.
+func NotModified(w http.ResponseWriter, r *http.Request, employee models.Employee) bool {
+	SetETag(w, employee)
+	header := r.Header.Get("If-None-Match")
+	if header == "" || !matchETag(header, ETag(employee), true) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// matchETag reports whether etag is one of the comma separated tags in
+// header, or header is "*". A weak comparison ignores the W/ prefix.
+func matchETag(header, etag string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,24 @@
+package utils
+
+import "testing"
+
+func TestMatchETag(t *testing.T) {
+	tests := []struct {
+		header string
+		weak   bool
+		want   bool
+	}{
+		{`"3"`, false, true},
+		{`"2", "3"`, false, true},
+		{`"2"`, false, false},
+		{`W/"3"`, false, false},
+		{`W/"3"`, true, true},
+		{"*", false, true},
+		{`3`, true, false},
+	}
+	for _, tt := range tests {
+		if got := matchETag(tt.header, `"3"`, tt.weak); got != tt.want {
+			t.Errorf("matchETag(%s, weak=%t) = %t, want %t", tt.header, tt.weak, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"example.com/task3gcp/shared/config"
+)
+
+// CreateFirestoreClient opens a Firestore client for the configured project,
+// credentials or emulator (see config.Load).
+func CreateFirestoreClient() (*firestore.Client, error) {
+	ctx := context.Background()
+
+	cfg, err := config.Current()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := cfg.NewFirestoreClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"net/http"
+	"sync"
+
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/policy"
+)
+
+var (
+	verifierMu sync.Mutex
+	// verifier checks the access tokens the gateway forwards. It is read
+	// from JWT_KEY_FILE on first use unless UseVerifier set it.
+	verifier *auth.Verifier
+)
+
+// UseVerifier makes the handlers verify access tokens with v instead of the
+// key in JWT_KEY_FILE, e.g. with the key the gateway generated in local mode.
+func UseVerifier(v *auth.Verifier) {
+	verifierMu.Lock()
+	defer verifierMu.Unlock()
+	verifier = v
+}
+
+// tokenVerifier returns the verifier, loading the key on first use.
+func tokenVerifier() (*auth.Verifier, error) {
+	verifierMu.Lock()
+	defer verifierMu.Unlock()
+	if verifier == nil {
+		key, err := auth.LoadPublicKey(auth.KeyFile())
+		if err != nil {
+			return nil, err
+		}
+		verifier = auth.NewVerifier(key)
+	}
+	return verifier, nil
+}
+
+// CallerIdentity returns the caller identity from the access token the
+// gateway forwards in the Authorization header. The identity headers are
+// not trusted, since anyone who can reach a function directly can set them.
+// It returns policy.ErrUnauthenticated for requests without a valid access
+// token.
+func CallerIdentity(r *http.Request) (auth.Identity, error) {
+	token, ok := auth.BearerToken(r.Header)
+	if !ok {
+		return auth.Identity{}, policy.ErrUnauthenticated
+	}
+	v, err := tokenVerifier()
+	if err != nil {
+		return auth.Identity{}, err
+	}
+	claims, err := v.Verify(token, auth.AccessToken)
+	if err != nil {
+		return auth.Identity{}, policy.ErrUnauthenticated
+	}
+	return claims.Identity()
+}
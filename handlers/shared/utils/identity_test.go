@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/policy"
+)
+
+func TestAuthorize(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	UseVerifier(auth.NewVerifier(key.Public().(ed25519.PublicKey)))
+	t.Cleanup(func() { UseVerifier(nil) })
+
+	token := func(key ed25519.PrivateKey, role string) string {
+		pair, err := auth.NewIssuer(key).Issue(models.Employee{ID: 5, Email: "ada@example.com", Role: role})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pair.AccessToken
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		// spoof sets identity headers claiming to be an admin, as a client
+		// calling the function directly could.
+		spoof bool
+		want  int
+	}{
+		{"no token", "", false, http.StatusUnauthorized},
+		{"spoofed headers", "", true, http.StatusUnauthorized},
+		{"spoofed headers with an employee token", token(key, "employee"), true, http.StatusForbidden},
+		{"token signed with another key", token(otherKey, "admin"), false, http.StatusUnauthorized},
+		{"admin token", token(key, "admin"), false, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/purge", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if tt.spoof {
+				req.Header.Set(auth.HeaderEmployeeID, "1")
+				req.Header.Set(auth.HeaderEmployeeRole, "admin")
+			}
+			rec := httptest.NewRecorder()
+			if _, ok := Authorize(rec, req, policy.Purge, 0); ok {
+				rec.WriteHeader(http.StatusOK)
+			}
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token(key, "hr"))
+	req.Header.Set(auth.HeaderEmployeeRole, "admin")
+	identity, ok := auth.IdentityFromContext(CallerContext(req))
+	if !ok || identity.EmployeeID != 5 || identity.Role != "hr" {
+		t.Errorf("CallerContext identity = %+v, %v, want employee 5 with role hr", identity, ok)
+	}
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+
+	"cloud.google.com/go/logging"
+	"example.com/task3gcp/shared/config"
+)
+
+var (
+	// Logger writes to Cloud Logging. It is nil when no logging client could
+	// be created, and InfoLog and ErrorLog then write to standard output.
+	Logger *logging.Logger
+
+	initLogger sync.Once
+)
+
+// InitLogger sets up Logger. Handlers call it on every request; only the
+// first call creates the logging client.
+func InitLogger() {
+	initLogger.Do(func() {
+		log.SetOutput(os.Stdout)
+
+		// Cloud Logging needs the project ID; against the emulator, or when
+		// the project is detected from the credentials, log to standard output
+		cfg, err := config.Current()
+		if err != nil || cfg.ProjectID == "" || cfg.EmulatorHost != "" {
+			return
+		}
+
+		ctx := context.Background()
+		client, err := logging.NewClient(ctx, cfg.ProjectID, cfg.ClientOptions()...)
+		if err != nil {
+			// Without credentials (e.g. in unit tests) fall back to standard output
+			log.Printf("Failed to create logging client, logging to stdout: %v", err)
+			return
+		}
+
+		Logger = client.Logger(cfg.LogName)
+	})
+}
+
+func InfoLog(message string) {
+	if Logger == nil {
+		log.Println("INFO:", message)
+		return
+	}
+	Logger.Log(logging.Entry{Payload: message, Severity: logging.Info})
+}
+
+func ErrorLog(err error) {
+	if Logger == nil {
+		log.Println("ERROR:", err)
+		return
+	}
+	Logger.Log(logging.Entry{Payload: err.Error(), Severity: logging.Error})
+}
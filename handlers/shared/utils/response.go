@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/domain"
+	"example.com/task3gcp/shared/policy"
+
+	"github.com/gorilla/mux"
+)
+
+// HeaderRequestID carries the ID that ties a response to the gateway and
+// Cloud Function logs of its request.
+const HeaderRequestID = "X-Request-Id"
+
+// ProblemContentType is the media type of Problem responses.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details response. Code is the
+// domain.Kind of the error, so clients can branch on it without parsing
+// Detail.
+type Problem struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Detail    string              `json:"detail,omitempty"`
+	Instance  string              `json:"instance,omitempty"`
+	Code      string              `json:"code"`
+	RequestID string              `json:"requestId,omitempty"`
+	Errors    []domain.FieldError `json:"errors,omitempty"`
+}
+
+// NewProblem returns the problem for a response with the given status.
+func NewProblem(r *http.Request, status int, kind domain.Kind, detail string) Problem {
+	return Problem{
+		Type:      "/problems/" + string(kind),
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      string(kind),
+		RequestID: RequestID(r),
+	}
+}
+
+// RespondWithProblem writes problem as application/problem+json and echoes
+// its request ID.
+func RespondWithProblem(w http.ResponseWriter, problem Problem) {
+	response, err := json.Marshal(problem)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if problem.RequestID != "" {
+		w.Header().Set(HeaderRequestID, problem.RequestID)
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	w.Write(response)
+}
+
+// RespondWithError writes a problem with the given status and message as
+// its detail. The code is the kind of error the status reports.
+func RespondWithError(w http.ResponseWriter, r *http.Request, code int, message string) {
+	RespondWithProblem(w, NewProblem(r, code, domain.KindForStatus(code), message))
+}
+
+// RespondWithDomainError writes the status for the kind of err (see
+// domain.KindOf). Clients see the text of the errors this code defines, such
+// as an invalid field or an unknown employee, since they can act on it.
+// Firestore and gRPC errors, and internal and unavailable ones, are answered
+// with message so that backend details do not leak. Validation errors list
+// their invalid fields.
+func RespondWithDomainError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	kind := domain.KindOf(err)
+	var domainErr *domain.Error
+	isDomain := errors.As(err, &domainErr)
+	if isDomain && domainErr.Err == nil && kind != domain.Internal && kind != domain.Unavailable {
+		message = err.Error()
+	}
+	problem := NewProblem(r, kind.HTTPStatus(), kind, message)
+	if isDomain {
+		problem.Errors = domainErr.Fields
+	}
+	RespondWithProblem(w, problem)
+}
+
+// RequestID returns the ID of the request: the X-Request-Id header set by
+// the client or the gateway, else the trace ID Google front ends add, else
+// a new random ID.
+func RequestID(r *http.Request) string {
+	if id := r.Header.Get(HeaderRequestID); id != "" {
+		return id
+	}
+	if trace, _, _ := strings.Cut(r.Header.Get("X-Cloud-Trace-Context"), "/"); trace != "" {
+		return trace
+	}
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id[:])
+}
+
+// RespondWithJSON writes payload as JSON with the given status code.
+func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
+
+// EmployeeID returns the employee ID from the route variables, or from the
+// last path segment when the handler is not behind a router (as when it is
+// deployed as a Cloud Function).
+func EmployeeID(r *http.Request) string {
+	if id, ok := mux.Vars(r)["id"]; ok {
+		return id
+	}
+	return path.Base(r.URL.Path)
+}
+
+// CallerContext returns the context of r carrying the caller identity from
+// the access token, so that repository writes record who made them in the
+// audit log.
+func CallerContext(r *http.Request) context.Context {
+	identity, err := CallerIdentity(r)
+	if err != nil {
+		return r.Context()
+	}
+	return auth.ContextWithIdentity(r.Context(), identity)
+}
+
+// Authorize checks the caller identified by the access token against the
+// access policy and writes the error response when the request is not
+// allowed.
+func Authorize(w http.ResponseWriter, r *http.Request, op policy.Operation, targetID int) (policy.Rule, bool) {
+	access, err := policy.FromEnv()
+	if err != nil {
+		log.Print("Failed to load access policy:", err)
+		RespondWithError(w, r, http.StatusInternalServerError, "Failed to load access policy")
+		return policy.Rule{}, false
+	}
+	identity, err := CallerIdentity(r)
+	if errors.Is(err, policy.ErrUnauthenticated) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		RespondWithError(w, r, http.StatusUnauthorized, "Authentication required")
+		return policy.Rule{}, false
+	}
+	if err != nil {
+		log.Print("Failed to load token verification key:", err)
+		RespondWithError(w, r, http.StatusInternalServerError, "Failed to load token verification key")
+		return policy.Rule{}, false
+	}
+	rule, err := access.Authorize(identity, op, targetID)
+	if err != nil {
+		log.Print("Request denied by access policy:", err)
+		RespondWithError(w, r, http.StatusForbidden, "Forbidden")
+		return policy.Rule{}, false
+	}
+	return rule, true
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) Problem {
+	t.Helper()
+	if got := rec.Header().Get("Content-Type"); got != ProblemContentType {
+		t.Fatalf("Content-Type = %q, want %q", got, ProblemContentType)
+	}
+	var problem Problem
+	if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	return problem
+}
+
+func TestRespondWithDomainError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/employees", nil)
+	req.Header.Set(HeaderRequestID, "req-1")
+	rec := httptest.NewRecorder()
+	RespondWithDomainError(rec, req, models.Employee{FirstName: "Ada"}.Validate(), "Invalid employee")
+
+	problem := decodeProblem(t, rec)
+	if rec.Code != http.StatusBadRequest || problem.Status != http.StatusBadRequest {
+		t.Errorf("status = %d, problem status = %d, want 400", rec.Code, problem.Status)
+	}
+	if problem.Type != "/problems/validation" || problem.Code != "validation" || problem.Title != "Bad Request" {
+		t.Errorf("problem = %+v, want a validation problem", problem)
+	}
+	if problem.Instance != "/employees" || problem.RequestID != "req-1" || rec.Header().Get(HeaderRequestID) != "req-1" {
+		t.Errorf("instance = %q, request ID = %q, header = %q", problem.Instance, problem.RequestID, rec.Header().Get(HeaderRequestID))
+	}
+	fields := map[string]string{}
+	for _, field := range problem.Errors {
+		fields[field.Field] = field.Code
+	}
+	for _, name := range []string{"lastName", "email", "password", "role"} {
+		if fields[name] != "required" {
+			t.Errorf("errors = %+v, want %s required", problem.Errors, name)
+		}
+	}
+	if _, ok := fields["firstName"]; ok {
+		t.Errorf("errors = %+v, want firstName valid", problem.Errors)
+	}
+
+	rec = httptest.NewRecorder()
+	RespondWithDomainError(rec, req, errors.New("dial tcp: connection refused"), "Failed to list employees")
+	if problem := decodeProblem(t, rec); problem.Code != "internal" || problem.Detail != "Failed to list employees" {
+		t.Errorf("problem = %+v, want an internal problem that hides the error", problem)
+	}
+
+	// Backend errors of other kinds keep their text from clients too
+	for _, err := range []error{
+		status.Error(codes.Unavailable, "dial tcp 10.0.0.1:443: connection refused"),
+		status.Error(codes.Aborted, "too much contention on projects/p/databases/(default)/documents/employees/1"),
+		status.Error(codes.NotFound, "projects/p/databases/(default)/documents/employees/1"),
+		ErrPoolClosed,
+	} {
+		rec = httptest.NewRecorder()
+		RespondWithDomainError(rec, req, err, "Failed to update employee in Firestore")
+		if problem := decodeProblem(t, rec); problem.Detail != "Failed to update employee in Firestore" {
+			t.Errorf("%v: detail = %q, want the handler's message", err, problem.Detail)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	RespondWithDomainError(rec, req, fmt.Errorf("%w: salary", repository.ErrReadOnlyField), "Failed to patch employee")
+	if problem := decodeProblem(t, rec); problem.Code != "validation" || problem.Detail != "field cannot be patched: salary" {
+		t.Errorf("problem = %+v, want the validation error's text", problem)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Cloud-Trace-Context", "105445aa7843bc8bf206b12000100000/1;o=1")
+	if got := RequestID(req); got != "105445aa7843bc8bf206b12000100000" {
+		t.Errorf("RequestID with a trace header = %q, want the trace ID", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	first, second := RequestID(req), RequestID(req)
+	if len(first) != 32 || first == second {
+		t.Errorf("generated request IDs %q and %q, want distinct 32 character IDs", first, second)
+	}
+}
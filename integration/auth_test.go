@@ -0,0 +1,35 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"example.com/task3gcp/function7"
+	"example.com/task3gcp/shared/repository"
+)
+
+func TestLogin(t *testing.T) {
+	e := newEnv(t)
+	ada := e.seed(employee("Ada", "Lovelace"))[0]
+
+	// The email index is keyed by the normalized email
+	for _, email := range []string{"ada@example.com", "Ada@Example.COM"} {
+		body := `{"email": "` + email + `", "password": "secret1"}`
+		if rec := call(function7.LoginHandler, http.MethodPost, "/", body); rec.Code != http.StatusOK {
+			t.Errorf("login as %s: status = %d: %s", email, rec.Code, rec.Body)
+		}
+	}
+	if rec := call(function7.LoginHandler, http.MethodPost, "/", `{"email": "ada@example.com", "password": "wrong"}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong password: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	if err := e.repo.Delete(context.Background(), ada.ID, ada.Version); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.repo.GetByEmail(context.Background(), "ada@example.com"); err != repository.ErrNotFound {
+		t.Errorf("GetByEmail of a deleted employee: err = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,80 @@
+//go:build integration
+
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"example.com/task3gcp/function3"
+	"example.com/task3gcp/function4"
+	"example.com/task3gcp/function8"
+	"example.com/task3gcp/shared/models"
+)
+
+func TestDepartments(t *testing.T) {
+	e := newEnv(t)
+	head := e.seed(employee("Ada", "Lovelace"))[0]
+
+	body := fmt.Sprintf(`{"name": "Engineering", "code": "eng", "costCenter": "CC-100", "headEmployeeId": %d}`, head.ID)
+	rec := call(function8.CreateDepartmentHandler, http.MethodPost, "/", body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d: %s", rec.Code, rec.Body)
+	}
+	var department models.Department
+	if err := json.NewDecoder(rec.Body).Decode(&department); err != nil {
+		t.Fatal(err)
+	}
+	if department.ID == 0 || department.Code != "ENG" {
+		t.Fatalf("created department = %+v, want an ID and code ENG", department)
+	}
+	if rec := call(function8.CreateDepartmentHandler, http.MethodPost, "/", `{"name": "Engines", "code": "ENG"}`); rec.Code != http.StatusConflict {
+		t.Errorf("taken code: status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	target := fmt.Sprintf("/%d", department.ID)
+
+	alan := employee("Alan", "Turing")
+	alan.DepartmentID = department.ID
+	alan = e.seed(alan)[0]
+
+	t.Run("employees", func(t *testing.T) {
+		unknown := `{"firstName": "Grace", "lastName": "Hopper", "email": "grace@example.com", "password": "secret1", "role": "employee", "departmentId": 999}`
+		if rec := call(function3.CreateEmployeeHandler, http.MethodPost, "/", unknown); rec.Code != http.StatusBadRequest {
+			t.Errorf("unknown department: status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+
+		rec := call(function8.DepartmentEmployeesHandler, http.MethodGet, target, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+		}
+		var page struct {
+			Employees []models.EmployeeResponse `json:"employees"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Employees) != 1 || page.Employees[0].Email != "alan@example.com" || page.Employees[0].DepartmentID != department.ID {
+			t.Errorf("employees = %+v, want Alan in department %d", page.Employees, department.ID)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if rec := call(function8.DeleteDepartmentHandler, http.MethodDelete, target, ""); rec.Code != http.StatusConflict {
+			t.Fatalf("delete with employees: status = %d, want %d", rec.Code, http.StatusConflict)
+		}
+
+		// Moving the employee out of the department frees it
+		body := `{"firstName": "Alan", "lastName": "Turing", "email": "alan@example.com", "password": "secret1", "role": "employee"}`
+		if rec := call(function4.UpdateEmployeeHandler, http.MethodPut, fmt.Sprintf("/%d", alan.ID), body); rec.Code != http.StatusOK {
+			t.Fatalf("update: status = %d: %s", rec.Code, rec.Body)
+		}
+		if rec := call(function8.DeleteDepartmentHandler, http.MethodDelete, target, ""); rec.Code != http.StatusOK {
+			t.Fatalf("delete: status = %d: %s", rec.Code, rec.Body)
+		}
+		if rec := call(function8.GetDepartmentHandler, http.MethodGet, target, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("get after delete: status = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+}
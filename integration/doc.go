@@ -0,0 +1,10 @@
+// Package integration tests the employee handlers against the Firestore
+// emulator. The tests are behind the integration build tag:
+//
+//	go test -tags integration ./integration
+//
+// They use the emulator at FIRESTORE_EMULATOR_HOST (set GOOGLE_CLOUD_PROJECT
+// as well, as the functions require a project with the emulator), or start
+// one with the gcloud CLI, and are skipped when neither is available. Every
+// test gets its own emulator project, so tests never see each other's data.
+package integration
@@ -0,0 +1,414 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"example.com/task3gcp/function1"
+	"example.com/task3gcp/function2"
+	"example.com/task3gcp/function3"
+	"example.com/task3gcp/function4"
+	"example.com/task3gcp/function5"
+	"example.com/task3gcp/shared/importer"
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/repository"
+)
+
+func TestCreateEmployee(t *testing.T) {
+	e := newEnv(t)
+	existing := e.seed(employee("Ada", "Lovelace"))[0]
+
+	t.Run("success", func(t *testing.T) {
+		rec := call(function3.CreateEmployeeHandler, http.MethodPost, "/",
+			`{"firstName": "Alan", "lastName": "Turing", "email": "alan@example.com", "password": "secret1", "role": "employee"}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+		}
+		created, err := e.get(existing.ID + 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if created.Email != "alan@example.com" || !created.CheckPassword("secret1") {
+			t.Errorf("stored employee = %+v", created)
+		}
+	})
+
+	t.Run("validation failure", func(t *testing.T) {
+		rec := call(function3.CreateEmployeeHandler, http.MethodPost, "/", `{"firstName": "Grace"}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("duplicate email", func(t *testing.T) {
+		rec := call(function3.CreateEmployeeHandler, http.MethodPost, "/",
+			`{"firstName": "Ada", "lastName": "King", "email": "ADA@example.com", "password": "secret1", "role": "employee"}`)
+		if rec.Code != http.StatusConflict {
+			t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body)
+		}
+	})
+
+	t.Run("duplicate ID", func(t *testing.T) {
+		// A client supplied ID must not overwrite the employee that has it
+		rec := call(function3.CreateEmployeeHandler, http.MethodPost, "/", fmt.Sprintf(
+			`{"id": %d, "firstName": "Eve", "lastName": "Mallory", "email": "eve@example.com", "password": "secret1", "role": "employee"}`,
+			existing.ID))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+		}
+		if stored, _ := e.get(existing.ID); stored.Email != existing.Email {
+			t.Errorf("employee %d overwritten by create: %+v", existing.ID, stored)
+		}
+	})
+}
+
+func TestGetAllEmployees(t *testing.T) {
+	e := newEnv(t)
+	created := e.seed(employee("Ada", "Lovelace"), employee("Alan", "Turing"), employee("Grace", "Hopper"))
+	if err := e.repo.Delete(context.Background(), created[2].ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("success", func(t *testing.T) {
+		rec := call(function1.GetAllEmployees, http.MethodGet, "/?limit=1&orderBy=lastName", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+		}
+		first := decodePage(t, rec.Body.Bytes())
+		rec = call(function1.GetAllEmployees, http.MethodGet, "/?limit=1&orderBy=lastName&pageToken="+first.NextPageToken, "")
+		second := decodePage(t, rec.Body.Bytes())
+		if len(first.Employees) != 1 || len(second.Employees) != 1 ||
+			first.Employees[0].LastName != "Lovelace" || second.Employees[0].LastName != "Turing" || second.NextPageToken != "" {
+			t.Errorf("pages = %+v, %+v; want Lovelace then Turing, without the deleted Hopper", first, second)
+		}
+	})
+
+	t.Run("validation failure", func(t *testing.T) {
+		if rec := call(function1.GetAllEmployees, http.MethodGet, "/?limit=0", ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		rec := call(function1.GetAllEmployees, http.MethodGet, "/?role=nobody", "")
+		if page := decodePage(t, rec.Body.Bytes()); rec.Code != http.StatusOK || len(page.Employees) != 0 {
+			t.Errorf("status = %d, page = %+v; want an empty page", rec.Code, page)
+		}
+	})
+
+	t.Run("export", func(t *testing.T) {
+		rec := call(function1.ExportEmployeesHandler, http.MethodGet, "/export?format=json&orderBy=lastName", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+		}
+		var employees []models.EmployeeResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &employees); err != nil {
+			t.Fatal(err)
+		}
+		if len(employees) != 2 || employees[0].LastName != "Lovelace" || employees[1].LastName != "Turing" {
+			t.Errorf("export = %+v, want Lovelace then Turing", employees)
+		}
+	})
+}
+
+func TestGetEmployeeByID(t *testing.T) {
+	e := newEnv(t)
+	created := e.seed(employee("Ada", "Lovelace"), employee("Alan", "Turing"))
+	if err := e.repo.Delete(context.Background(), created[1].ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, target string
+		want         int
+	}{
+		{"success", fmt.Sprintf("/%d", created[0].ID), http.StatusOK},
+		{"validation failure", "/abc", http.StatusBadRequest},
+		{"not found", "/999", http.StatusNotFound},
+		{"deleted", fmt.Sprintf("/%d", created[1].ID), http.StatusNotFound},
+		{"deleted included", fmt.Sprintf("/%d?includeDeleted=true", created[1].ID), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := call(function2.GetEmployeeByID, http.MethodGet, tt.target, "")
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if rec.Code == http.StatusOK {
+				var got models.EmployeeResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || got.ID == 0 {
+					t.Errorf("body = %s, %v", rec.Body, err)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateEmployee(t *testing.T) {
+	e := newEnv(t)
+	existing := e.seed(employee("Ada", "Lovelace"))[0]
+	body := `{"firstName": "Ada", "lastName": "King", "email": "ada@example.com", "password": "secret2", "role": "manager"}`
+
+	t.Run("success", func(t *testing.T) {
+		rec := call(function4.UpdateEmployeeHandler, http.MethodPut, fmt.Sprintf("/%d", existing.ID), body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+		}
+		stored, _ := e.get(existing.ID)
+		if stored.LastName != "King" || stored.Role != "manager" || !stored.CheckPassword("secret2") {
+			t.Errorf("stored employee = %+v", stored)
+		}
+	})
+
+	t.Run("validation failure", func(t *testing.T) {
+		rec := call(function4.UpdateEmployeeHandler, http.MethodPut, fmt.Sprintf("/%d", existing.ID), `{"firstName": "Ada"}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		if rec := call(function4.UpdateEmployeeHandler, http.MethodPut, "/999", body); rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusNotFound, rec.Body)
+		}
+		if _, err := e.get(999); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("update of a missing employee created it: err = %v", err)
+		}
+	})
+}
+
+func TestPatchEmployee(t *testing.T) {
+	e := newEnv(t)
+	existing := e.seed(employee("Ada", "Lovelace"))[0]
+	patch := func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set("Content-Type", "application/merge-patch+json")
+		function4.PatchEmployeeHandler(w, r)
+	}
+
+	t.Run("success", func(t *testing.T) {
+		rec := call(patch, http.MethodPatch, fmt.Sprintf("/%d", existing.ID), `{"lastName": "King"}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+		}
+		stored, _ := e.get(existing.ID)
+		if stored.LastName != "King" || stored.LastNameLower != "king" || stored.Role != "employee" || stored.Password != existing.Password {
+			t.Errorf("stored employee = %+v, want only the last name changed", stored)
+		}
+	})
+
+	t.Run("stale version", func(t *testing.T) {
+		// existing still holds the version from before the patch
+		if _, err := e.repo.Update(context.Background(), existing); !errors.Is(err, repository.ErrVersionMismatch) {
+			t.Errorf("Update at a stale version: err = %v, want ErrVersionMismatch", err)
+		}
+	})
+
+	t.Run("validation failure", func(t *testing.T) {
+		rec := call(patch, http.MethodPatch, fmt.Sprintf("/%d", existing.ID), `{"email": "ada"}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		if rec := call(patch, http.MethodPatch, "/999", `{"lastName": "King"}`); rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusNotFound, rec.Body)
+		}
+	})
+}
+
+func TestDeleteEmployee(t *testing.T) {
+	e := newEnv(t)
+	existing := e.seed(employee("Ada", "Lovelace"))[0]
+	target := fmt.Sprintf("/%d", existing.ID)
+
+	t.Run("success", func(t *testing.T) {
+		if rec := call(function5.DeleteEmployeeHandler, http.MethodDelete, target, ""); rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+		}
+		if stored, _ := e.get(existing.ID); !stored.Deleted || stored.DeletedAt == nil {
+			t.Errorf("stored employee = %+v, want soft-deleted", stored)
+		}
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		if rec := call(function5.DeleteEmployeeHandler, http.MethodDelete, target, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("second delete: status = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("validation failure", func(t *testing.T) {
+		if rec := call(function5.DeleteEmployeeHandler, http.MethodDelete, "/abc", ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		if rec := call(function5.DeleteEmployeeHandler, http.MethodDelete, "/999", ""); rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestEmployeeHistory(t *testing.T) {
+	e := newEnv(t)
+	existing := e.seed(employee("Ada", "Lovelace"))[0]
+	target := fmt.Sprintf("/%d", existing.ID)
+
+	body := `{"firstName": "Ada", "lastName": "King", "email": "ada@example.com", "password": "secret2", "role": "employee"}`
+	if rec := call(function4.UpdateEmployeeHandler, http.MethodPut, target, body); rec.Code != http.StatusOK {
+		t.Fatalf("update: status = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := call(function5.DeleteEmployeeHandler, http.MethodDelete, target, ""); rec.Code != http.StatusOK {
+		t.Fatalf("delete: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := call(function2.EmployeeHistoryHandler, http.MethodGet, target, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), "secret2") {
+		t.Errorf("history leaks the password: %s", rec.Body)
+	}
+	var history struct {
+		Entries []repository.AuditEntry `json:"entries"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&history); err != nil {
+		t.Fatal(err)
+	}
+	var operations []string
+	for _, entry := range history.Entries {
+		operations = append(operations, entry.Operation)
+	}
+	if strings.Join(operations, ",") != "create,update,delete" {
+		t.Fatalf("operations = %v, want create, update and delete", operations)
+	}
+	update := history.Entries[1]
+	if update.Actor.EmployeeID != 1 || update.Actor.Role != "admin" || update.Version != 2 {
+		t.Errorf("update entry = %+v, want version 2 by the admin", update)
+	}
+	changed := map[string]repository.FieldChange{}
+	for _, change := range update.Changes {
+		changed[change.Field] = change
+	}
+	if changed["lastName"].Before != "Lovelace" || changed["lastName"].After != "King" || changed["password"].After != repository.Redacted {
+		t.Errorf("update changes = %+v", update.Changes)
+	}
+
+	if rec := call(function2.EmployeeHistoryHandler, http.MethodGet, "/999", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown employee: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestRollbackEmployee(t *testing.T) {
+	e := newEnv(t)
+	existing := e.seed(employee("Ada", "Lovelace"))[0]
+	target := fmt.Sprintf("/%d", existing.ID)
+
+	body := `{"firstName": "Ada", "lastName": "King", "email": "ada.king@example.com", "password": "secret2", "role": "manager"}`
+	if rec := call(function4.UpdateEmployeeHandler, http.MethodPut, target, body); rec.Code != http.StatusOK {
+		t.Fatalf("update: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	t.Run("version", func(t *testing.T) {
+		rec := call(function2.EmployeeVersionHandler, http.MethodGet, target+"/1", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+		}
+		var first models.EmployeeResponse
+		if err := json.NewDecoder(rec.Body).Decode(&first); err != nil {
+			t.Fatal(err)
+		}
+		if first.LastName != "Lovelace" || first.Email != "ada@example.com" || first.Version != 1 {
+			t.Errorf("version 1 = %+v", first)
+		}
+		if rec := call(function2.EmployeeVersionHandler, http.MethodGet, target+"/3", ""); rec.Code != http.StatusNotFound {
+			t.Errorf("future version: status = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		rec := call(function4.RollbackEmployeeHandler, http.MethodPost, target+"?to=1", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+		}
+		stored, _ := e.get(existing.ID)
+		if stored.LastName != "Lovelace" || stored.LastNameLower != "lovelace" || stored.Email != "ada@example.com" ||
+			stored.Role != "employee" || stored.Version != 3 || !stored.CheckPassword("secret2") {
+			t.Errorf("stored employee = %+v, want version 1 restored with the current password", stored)
+		}
+		if rec := call(function2.EmployeeVersionHandler, http.MethodGet, target+"/3", ""); rec.Code != http.StatusOK {
+			t.Errorf("version of the rollback: status = %d, want %d", rec.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("emails", func(t *testing.T) {
+		// The rollback released the newer email and claimed the old one
+		rec := call(function3.CreateEmployeeHandler, http.MethodPost, "/",
+			`{"firstName": "Ada", "lastName": "Byron", "email": "Ada.King@example.com", "password": "secret1", "role": "employee"}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create with the released email: status = %d: %s", rec.Code, rec.Body)
+		}
+		if rec := call(function4.RollbackEmployeeHandler, http.MethodPost, target+"?to=2", ""); rec.Code != http.StatusConflict {
+			t.Errorf("rollback to a taken email: status = %d, want %d", rec.Code, http.StatusConflict)
+		}
+	})
+}
+
+func TestImportEmployees(t *testing.T) {
+	e := newEnv(t)
+	existing := e.seed(employee("Ada", "Lovelace"))[0]
+
+	file := "First Name,lastName,email,password,role\n" +
+		"Alan,Turing,alan@example.com,secret1,employee\n" +
+		"Ada,King,ADA@example.com,secret1,employee\n" +
+		"Grace,Hopper,grace@example.com,secret1,manager\n" +
+		"Edsger,Dijkstra,edsger@example.com,short,employee\n" +
+		"Barbara,Liskov,barbara@example.com,secret1,employee\n"
+	result, err := importer.Import(context.Background(), e.repo, strings.NewReader(file), importer.Options{
+		Mapping:   importer.Mapping{"firstName": "First Name"},
+		BatchSize: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 3 || len(result.Rejected) != 2 || result.Rejected[0].Line != 3 || result.Rejected[1].Line != 5 {
+		t.Fatalf("result = %+v, want lines 3 and 5 rejected", result)
+	}
+
+	page, err := e.repo.List(context.Background(), repository.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Employees) != 4 {
+		t.Fatalf("stored %d employees, want 4", len(page.Employees))
+	}
+	for _, stored := range page.Employees[1:] {
+		if stored.ID <= existing.ID || stored.Version != 1 || !stored.CheckPassword("secret1") {
+			t.Errorf("imported employee = %+v", stored)
+		}
+	}
+
+	// Imported emails are claimed like created ones
+	rec := call(function3.CreateEmployeeHandler, http.MethodPost, "/",
+		`{"firstName": "Grace", "lastName": "Murray", "email": "Grace@example.com", "password": "secret1", "role": "employee"}`)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("create with an imported email: status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+}
+
+func decodePage(t *testing.T, body []byte) repository.Page {
+	t.Helper()
+	var page repository.Page
+	if err := json.Unmarshal(body, &page); err != nil {
+		t.Fatalf("decode page %s: %v", body, err)
+	}
+	return page
+}
@@ -0,0 +1,216 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"example.com/task3gcp/function1"
+	"example.com/task3gcp/function2"
+	"example.com/task3gcp/function3"
+	"example.com/task3gcp/function4"
+	"example.com/task3gcp/function5"
+	"example.com/task3gcp/function6"
+	"example.com/task3gcp/function7"
+	"example.com/task3gcp/function8"
+	"example.com/task3gcp/shared/auth"
+	"example.com/task3gcp/shared/config"
+	"example.com/task3gcp/shared/models"
+	"example.com/task3gcp/shared/repository"
+	"example.com/task3gcp/shared/utils"
+)
+
+// emulatorHost is the address of the running emulator, or empty when there
+// is none and the tests are skipped.
+var emulatorHost string
+
+// adminToken is the access token the handlers get from call.
+var adminToken string
+
+func TestMain(m *testing.M) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	utils.UseVerifier(auth.NewVerifier(key.Public().(ed25519.PublicKey)))
+	pair, err := auth.NewIssuer(key).Issue(models.Employee{ID: 1, Role: "admin"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	adminToken = pair.AccessToken
+	function7.UseSigningKey(key)
+
+	stop, err := startEmulator()
+	if err != nil {
+		log.Print("Firestore emulator unavailable, skipping integration tests: ", err)
+	}
+	code := m.Run()
+	stop()
+	os.Exit(code)
+}
+
+// startEmulator uses FIRESTORE_EMULATOR_HOST if it is set and otherwise
+// starts the emulator with gcloud on a free port. It returns a function that
+// stops an emulator it started.
+func startEmulator() (stop func(), err error) {
+	stop = func() {}
+	if host := os.Getenv("FIRESTORE_EMULATOR_HOST"); host != "" {
+		emulatorHost = host
+		return stop, waitForEmulator(host, 5*time.Second)
+	}
+
+	gcloud, err := exec.LookPath("gcloud")
+	if err != nil {
+		return stop, err
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return stop, err
+	}
+	host := listener.Addr().String()
+	listener.Close()
+
+	cmd := exec.Command(gcloud, "emulators", "firestore", "start", "--host-port="+host)
+	cmd.Stdout, cmd.Stderr = io.Discard, io.Discard
+	if err := cmd.Start(); err != nil {
+		return stop, err
+	}
+	stop = func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}
+	if err := waitForEmulator(host, time.Minute); err != nil {
+		stop()
+		return func() {}, err
+	}
+	emulatorHost = host
+	return stop, nil
+}
+
+// waitForEmulator waits until the emulator accepts HTTP requests.
+func waitForEmulator(host string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		resp, err := http.Get("http://" + host)
+		if err == nil {
+			resp.Body.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			emulatorHost = ""
+			return fmt.Errorf("no emulator at %s: %w", host, err)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// projects numbers the emulator projects so every test has its own,
+// empty database.
+var projects atomic.Int64
+
+// env is one test's view of the emulator.
+type env struct {
+	t    *testing.T
+	repo repository.EmployeeRepository
+}
+
+// newEnv points every handler at a fresh emulator project and returns the
+// repository for seeding and checking it. It skips the test when no emulator
+// is available.
+func newEnv(t *testing.T) *env {
+	t.Helper()
+	if emulatorHost == "" {
+		t.Skip("Firestore emulator unavailable")
+	}
+
+	cfg := &config.Config{
+		ProjectID:    fmt.Sprintf("integration-%d-%d", time.Now().UnixNano(), projects.Add(1)),
+		EmulatorHost: emulatorHost,
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	pool := utils.NewClientPool(func() (*firestore.Client, error) {
+		return cfg.NewFirestoreClient(context.Background())
+	})
+	t.Cleanup(func() { pool.Shutdown(context.Background()) })
+
+	open := func() (repository.EmployeeRepository, error) {
+		return pool.Repository(context.Background())
+	}
+	for _, use := range []func(func() (repository.EmployeeRepository, error)){
+		function1.UseRepository,
+		function2.UseRepository,
+		function3.UseRepository,
+		function4.UseRepository,
+		function5.UseRepository,
+		function6.UseRepository,
+		function7.UseRepository,
+		function8.UseRepository,
+	} {
+		use(open)
+	}
+	function8.UseDepartments(func() (repository.DepartmentRepository, error) {
+		return pool.Departments(context.Background())
+	})
+
+	repo, err := open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return &env{t: t, repo: repo}
+}
+
+// seed creates the given employees and returns them with their IDs.
+func (e *env) seed(employees ...models.Employee) []models.Employee {
+	e.t.Helper()
+	created := make([]models.Employee, len(employees))
+	for i, employee := range employees {
+		var err error
+		if created[i], err = e.repo.Create(context.Background(), employee); err != nil {
+			e.t.Fatal(err)
+		}
+	}
+	return created
+}
+
+// get returns the stored employee with the given ID.
+func (e *env) get(id int) (models.Employee, error) {
+	return e.repo.Get(context.Background(), id)
+}
+
+// call runs handler on a request from an admin and returns the recorded
+// response.
+func call(handler http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+// employee returns a valid employee with the given name.
+func employee(first, last string) models.Employee {
+	return models.Employee{
+		FirstName: first,
+		LastName:  last,
+		Email:     strings.ToLower(first) + "@example.com",
+		Password:  "secret1",
+		Role:      "employee",
+	}
+}